@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Session prompt guard modes (AGENT_SESSION_PROMPT_GUARD).
+const (
+	sessionGuardOff    = "off"
+	sessionGuardWarn   = "warn"
+	sessionGuardReject = "reject"
+)
+
+// sessionCollisionSimilarity is the word-overlap (Jaccard) below which a prompt
+// is considered unrelated to the prompt of a run already active on the session.
+const sessionCollisionSimilarity = 0.1
+
+// ErrSessionCollision is returned by AgentLoop when AGENT_SESSION_PROMPT_GUARD=reject
+// and the session ID appears to be shared by an unrelated concurrent conversation.
+var ErrSessionCollision = errors.New("session id is in use by an unrelated active run")
+
+// activeRuns tracks the runs in flight per session, with a fingerprint (word set)
+// of the first active run's prompt. Fingerprints are dropped once the session has
+// no active runs.
+type activeRuns struct {
+	mu       sync.Mutex
+	sessions map[string]*activeSession
+}
+
+type activeSession struct {
+	fingerprint map[string]bool
+	runs        int
+}
+
+func newActiveRuns() *activeRuns {
+	return &activeRuns{sessions: map[string]*activeSession{}}
+}
+
+// begin registers a run for sessionID. When another run is already active on the
+// session, it returns the similarity between the prompts and whether they look
+// like a collision. end must be called when the run finishes.
+func (a *activeRuns) begin(sessionID, prompt string) (similarity float64, collision bool, end func()) {
+	fp := promptFingerprint(prompt)
+
+	a.mu.Lock()
+	s := a.sessions[sessionID]
+	if s == nil {
+		s = &activeSession{fingerprint: fp}
+		a.sessions[sessionID] = s
+	} else if s.runs > 0 {
+		similarity = jaccard(s.fingerprint, fp)
+		collision = similarity < sessionCollisionSimilarity
+	}
+	s.runs++
+	a.mu.Unlock()
+
+	var once sync.Once
+	return similarity, collision, func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			s.runs--
+			if s.runs == 0 {
+				delete(a.sessions, sessionID)
+			}
+		})
+	}
+}
+
+// promptFingerprint returns the set of lowercased words of at least 3 letters.
+func promptFingerprint(prompt string) map[string]bool {
+	fp := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len([]rune(w)) >= 3 {
+			fp[w] = true
+		}
+	}
+	return fp
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	inter := 0
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// errRunCanceled is the cancellation cause of runs stopped with CancelRun.
+var errRunCanceled = errors.New("run canceled by request")
+
+// runCancels maps session IDs to the cancel functions of their in-flight runs.
+type runCancels struct {
+	mu       sync.Mutex
+	next     uint64
+	sessions map[string]map[uint64]context.CancelCauseFunc
+}
+
+func newRunCancels() *runCancels {
+	return &runCancels{sessions: map[string]map[uint64]context.CancelCauseFunc{}}
+}
+
+// register adds a run's cancel function; the returned func removes it.
+func (r *runCancels) register(sessionID string, cancel context.CancelCauseFunc) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	id := r.next
+	if r.sessions[sessionID] == nil {
+		r.sessions[sessionID] = map[uint64]context.CancelCauseFunc{}
+	}
+	r.sessions[sessionID][id] = cancel
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.sessions[sessionID], id)
+		if len(r.sessions[sessionID]) == 0 {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// cancel cancels every in-flight run of sessionID and returns how many there were.
+func (r *runCancels) cancel(sessionID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.sessions[sessionID] {
+		cancel(errRunCanceled)
+	}
+	return len(r.sessions[sessionID])
+}
+
+// runCanceled reports whether ctx was canceled through CancelRun.
+func runCanceled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRunCanceled)
+}
+
+// CancelRun cancels the in-flight runs of a session; they return promptly with
+// OutcomeCanceled. It returns the number of runs canceled.
+func (p *Planner) CancelRun(sessionID string) int {
+	return p.runCancels.cancel(sessionID)
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunCancels_CancelsSessionRuns(t *testing.T) {
+	r := newRunCancels()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	unregister := r.register("s1", cancel)
+	other, cancelOther := context.WithCancelCause(context.Background())
+	defer r.register("s2", cancelOther)()
+
+	if n := r.cancel("s1"); n != 1 {
+		t.Fatalf("expected 1 canceled run, got %d", n)
+	}
+	if !runCanceled(ctx) {
+		t.Fatalf("expected run to be canceled with errRunCanceled, cause: %v", context.Cause(ctx))
+	}
+	if other.Err() != nil {
+		t.Fatalf("other session's run must keep running")
+	}
+	unregister()
+	if n := r.cancel("s1"); n != 0 {
+		t.Fatalf("expected no runs after unregister, got %d", n)
+	}
+}
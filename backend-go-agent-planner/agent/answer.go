@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// extractAnswer returns the human-readable answer of a final (non-tool-call)
+// plan: an explicit "final_answer" or "answer" field when present, otherwise the
+// plan's steps joined by newlines. Plans that are not JSON are returned as is.
+func extractAnswer(planJSON string) string {
+	if answer, ok := parseFinalAnswer(planJSON); ok {
+		return answer
+	}
+	var plan struct {
+		Answer string   `json:"answer"`
+		Steps  []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plan); err != nil {
+		return planJSON
+	}
+	if strings.TrimSpace(plan.Answer) != "" {
+		return plan.Answer
+	}
+	var steps []string
+	for _, s := range plan.Steps {
+		if s = strings.TrimSpace(s); s != "" {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return planJSON
+	}
+	return strings.Join(steps, "\n")
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Approval modes for tools in Config.ApprovalRequiredTools (AGENT_APPROVAL_MODE).
+const (
+	// approvalModeToken pauses the run and returns a confirmation token (/plan/confirm).
+	approvalModeToken = "token"
+	// approvalModeWait keeps the run waiting for a decision pushed to Redis.
+	approvalModeWait = "wait"
+)
+
+// Approval decisions recorded in TOOL_APPROVAL_RESOLVED.
+const (
+	approvalApproved    = "approved"
+	approvalDenied      = "denied"
+	approvalTimeout     = "timeout"
+	approvalUnavailable = "unavailable"
+)
+
+// approvalPollInterval bounds each blocking Redis read so cancellation is noticed.
+const approvalPollInterval = time.Second
+
+// ErrApprovalNotFound is returned by ResolveApproval for an unknown or expired
+// approval request.
+var ErrApprovalNotFound = errors.New("approval request not found or expired")
+
+// approvalKey marks a pending approval request; approvalReplyKey receives the decision.
+func (p *Planner) approvalKey(id string) string      { return p.cfg.NotifyPrefix + "pagi:approval:" + id }
+func (p *Planner) approvalReplyKey(id string) string { return p.approvalKey(id) + ":reply" }
+
+// awaitApproval publishes an approval request for calls and blocks until a
+// decision arrives (ResolveApproval), Config.ApprovalTimeout passes, or ctx is
+// done. Only an explicit approval returns approvalApproved; without Redis the
+// request fails closed with approvalUnavailable.
+func (p *Planner) awaitApproval(ctx context.Context, sessionID string, turn int, calls []ToolCall) string {
+	names := toolCallNames(calls)
+	resolve := func(id, decision string) string {
+		_ = p.RecordStep(ctx, sessionID, "TOOL_APPROVAL_RESOLVED", map[string]any{"approval_id": id, "tools": names, "turn": turn, "decision": decision})
+		return decision
+	}
+	if p.redis == nil {
+		return resolve("", approvalUnavailable)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return resolve("", approvalUnavailable)
+	}
+	id := hex.EncodeToString(buf)
+	timeout := p.cfg.ApprovalTimeout
+	// The marker outlives the wait slightly so late decisions are rejected cleanly.
+	if err := p.redis.Set(ctx, p.approvalKey(id), sessionID, timeout+time.Minute).Err(); err != nil {
+		return resolve(id, approvalUnavailable)
+	}
+	defer p.redis.Del(context.WithoutCancel(ctx), p.approvalKey(id), p.approvalReplyKey(id))
+
+	_ = p.RecordStep(ctx, sessionID, "TOOL_APPROVAL_REQUESTED", map[string]any{"approval_id": id, "tools": calls, "turn": turn, "timeout_seconds": int(timeout.Seconds())})
+	_ = p.publishStatus(ctx, sessionID, "APPROVAL_REQUESTED", map[string]any{
+		"approval_id":     id,
+		"tools":           calls,
+		"timeout_seconds": int(timeout.Seconds()),
+	})
+
+	deadline := time.Now().Add(timeout)
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return resolve(id, approvalTimeout)
+		}
+		if wait > approvalPollInterval {
+			wait = approvalPollInterval
+		}
+		reply, err := p.redis.BLPop(ctx, wait, p.approvalReplyKey(id)).Result()
+		switch {
+		case err == nil && len(reply) == 2:
+			if reply[1] == approvalApproved {
+				return resolve(id, approvalApproved)
+			}
+			return resolve(id, approvalDenied)
+		case errors.Is(err, redis.Nil):
+			// No decision yet.
+		case ctx.Err() != nil:
+			return resolve(id, approvalTimeout)
+		case err != nil:
+			return resolve(id, approvalUnavailable)
+		}
+	}
+}
+
+// ResolveApproval delivers an approve/deny decision to a run waiting in
+// awaitApproval (AGENT_APPROVAL_MODE=wait).
+func (p *Planner) ResolveApproval(ctx context.Context, id string, approved bool) error {
+	if p == nil || p.redis == nil {
+		return errRedisUnavailable
+	}
+	n, err := p.redis.Exists(ctx, p.approvalKey(id)).Result()
+	if err != nil {
+		return fmt.Errorf("look up approval request: %w", err)
+	}
+	if n == 0 {
+		return ErrApprovalNotFound
+	}
+	decision := approvalDenied
+	if approved {
+		decision = approvalApproved
+	}
+	pipe := p.redis.TxPipeline()
+	pipe.RPush(ctx, p.approvalReplyKey(id), decision)
+	pipe.Expire(ctx, p.approvalReplyKey(id), time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("deliver approval decision: %w", err)
+	}
+	return nil
+}
+
+// buildApprovalRejectedMessage tells the model the tools were not approved.
+func buildApprovalRejectedMessage(names []string, decision string) string {
+	return fmt.Sprintf("Tool error: %s was not approved (%s); answer without it.", strings.Join(names, ", "), decision)
+}
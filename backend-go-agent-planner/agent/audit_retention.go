@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"backend-go-agent-planner/audit"
+)
+
+// auditVacuumInterval is the minimum time between VACUUMs of the audit database.
+// VACUUM rewrites the whole file, so it runs far less often than pruning.
+const auditVacuumInterval = 24 * time.Hour
+
+// runAuditPruning deletes audit steps older than retention every interval until
+// ctx is done, and periodically vacuums the database to reclaim the freed space.
+func runAuditPruning(ctx context.Context, db *audit.AuditDB, retention, interval time.Duration, lg *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastVacuum time.Time
+	pruned := int64(0)
+	for {
+		cutoff := time.Now().Add(-retention)
+		n, err := db.PruneOlderThan(ctx, cutoff)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			lg.Warn("audit_prune_failed", "cutoff", cutoff.UTC(), "pruned", n, "error", err)
+		default:
+			lg.Info("audit_pruned", "cutoff", cutoff.UTC(), "pruned", n)
+		}
+		pruned += n
+		if pruned > 0 && time.Since(lastVacuum) >= auditVacuumInterval {
+			if err := db.Vacuum(ctx); err != nil {
+				lg.Warn("audit_vacuum_failed", "error", err)
+			} else {
+				lastVacuum = time.Now()
+				pruned = 0
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
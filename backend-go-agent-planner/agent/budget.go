@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// errRedisUnavailable is returned by Redis-backed helpers when no client is configured.
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// errTokenBudgetExceeded is returned by a plan call when the run has used up
+// Config.MaxTotalTokens; the run ends with OutcomeTokenBudget.
+var errTokenBudgetExceeded = errors.New("token budget exceeded")
+
+// sessionToolCallsKey is the Redis key holding a session's cumulative tool-call count.
+func (p *Planner) sessionToolCallsKey(sessionID string) string {
+	return p.cfg.NotifyPrefix + "pagi:session:" + sessionID + ":tool_calls"
+}
+
+// sessionToolCalls returns the cumulative number of tool calls made by a session.
+func (p *Planner) sessionToolCalls(ctx context.Context, sessionID string) (int, error) {
+	if p == nil || p.redis == nil {
+		return 0, errRedisUnavailable
+	}
+	n, err := p.redis.Get(ctx, p.sessionToolCallsKey(sessionID)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get session tool calls: %w", err)
+	}
+	return n, nil
+}
+
+// incrSessionToolCalls records one more tool call for the session and refreshes the key TTL.
+func (p *Planner) incrSessionToolCalls(ctx context.Context, sessionID string) (int, error) {
+	if p == nil || p.redis == nil {
+		return 0, errRedisUnavailable
+	}
+	key := p.sessionToolCallsKey(sessionID)
+	pipe := p.redis.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	if p.cfg.SessionToolCallTTL > 0 {
+		pipe.Expire(ctx, key, p.cfg.SessionToolCallTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("incr session tool calls: %w", err)
+	}
+	return int(incr.Val()), nil
+}
+
+// toolCallBudgetExhausted reports whether the session reached its tool-call cap.
+// It fails open (returns false) when the cap is disabled or Redis is unavailable.
+func (p *Planner) toolCallBudgetExhausted(ctx context.Context, sessionID string) bool {
+	if p.cfg.SessionToolCallCap <= 0 {
+		return false
+	}
+	used, err := p.sessionToolCalls(ctx, sessionID)
+	if err != nil {
+		return false
+	}
+	return used >= p.cfg.SessionToolCallCap
+}
+
+// fillToolCallBudget populates res with the session's tool-call usage. The fields
+// stay nil when Redis is unavailable, and ToolCallsRemaining stays nil without a cap.
+func (p *Planner) fillToolCallBudget(ctx context.Context, sessionID string, res *RunResult) {
+	used, err := p.sessionToolCalls(ctx, sessionID)
+	if err != nil {
+		return
+	}
+	res.ToolCallsUsed = &used
+	if p.cfg.SessionToolCallCap > 0 {
+		remaining := p.cfg.SessionToolCallCap - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		res.ToolCallsRemaining = &remaining
+	}
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"backend-go-model-gateway/jsonextract"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// citationsInstruction asks the model to cite the RAG matches its answer uses
+// (AGENT_CITATIONS_ENABLED).
+const citationsInstruction = "\n<citation_policy>\nWhen your final answer uses entries from <rag_context>, add a \"citations\" array with their IDs " +
+	"to the final answer JSON, e.g. {\"final_answer\": \"...\", \"citations\": [\"<ID>\"]}. Only cite IDs listed in <rag_context>.\n</citation_policy>\n"
+
+// Source is a RAG match cited by the final answer.
+type Source struct {
+	ID            string `json:"id"`
+	KnowledgeBase string `json:"knowledge_base,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Text          string `json:"text"`
+}
+
+// withCitationsInstruction appends the citation policy when Config.CitationsEnabled is set.
+func (p *Planner) withCitationsInstruction(plannerInput string) string {
+	if !p.cfg.CitationsEnabled {
+		return plannerInput
+	}
+	return plannerInput + citationsInstruction
+}
+
+// rememberMatches adds the RAG matches injected into the prompt to injected, by ID.
+func rememberMatches(injected map[string]*pb.RAGMatch, rag *pb.RAGContextResponse) {
+	for _, m := range rag.GetMatches() {
+		if m.GetId() != "" {
+			injected[m.GetId()] = m
+		}
+	}
+}
+
+// parseCitations returns the "citations" array of a final answer. Like
+// parseFinalAnswer, it also inspects the text of a single wrapped plan step.
+func parseCitations(planJSON string) []string {
+	texts := []string{planJSON}
+	var plan struct {
+		Steps []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plan); err == nil && len(plan.Steps) == 1 {
+		texts = append(texts, plan.Steps[0])
+	}
+	for _, text := range texts {
+		candidates := append([]string{jsonextract.StripFences(text)}, jsonextract.Candidates(text)...)
+		for _, c := range candidates {
+			var obj struct {
+				Citations []string `json:"citations"`
+			}
+			if err := json.Unmarshal([]byte(c), &obj); err == nil && len(obj.Citations) > 0 {
+				return obj.Citations
+			}
+		}
+	}
+	return nil
+}
+
+// resolveCitations validates the citations of the final plan against the matches
+// injected during the run. Cited matches are returned as sources; IDs that were
+// never injected are returned as hallucinated. Both are recorded as a CITATIONS
+// audit event.
+func (p *Planner) resolveCitations(ctx context.Context, sessionID, plan string, injected map[string]*pb.RAGMatch) (sources []Source, hallucinated []string) {
+	cited := parseCitations(plan)
+	if len(cited) == 0 {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	var valid []string
+	for _, id := range cited {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		m, ok := injected[id]
+		if !ok {
+			hallucinated = append(hallucinated, id)
+			continue
+		}
+		valid = append(valid, id)
+		sources = append(sources, Source{ID: id, KnowledgeBase: m.GetKnowledgeBase(), Source: m.GetSource(), Text: m.GetText()})
+	}
+	_ = p.RecordStep(ctx, sessionID, "CITATIONS", map[string]any{"cited": cited, "valid": valid, "hallucinated": hallucinated})
+	return sources, hallucinated
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrConfirmationNotFound is returned for an unknown, already used or expired
+// confirmation token.
+var ErrConfirmationNotFound = errors.New("confirmation not found or expired")
+
+// pendingConfirmation is the state of a run paused before a tool that requires
+// confirmation (Config.ApprovalRequiredTools), persisted in Redis until the client
+// confirms or Config.ConfirmationTTL expires.
+type pendingConfirmation struct {
+	Request RunRequest `json:"request"`
+	// Turn is the turn that produced Tools; the resumed run continues after it.
+	Turn  int        `json:"turn"`
+	Plan  string     `json:"plan"`
+	Tools []ToolCall `json:"tools"`
+	// Prompt, Playbook, HadToolStep and ExecutedCalls are the loop state when the run paused.
+	Prompt        string              `json:"prompt"`
+	Playbook      []map[string]string `json:"playbook"`
+	HadToolStep   bool                `json:"had_tool_step"`
+	ExecutedCalls map[string]int      `json:"executed_calls,omitempty"`
+	// Result carries tool outcomes and token usage accumulated so far.
+	Result RunResult `json:"result"`
+}
+
+// resumeState resumes a paused run with the client's decision.
+type resumeState struct {
+	pending  *pendingConfirmation
+	approved bool
+}
+
+// confirmationKey is the Redis key holding a pending confirmation.
+func (p *Planner) confirmationKey(token string) string {
+	return p.cfg.NotifyPrefix + "pagi:confirmation:" + token
+}
+
+// requiresConfirmation reports whether any of the calls is to a tool listed in
+// AGENT_APPROVAL_REQUIRED_TOOLS; a turn's calls are confirmed together.
+func (p *Planner) requiresConfirmation(calls []ToolCall) bool {
+	for _, c := range calls {
+		for _, t := range p.cfg.ApprovalRequiredTools {
+			if t == c.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// savePendingConfirmation stores pc under a new random token.
+func (p *Planner) savePendingConfirmation(ctx context.Context, pc *pendingConfirmation) (string, error) {
+	if p == nil || p.redis == nil {
+		return "", errRedisUnavailable
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	b, err := json.Marshal(pc)
+	if err != nil {
+		return "", fmt.Errorf("encode pending confirmation: %w", err)
+	}
+	if err := p.redis.Set(ctx, p.confirmationKey(token), b, p.cfg.ConfirmationTTL).Err(); err != nil {
+		return "", fmt.Errorf("store pending confirmation: %w", err)
+	}
+	return token, nil
+}
+
+// takePendingConfirmation loads and deletes the confirmation, so a token can only
+// be used once.
+func (p *Planner) takePendingConfirmation(ctx context.Context, token string) (*pendingConfirmation, error) {
+	if p == nil || p.redis == nil {
+		return nil, errRedisUnavailable
+	}
+	b, err := p.redis.GetDel(ctx, p.confirmationKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrConfirmationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load pending confirmation: %w", err)
+	}
+	var pc pendingConfirmation
+	if err := json.Unmarshal(b, &pc); err != nil {
+		return nil, fmt.Errorf("decode pending confirmation: %w", err)
+	}
+	return &pc, nil
+}
+
+// ConfirmRun resumes a run paused with OutcomePendingConfirmation. When approved
+// the pending tool calls run; otherwise the denial is fed back to the model. The run
+// then continues like AgentLoop.
+func (p *Planner) ConfirmRun(ctx context.Context, token string, approved bool) (*RunResult, error) {
+	pc, err := p.takePendingConfirmation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return p.agentLoop(ctx, pc.Request, &resumeState{pending: pc, approved: approved})
+}
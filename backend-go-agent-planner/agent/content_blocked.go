@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// contentBlockedReason matches the ErrorInfo reason the model gateway attaches when
+// the LLM provider refuses a request for content-policy reasons.
+const contentBlockedReason = "CONTENT_BLOCKED"
+
+// contentBlockedMessage is the run result reported for OutcomeContentBlocked.
+const contentBlockedMessage = "The request was blocked by the model provider's content policy."
+
+// isContentBlocked reports whether err is the gateway's content-policy status.
+func isContentBlocked(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() == contentBlockedReason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// emptyGenerationReason matches the ErrorInfo reason the model gateway attaches
+// when the provider returned no choices or only whitespace content.
+const emptyGenerationReason = "EMPTY_GENERATION"
+
+// ErrEmptyGeneration is returned by AgentLoop when the model kept returning empty
+// generations after Config.EmptyGenerationRetries retries.
+var ErrEmptyGeneration = errors.New("model returned an empty generation")
+
+// isEmptyGeneration reports whether err is the gateway's empty-generation status.
+func isEmptyGeneration(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() == emptyGenerationReason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultOutputFilterMessage = "I'm sorry, but I can't provide a response to that request."
+
+// outputFilter is the post-generation compliance filter applied to final answers.
+//
+// Only a regex denylist is supported today; the model gateway does not expose a
+// moderation endpoint yet.
+type outputFilter struct {
+	patterns []*regexp.Regexp
+	message  string
+}
+
+// splitPatterns splits a ";"-separated pattern list, dropping empty entries.
+func splitPatterns(spec string) []string {
+	var out []string
+	for _, p := range strings.Split(spec, ";") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// newOutputFilter compiles the deny patterns. It returns nil when the filter is
+// disabled or has no patterns.
+func newOutputFilter(enabled bool, patterns []string, message string) (*outputFilter, error) {
+	if !enabled || len(patterns) == 0 {
+		return nil, nil
+	}
+	f := &outputFilter{message: message}
+	if strings.TrimSpace(f.message) == "" {
+		f.message = defaultOutputFilterMessage
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("compile AGENT_OUTPUT_DENY_PATTERNS entry %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// match returns the first deny pattern matching text.
+func (f *outputFilter) match(text string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
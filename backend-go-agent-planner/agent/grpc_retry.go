@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grpcRetryInitialBackoff = 100 * time.Millisecond
+	grpcRetryMaxBackoff     = 2 * time.Second
+)
+
+// isTransientGRPC reports whether err is an Unavailable or DeadlineExceeded status
+// worth retrying: the caller's context is still live and the gateway did not
+// report an empty generation (which has its own retry policy).
+func isTransientGRPC(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || isEmptyGeneration(err) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// isTransportGRPC reports whether err is an Unavailable status, i.e. the call
+// failed to reach the server. Tool calls only retry these: a DeadlineExceeded
+// tool may already have run, and retrying would repeat its side effects.
+func isTransportGRPC(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && status.Code(err) == codes.Unavailable
+}
+
+// withGRPCRetry calls fn, retrying up to Config.GRPCMaxRetries times with
+// exponential backoff while retryable(err) holds (AGENT_GRPC_MAX_RETRIES).
+// Waiting stops as soon as ctx is done.
+func withGRPCRetry[T any](ctx context.Context, p *Planner, dependency string, retryable func(context.Context, error) bool, fn func() (T, error)) (T, error) {
+	backoff := grpcRetryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || attempt > p.cfg.GRPCMaxRetries || !retryable(ctx, err) {
+			return out, err
+		}
+		logger.NewContextLogger(ctx).Warn("grpc_retry", "dependency", dependency, "attempt", attempt, "backoff_ms", backoff.Milliseconds(), "error", err)
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > grpcRetryMaxBackoff {
+			backoff = grpcRetryMaxBackoff
+		}
+	}
+}
+
+// retried wraps fn with withGRPCRetry.
+func retried[T any](ctx context.Context, p *Planner, dependency string, retryable func(context.Context, error) bool, fn func() (T, error)) func() (T, error) {
+	return func() (T, error) { return withGRPCRetry(ctx, p, dependency, retryable, fn) }
+}
@@ -0,0 +1,47 @@
+package agent
+
+import "unicode/utf8"
+
+// historyOmittedMarker replaces history entries dropped by trimHistory.
+const historyOmittedMarker = "[...older messages omitted...]"
+
+// estimateTokens approximates the token count of text as one token per four
+// characters, which is close enough for budgeting prompt sections.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// historyEntryTokens estimates the tokens an entry adds to the planner prompt.
+func historyEntryTokens(m map[string]any) int {
+	role, _ := m["role"].(string)
+	content, _ := m["content"].(string)
+	return estimateTokens(role + ": " + content + "\n")
+}
+
+// trimHistory keeps the most recent history entries that fit within maxTokens
+// (estimated with count) and maxMessages; 0 disables a limit. It returns the kept
+// entries, oldest first, and how many older entries were dropped.
+func trimHistory(history []map[string]any, maxTokens, maxMessages int, count func(map[string]any) int) ([]map[string]any, int) {
+	start := len(history)
+	used := 0
+	for start > 0 {
+		if maxMessages > 0 && len(history)-start >= maxMessages {
+			break
+		}
+		n := count(history[start-1])
+		if maxTokens > 0 && used+n > maxTokens {
+			break
+		}
+		used += n
+		start--
+	}
+	return history[start:], start
+}
+
+// withOmittedMarker prepends historyOmittedMarker when older entries were dropped.
+func withOmittedMarker(history []map[string]any, omitted int) []map[string]any {
+	if omitted == 0 {
+		return history
+	}
+	return append([]map[string]any{{"role": "system", "content": historyOmittedMarker}}, history...)
+}
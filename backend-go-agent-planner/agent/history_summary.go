@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+const (
+	// historySummaryInputChars bounds the messages sent for one summarization.
+	historySummaryInputChars = 8000
+	// historySummaryMaxChars bounds the stored history summary.
+	historySummaryMaxChars = 2000
+	// historySummaryPrefix introduces the summary in the planner prompt.
+	historySummaryPrefix = "Summary of earlier messages: "
+)
+
+// historySummary is a session's cached summary of its oldest history entries.
+// Count and Hash identify the history prefix it covers, so a grown overflow only
+// summarizes the new entries.
+type historySummary struct {
+	Count   int    `json:"count"`
+	Hash    string `json:"hash"`
+	Summary string `json:"summary"`
+}
+
+// historySummaryKey is the Redis key caching a session's history summary.
+func (p *Planner) historySummaryKey(sessionID string) string {
+	return p.cfg.NotifyPrefix + "pagi:session:" + sessionID + ":history_summary"
+}
+
+// historyHash fingerprints history entries by role and content.
+func historyHash(entries []map[string]any) string {
+	h := sha256.New()
+	for _, m := range entries {
+		role, _ := m["role"].(string)
+		content, _ := m["content"].(string)
+		fmt.Fprintf(h, "%d:%s%d:%s", len(role), role, len(content), content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// summarizeHistory returns a summary of overflow, the history entries that do not
+// fit the prompt budget (AGENT_HISTORY_SUMMARIZE). The summary is cached per
+// session in Redis; when the cached summary covers a prefix of overflow, only the
+// newer entries are summarized together with it. cached reports whether the
+// cached summary was used unchanged.
+func (p *Planner) summarizeHistory(ctx context.Context, sessionID string, overflow []map[string]any) (summary string, cached bool, err error) {
+	var prev historySummary
+	if p.redis != nil {
+		if b, getErr := p.redis.Get(ctx, p.historySummaryKey(sessionID)).Bytes(); getErr == nil {
+			_ = json.Unmarshal(b, &prev)
+		}
+	}
+	if prev.Count > len(overflow) || prev.Summary == "" || historyHash(overflow[:prev.Count]) != prev.Hash {
+		prev = historySummary{}
+	}
+	if prev.Count == len(overflow) {
+		return prev.Summary, true, nil
+	}
+
+	summary, err = p.generateHistorySummary(ctx, prev.Summary, overflow[prev.Count:])
+	if err != nil {
+		return "", false, err
+	}
+	if p.redis != nil {
+		b, _ := json.Marshal(historySummary{Count: len(overflow), Hash: historyHash(overflow), Summary: summary})
+		_ = p.redis.Set(ctx, p.historySummaryKey(sessionID), b, p.cfg.SummaryTTL).Err()
+	}
+	return summary, false, nil
+}
+
+// generateHistorySummary asks the Model Gateway to fold entries into the previous
+// summary (empty for the first summarization).
+func (p *Planner) generateHistorySummary(ctx context.Context, previous string, entries []map[string]any) (string, error) {
+	if p.modelClient == nil {
+		return "", fmt.Errorf("model client is nil")
+	}
+	var b strings.Builder
+	b.WriteString("Summarize the earlier part of this conversation in one short paragraph. Keep facts, decisions, " +
+		"user preferences and open questions; drop pleasantries. Reply with the summary only.\n\n")
+	if previous != "" {
+		b.WriteString("Summary so far:\n" + previous + "\n\n")
+	}
+	b.WriteString("Messages:\n")
+	for _, m := range entries {
+		role, _ := m["role"].(string)
+		content, _ := m["content"].(string)
+		b.WriteString(role + ": " + content + "\n")
+	}
+	input := truncateRunes(b.String(), historySummaryInputChars)
+	resp, err := p.modelClient.GetPlan(injectTraceIDToOutgoingGRPC(ctx), &pb.PlanRequest{Prompt: input, Model: p.cfg.SummaryModel})
+	if err != nil {
+		return "", fmt.Errorf("summarize history: %w", err)
+	}
+	summary := resp.GetPlan()
+	if steps := resp.GetSteps(); len(steps) > 0 {
+		summary = strings.Join(steps, " ")
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("summarize history: empty summary")
+	}
+	return truncateRunes(summary, historySummaryMaxChars), nil
+}
+
+// withHistorySummary prepends summary to the kept history entries.
+func withHistorySummary(history []map[string]any, summary string) []map[string]any {
+	return append([]map[string]any{{"role": "system", "content": historySummaryPrefix + summary}}, history...)
+}
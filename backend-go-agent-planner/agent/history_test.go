@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrimHistory_KeepsMostRecentWithinBudget(t *testing.T) {
+	history := []map[string]any{
+		{"role": "user", "content": strings.Repeat("a", 40)},
+		{"role": "assistant", "content": strings.Repeat("b", 40)},
+		{"role": "user", "content": strings.Repeat("c", 40)},
+		{"role": "assistant", "content": "short"},
+	}
+	count := func(m map[string]any) int { return estimateTokens(m["content"].(string)) }
+
+	kept, omitted := trimHistory(history, 15, 0, count)
+	if omitted != 2 || len(kept) != 2 || kept[0]["content"] != strings.Repeat("c", 40) {
+		t.Fatalf("unexpected trim by tokens: omitted=%d kept=%v", omitted, kept)
+	}
+	if kept, omitted := trimHistory(history, 0, 1, count); omitted != 3 || kept[0]["content"] != "short" {
+		t.Fatalf("unexpected trim by messages: omitted=%d kept=%v", omitted, kept)
+	}
+	if _, omitted := trimHistory(history, 0, 0, count); omitted != 0 {
+		t.Fatalf("expected no trimming without limits, omitted=%d", omitted)
+	}
+
+	prompt := buildPlannerPrompt("q", withOmittedMarker(kept, omitted), nil)
+	if !strings.Contains(prompt, historyOmittedMarker) || strings.Contains(prompt, "aaaa") {
+		t.Fatalf("expected marker instead of old messages, got:\n%s", prompt)
+	}
+}
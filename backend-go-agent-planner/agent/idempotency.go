@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrIdempotencyInProgress is returned by BeginIdempotent while the first request
+// with the same idempotency key is still running.
+var ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+
+// IdempotentResponse is the HTTP response stored for a completed request and
+// replayed for repeats of its idempotency key.
+type IdempotentResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// idempotencyRecord is the Redis value for an idempotency key: in progress until
+// Response is set.
+type idempotencyRecord struct {
+	Response *IdempotentResponse `json:"response,omitempty"`
+}
+
+// idempotencyKey is the Redis key for a client's idempotency key, scoped to the
+// session. The client's key is hashed to keep the Redis key bounded.
+func (p *Planner) idempotencyKey(sessionID, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return p.cfg.NotifyPrefix + "pagi:idempotency:" + sessionID + ":" + hex.EncodeToString(sum[:])
+}
+
+// BeginIdempotent claims an idempotency key for a new run in sessionID. When the
+// key was already used it returns the stored response, or
+// ErrIdempotencyInProgress while that run has not finished. A nil response and
+// nil error mean the caller owns the key and must call FinishIdempotent.
+func (p *Planner) BeginIdempotent(ctx context.Context, sessionID, key string) (*IdempotentResponse, error) {
+	if p == nil || p.redis == nil || p.cfg.IdempotencyTTL <= 0 {
+		return nil, errRedisUnavailable
+	}
+	rk := p.idempotencyKey(sessionID, key)
+	claimed, err := p.redis.SetNX(ctx, rk, "{}", p.cfg.IdempotencyTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+	b, err := p.redis.Get(ctx, rk).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Released or expired between SETNX and GET; the client may retry.
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load idempotency key: %w", err)
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("decode idempotency key: %w", err)
+	}
+	if rec.Response == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+	return rec.Response, nil
+}
+
+// FinishIdempotent stores resp for a key claimed with BeginIdempotent, keeping
+// the original TTL. A nil resp releases the key so a retry runs again.
+func (p *Planner) FinishIdempotent(ctx context.Context, sessionID, key string, resp *IdempotentResponse) error {
+	if p == nil || p.redis == nil {
+		return errRedisUnavailable
+	}
+	rk := p.idempotencyKey(sessionID, key)
+	if resp == nil {
+		if err := p.redis.Del(ctx, rk).Err(); err != nil {
+			return fmt.Errorf("release idempotency key: %w", err)
+		}
+		return nil
+	}
+	b, err := json.Marshal(idempotencyRecord{Response: resp})
+	if err != nil {
+		return fmt.Errorf("encode idempotency response: %w", err)
+	}
+	if err := p.redis.SetXX(ctx, rk, b, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("store idempotency response: %w", err)
+	}
+	return nil
+}
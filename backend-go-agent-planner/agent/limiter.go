@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// toolLimiter bounds concurrent tool executions globally and per session.
+//
+// A tool execution must hold a session slot and a global slot. The session slot
+// is acquired first so a session waiting on its own limit does not tie up global
+// capacity that other sessions could use.
+type toolLimiter struct {
+	// global is nil when the global pool is unbounded.
+	global     chan struct{}
+	perSession int
+
+	mu       sync.Mutex
+	sessions map[string]*sessionSlots
+}
+
+// sessionSlots is a session's semaphore, reference-counted so idle sessions are released.
+type sessionSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// newToolLimiter returns a limiter; a limit <= 0 disables that dimension.
+func newToolLimiter(global, perSession int) *toolLimiter {
+	l := &toolLimiter{perSession: perSession, sessions: map[string]*sessionSlots{}}
+	if global > 0 {
+		l.global = make(chan struct{}, global)
+	}
+	return l
+}
+
+// acquire blocks until both slots are available (or ctx is done) and returns a
+// release func that must be called once the tool execution finishes.
+func (l *toolLimiter) acquire(ctx context.Context, sessionID string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	var slots *sessionSlots
+	if l.perSession > 0 {
+		l.mu.Lock()
+		slots = l.sessions[sessionID]
+		if slots == nil {
+			slots = &sessionSlots{sem: make(chan struct{}, l.perSession)}
+			l.sessions[sessionID] = slots
+		}
+		slots.refs++
+		l.mu.Unlock()
+
+		select {
+		case slots.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.releaseSession(sessionID, slots, false)
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.global != nil {
+		select {
+		case l.global <- struct{}{}:
+		case <-ctx.Done():
+			if slots != nil {
+				l.releaseSession(sessionID, slots, true)
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.global != nil {
+				<-l.global
+			}
+			if slots != nil {
+				l.releaseSession(sessionID, slots, true)
+			}
+		})
+	}, nil
+}
+
+func (l *toolLimiter) releaseSession(sessionID string, slots *sessionSlots, held bool) {
+	if held {
+		<-slots.sem
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.refs--
+	if slots.refs == 0 {
+		delete(l.sessions, sessionID)
+	}
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Response locale modes (AGENT_RESPONSE_LOCALE_MODE).
+//   - off:   no language instruction is added (default).
+//   - fixed: respond in the request locale, else AGENT_RESPONSE_LOCALE.
+//   - auto:  respond in the request locale, else the language detected from the prompt.
+const (
+	localeModeOff   = "off"
+	localeModeFixed = "fixed"
+	localeModeAuto  = "auto"
+)
+
+// Locale sources recorded in the PLAN_START audit event.
+const (
+	localeSourceRequest  = "request"
+	localeSourceConfig   = "config"
+	localeSourceDetected = "detected"
+)
+
+var localeRe = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// languageNames maps primary language subtags to the name used in the prompt instruction.
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// stopwords are frequent function words used to tell Latin-script languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "what", "how", "to", "of", "in", "for", "with", "please"},
+	"es": {"el", "la", "los", "las", "que", "de", "y", "es", "en", "por", "para", "cómo", "qué", "una"},
+	"fr": {"le", "la", "les", "des", "est", "et", "que", "pour", "dans", "avec", "une", "comment", "quel"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "wie", "was", "ein", "eine", "ich"},
+	"pt": {"o", "os", "as", "que", "de", "e", "é", "em", "para", "com", "uma", "não", "como", "você"},
+	"it": {"il", "lo", "gli", "che", "di", "e", "è", "per", "con", "una", "come", "non", "sono"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "van", "voor", "met", "hoe", "wat", "ik"},
+}
+
+// ValidateLocale checks a request locale (BCP 47 style, e.g. "es" or "pt-BR").
+func ValidateLocale(locale string) error {
+	if locale == "" || localeRe.MatchString(locale) {
+		return nil
+	}
+	return fmt.Errorf("locale %q is not a valid language tag (e.g. \"es\" or \"pt-BR\")", locale)
+}
+
+// resolveLocale picks the response locale for a run according to mode, returning
+// the locale and where it came from ("" when no instruction should be added).
+func resolveLocale(mode, requested, configured, prompt string) (string, string) {
+	switch mode {
+	case localeModeFixed:
+		if requested != "" {
+			return requested, localeSourceRequest
+		}
+		if configured != "" {
+			return configured, localeSourceConfig
+		}
+	case localeModeAuto:
+		if requested != "" {
+			return requested, localeSourceRequest
+		}
+		if detected := detectLanguage(prompt); detected != "" {
+			return detected, localeSourceDetected
+		}
+	}
+	return "", ""
+}
+
+// detectLanguage guesses the language of text, returning a language subtag or ""
+// when unsure. Non-Latin scripts are identified by script; Latin-script text by
+// stopword frequency.
+func detectLanguage(text string) string {
+	scripts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Kana marks Japanese even when most characters are Han.
+	if scripts["ja"] > 0 {
+		return "ja"
+	}
+	best, bestN := "", 0
+	for lang, n := range scripts {
+		if n > bestN {
+			best, bestN = lang, n
+		}
+	}
+	if bestN*2 > letters {
+		return best
+	}
+
+	counts := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for lang, words := range stopwords {
+			for _, sw := range words {
+				if w == sw {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, bestN, tie := "", 0, false
+	for lang, n := range counts {
+		switch {
+		case n > bestN:
+			best, bestN, tie = lang, n, false
+		case n == bestN:
+			tie = true
+		}
+	}
+	if bestN < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// languageName returns a human-readable language for locale, or the locale itself.
+func languageName(locale string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	if name, ok := languageNames[primary]; ok {
+		return name
+	}
+	return locale
+}
+
+// withLocaleInstruction appends a response-language instruction to the planner input.
+func withLocaleInstruction(plannerInput, locale string) string {
+	if locale == "" {
+		return plannerInput
+	}
+	return plannerInput + "\n<response_language>\nRespond in " + languageName(locale) + " (" + locale + ").\n</response_language>\n"
+}
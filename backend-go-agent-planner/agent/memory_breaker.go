@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sony/gobreaker"
+)
+
+// withMemoryBreaker runs a memory service HTTP call through the memory circuit
+// breaker shared with GetRAGContext, so failures of either count toward
+// AGENT_MEMORY_BREAKER_THRESHOLD.
+func (p *Planner) withMemoryBreaker(call func() error) error {
+	if p.memoryBreaker == nil {
+		return call()
+	}
+	_, err := p.memoryBreaker.Execute(func() (any, error) {
+		return nil, call()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return fmt.Errorf("memory service circuit open: %w", err)
+	}
+	return err
+}
+
+// memoryCircuitOpen reports whether the memory circuit breaker is open, i.e.
+// memory calls are being skipped until AGENT_MEMORY_BREAKER_COOLDOWN_SECONDS pass.
+func (p *Planner) memoryCircuitOpen() bool {
+	return p.memoryBreaker != nil && p.memoryBreaker.State() == gobreaker.StateOpen
+}
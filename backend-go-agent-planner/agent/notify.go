@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Notification backends (NOTIFY_BACKEND).
+const (
+	notifyBackendPubSub  = "pubsub"
+	notifyBackendStreams = "streams"
+)
+
+// notificationsChannel is the Redis channel notifications are published on:
+// NOTIFY_PREFIX followed by NOTIFY_CHANNEL.
+func (p *Planner) notificationsChannel() string {
+	return p.cfg.NotifyPrefix + p.cfg.NotifyChannel
+}
+
+// notificationsStream is the Redis Stream written with NOTIFY_BACKEND=streams.
+func (p *Planner) notificationsStream() string {
+	return p.notificationsChannel() + ":stream"
+}
+
+// Event types of notifications; stream entries carry them as event_type.
+const (
+	eventTypeStatus = "STATUS"
+	eventTypeResult = "RESULT"
+)
+
+// undeliveredEvent is a notification that could not be published to Redis.
+type undeliveredEvent struct {
+	eventType string
+	message   string
+}
+
+// notificationPayload builds the JSON payload shared by status and result
+// notifications: trace_id, session_id and timestamp, then fields and extra.
+func notificationPayload(ctx context.Context, sessionID string, fields, extra map[string]any) string {
+	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
+	payload := map[string]any{
+		"trace_id":   traceID,
+		"session_id": sessionID,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	b, _ := json.Marshal(payload)
+	return string(b)
+}
+
+// notifyTarget names where notifications go, for logs.
+func (p *Planner) notifyTarget() string {
+	if p.cfg.NotifyBackend == notifyBackendStreams {
+		return p.notificationsStream()
+	}
+	return p.notificationsChannel()
+}
+
+// send delivers one notification: PUBLISH on the notifications channel, or with
+// NOTIFY_BACKEND=streams an XADD to the notifications stream, whose entry IDs
+// let consumers resume with XREAD from the last ID they saw.
+func (p *Planner) send(ctx context.Context, ev undeliveredEvent) error {
+	if p.cfg.NotifyBackend != notifyBackendStreams {
+		return p.redis.Publish(ctx, p.notificationsChannel(), ev.message).Err()
+	}
+	args := &redis.XAddArgs{
+		Stream: p.notificationsStream(),
+		Values: map[string]any{"event_type": ev.eventType, "payload": ev.message},
+	}
+	if p.cfg.NotifyStreamMaxLen > 0 {
+		args.MaxLen = p.cfg.NotifyStreamMaxLen
+		args.Approx = true
+	}
+	return p.redis.XAdd(ctx, args).Err()
+}
+
+// publish sends a notification to Redis, retrying transient failures.
+//
+// When retries are exhausted the event is kept in a bounded local queue and
+// re-sent (in order, ahead of newer events) once a later publish succeeds or
+// the Redis health check sees Redis recover.
+func (p *Planner) publish(ctx context.Context, eventType string, message string) error {
+	if p == nil || p.redis == nil {
+		return nil
+	}
+	lg := logger.NewContextLogger(ctx)
+	ev := undeliveredEvent{eventType: eventType, message: message}
+
+	if err := p.flushUndelivered(ctx); err != nil {
+		p.bufferUndelivered(ctx, ev)
+		lg.Warn("redis_publish_failed", "channel", p.notifyTarget(), "stage", "flush", "buffered", p.undeliveredLen(), "error", err)
+		return err
+	}
+
+	attempts := p.cfg.PublishMaxRetries + 1
+	if attempts < 1 || !p.redisAvailable() {
+		// While Redis is known to be down, try once instead of stalling the run on retries.
+		attempts = 1
+	}
+	backoff := 100 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = p.send(ctx, ev); err == nil {
+			return nil
+		}
+		if attempt == attempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	p.bufferUndelivered(ctx, ev)
+	lg.Warn("redis_publish_failed", "channel", p.notifyTarget(), "attempts", attempts, "buffered", p.undeliveredLen(), "error", err)
+	return err
+}
+
+// bufferUndelivered queues an event for later delivery, dropping the oldest
+// event when the queue is full.
+func (p *Planner) bufferUndelivered(ctx context.Context, ev undeliveredEvent) {
+	if p.cfg.PublishBufferSize <= 0 {
+		return
+	}
+	p.pubMu.Lock()
+	defer p.pubMu.Unlock()
+	if len(p.undelivered) >= p.cfg.PublishBufferSize {
+		p.undelivered = p.undelivered[1:]
+		logger.NewContextLogger(ctx).Warn("redis_publish_buffer_overflow", "capacity", p.cfg.PublishBufferSize)
+	}
+	p.undelivered = append(p.undelivered, ev)
+}
+
+func (p *Planner) undeliveredLen() int {
+	p.pubMu.Lock()
+	defer p.pubMu.Unlock()
+	return len(p.undelivered)
+}
+
+// flushUndelivered re-publishes buffered events in order. Events that still
+// cannot be delivered stay queued.
+func (p *Planner) flushUndelivered(ctx context.Context) error {
+	p.pubMu.Lock()
+	defer p.pubMu.Unlock()
+	if len(p.undelivered) == 0 {
+		return nil
+	}
+	sent := 0
+	for _, ev := range p.undelivered {
+		if err := p.send(ctx, ev); err != nil {
+			p.undelivered = p.undelivered[sent:]
+			return err
+		}
+		sent++
+	}
+	logger.NewContextLogger(ctx).Info("redis_publish_buffer_flushed", "events", sent)
+	p.undelivered = nil
+	return nil
+}
@@ -0,0 +1,38 @@
+package agent
+
+import "context"
+
+// eventTypeStep is the stream event type of per-step notifications.
+const eventTypeStep = "STEP"
+
+// verboseStepEvents are the loop milestones published with NOTIFY_VERBOSE. All
+// but TURN_START are audit steps, published with the audit record's data.
+var verboseStepEvents = map[string]bool{
+	"TURN_START":          true,
+	"PLAN_MODEL_RESPONSE": true,
+	"TOOL_CALL":           true,
+	"TOOL_RESULT":         true,
+	"TOOL_ERROR":          true,
+}
+
+type notifyTurnKey struct{}
+
+// withNotifyTurn tags ctx with the current loop turn for step notifications.
+func withNotifyTurn(ctx context.Context, turn int) context.Context {
+	return context.WithValue(ctx, notifyTurnKey{}, turn)
+}
+
+// publishStep publishes a loop milestone to notification subscribers when
+// NOTIFY_VERBOSE is set. The payload carries event_type, turn and data (the
+// audit step's data) next to the standard trace_id, session_id and timestamp.
+func (p *Planner) publishStep(ctx context.Context, sessionID, eventType string, data any) error {
+	if p == nil || p.redis == nil || !p.cfg.NotifyVerbose || !verboseStepEvents[eventType] {
+		return nil
+	}
+	turn, _ := ctx.Value(notifyTurnKey{}).(int)
+	return p.publish(ctx, eventTypeStep, notificationPayload(ctx, sessionID, map[string]any{
+		"event_type": eventType,
+		"turn":       turn,
+		"data":       data,
+	}, nil))
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// errToolSkipped is recorded for tool calls not run because an earlier call in a
+// fail_fast batch failed.
+var errToolSkipped = errors.New("skipped: an earlier tool call in this fail_fast batch failed")
+
+// toolBatchOptions reads the "parallel" and "fail_fast" flags of a multi-tool
+// envelope ({"tools": [...], "parallel": true, "fail_fast": true}).
+func toolBatchOptions(calls []ToolCall) (parallel, failFast bool) {
+	if len(calls) == 0 {
+		return false, false
+	}
+	parallel, _ = calls[0].Raw["parallel"].(bool)
+	failFast, _ = calls[0].Raw["fail_fast"].(bool)
+	return parallel, failFast
+}
+
+// executeToolsParallel runs exec for calls 0..n-1 with at most
+// Config.MaxParallelTools in flight, returning outputs and errors in call order.
+// A failure cancels the remaining calls only when failFast is set.
+func (p *Planner) executeToolsParallel(ctx context.Context, n int, failFast bool, exec func(ctx context.Context, i int) (string, error)) ([]string, []error) {
+	outs := make([]string, n)
+	errs := make([]error, n)
+	g, gctx := &errgroup.Group{}, ctx
+	if failFast {
+		g, gctx = errgroup.WithContext(ctx)
+	}
+	g.SetLimit(p.cfg.MaxParallelTools)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			if failFast && gctx.Err() != nil {
+				errs[i] = errToolSkipped
+				return nil
+			}
+			outs[i], errs[i] = exec(gctx, i)
+			if failFast {
+				return errs[i]
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return outs, errs
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExecuteToolsParallel_OrderAndIsolation(t *testing.T) {
+	p := &Planner{cfg: Config{MaxParallelTools: 2}}
+	outs, errs := p.executeToolsParallel(context.Background(), 3, false, func(_ context.Context, i int) (string, error) {
+		if i == 1 {
+			return "", errors.New("boom")
+		}
+		return fmt.Sprintf("out-%d", i), nil
+	})
+	if outs[0] != "out-0" || outs[2] != "out-2" {
+		t.Fatalf("unexpected outputs: %v", outs)
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Fatalf("a failure must not affect other calls without fail_fast: %v", errs)
+	}
+}
+
+func TestExecuteToolsParallel_FailFastCancels(t *testing.T) {
+	p := &Planner{cfg: Config{MaxParallelTools: 2}}
+	_, errs := p.executeToolsParallel(context.Background(), 2, true, func(ctx context.Context, i int) (string, error) {
+		if i == 0 {
+			return "", errors.New("boom")
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if errs[0] == nil || !(errors.Is(errs[1], context.Canceled) || errors.Is(errs[1], errToolSkipped)) {
+		t.Fatalf("expected the second call to be cancelled or skipped: %v", errs)
+	}
+}
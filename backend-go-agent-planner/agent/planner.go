@@ -12,18 +12,23 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"backend-go-agent-planner/audit"
 	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/jsonextract"
 	pb "backend-go-model-gateway/proto/proto"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sony/gobreaker"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -34,6 +39,22 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+// grpcClientCreds returns the transport credentials for gRPC dependencies: TLS
+// verified against GRPCTLSCA when set, plaintext only when GRPCAllowInsecure.
+func grpcClientCreds(cfg Config) (credentials.TransportCredentials, error) {
+	if cfg.GRPCTLSCA != "" {
+		creds, err := credentials.NewClientTLSFromFile(cfg.GRPCTLSCA, "")
+		if err != nil {
+			return nil, fmt.Errorf("load GRPC_TLS_CA (%s): %w", filepath.Clean(cfg.GRPCTLSCA), err)
+		}
+		return creds, nil
+	}
+	if cfg.GRPCAllowInsecure {
+		return insecure.NewCredentials(), nil
+	}
+	return nil, fmt.Errorf("no gRPC transport security configured: set GRPC_TLS_CA, or GRPC_ALLOW_INSECURE=true for plaintext")
+}
+
 func loadMTLSClientCredsForAddr(addr string) (credentials.TransportCredentials, bool, error) {
 	clientCertPath := os.Getenv("TLS_CLIENT_CERT_PATH")
 	clientKeyPath := os.Getenv("TLS_CLIENT_KEY_PATH")
@@ -91,9 +112,343 @@ type Config struct {
 	AuditDBPath         string
 	RedisAddr           string
 
+	// GatewayAPIKey is sent to the Model Gateway as x-api-key metadata (GATEWAY_API_KEY;
+	// when it lists several comma-separated keys, the first is used).
+	GatewayAPIKey string
+	// GRPCTLSCA is a CA bundle used to verify the gRPC servers (GRPC_TLS_CA). The
+	// model gateway uses mutual TLS instead when TLS_CLIENT_CERT_PATH etc. are set.
+	GRPCTLSCA string
+	// GRPCDialTimeout bounds connecting to each gRPC dependency in NewPlanner, so an
+	// unreachable address fails startup instead of surfacing on the first call
+	// (GRPC_DIAL_TIMEOUT_SECONDS, default 10, 0 = connect lazily).
+	GRPCDialTimeout time.Duration
+	// GRPCMaxRetries is how many times a memory, model gateway or tool call is
+	// retried after a transient failure, with exponential backoff
+	// (AGENT_GRPC_MAX_RETRIES). Memory and gateway calls retry Unavailable and
+	// DeadlineExceeded; tool calls retry only Unavailable so a tool that may have
+	// run is not executed twice.
+	GRPCMaxRetries int
+	// MemoryBreakerThreshold consecutive memory service failures (history, store
+	// or RAG) open the memory circuit breaker (AGENT_MEMORY_BREAKER_THRESHOLD).
+	// While it is open, turns skip memory calls and use empty history and RAG
+	// context for MemoryBreakerCooldown (AGENT_MEMORY_BREAKER_COOLDOWN_SECONDS).
+	MemoryBreakerThreshold int
+	MemoryBreakerCooldown  time.Duration
+	// GRPCAllowInsecure permits plaintext gRPC when GRPCTLSCA is unset
+	// (GRPC_ALLOW_INSECURE=true); otherwise NewPlanner refuses to dial.
+	GRPCAllowInsecure bool
+
+	// AuditMaxDataBytes caps each audit event's serialized data (AUDIT_MAX_DATA_BYTES, 0 = unlimited).
+	AuditMaxDataBytes int
+	// AuditMaxDataExemptEvents are event types never truncated (AUDIT_MAX_DATA_EXEMPT_EVENTS).
+	AuditMaxDataExemptEvents []string
+	// AuditRedact masks secrets and email addresses in audit payloads (AUDIT_REDACT).
+	AuditRedact bool
+	// AuditAsync writes audit events on a background goroutine (AUDIT_ASYNC, default true).
+	AuditAsync bool
+	// AuditQueueSize bounds the async audit queue (AUDIT_QUEUE_SIZE); events beyond it are dropped.
+	AuditQueueSize int
+	// AuditBatchSize is the most queued audit events written in one insert (AUDIT_BATCH_SIZE, default 100).
+	AuditBatchSize int
+	// AuditFlushInterval is how often a partial batch of queued audit events is
+	// written (AUDIT_FLUSH_INTERVAL_MS, default 200).
+	AuditFlushInterval time.Duration
+	// AuditSlowWrite is the write latency that counts as a failure for the audit
+	// circuit (AUDIT_SLOW_WRITE_MS, 0 = only errors count).
+	AuditSlowWrite time.Duration
+	// AuditDBDriver selects the audit store (PAGI_AUDIT_DB_DRIVER): sqlite (default,
+	// at AuditDBPath) or postgres (at AuditDBDSN), which replicas can share.
+	AuditDBDriver string
+	// AuditDBDSN is the Postgres connection string (PAGI_AUDIT_DB_DSN).
+	AuditDBDSN string
+	// AuditRetention is how long audit steps are kept (AUDIT_RETENTION_DAYS, 0 = keep forever).
+	AuditRetention time.Duration
+	// AuditPruneInterval is how often expired audit steps are pruned
+	// (AUDIT_PRUNE_INTERVAL_MINUTES, default 60).
+	AuditPruneInterval time.Duration
+
+	// SandboxesJSON optionally declares multiple tool sandboxes (AGENT_SANDBOXES,
+	// a JSON array of SandboxConfig). When empty, RustSandboxGRPCAddr is the only sandbox.
+	SandboxesJSON string
+	// ToolNameConflict is the policy for tools exposed by more than one sandbox
+	// (AGENT_TOOL_NAME_CONFLICT: error, first, namespaced).
+	ToolNameConflict string
+	// ToolTransport is how tool calls reach the sandboxes (TOOL_TRANSPORT: grpc,
+	// the default, or http for sandboxes only reachable through their HTTP API).
+	ToolTransport string
+	// SandboxPoolSize is the number of gRPC connections per sandbox (AGENT_SANDBOX_POOL_SIZE, default 1).
+	SandboxPoolSize int
+	// ToolConcurrency caps concurrent tool executions across all sessions
+	// (AGENT_TOOL_CONCURRENCY, 0 = unlimited).
+	ToolConcurrency int
+	// SessionToolConcurrency caps concurrent tool executions per session
+	// (AGENT_SESSION_TOOL_CONCURRENCY, default 2, 0 = unlimited).
+	SessionToolConcurrency int
+	// ToolArgsFlat rejects tool calls whose args contain nested objects or arrays
+	// (AGENT_TOOL_ARGS_FLAT); the correction is fed back to the model.
+	ToolArgsFlat bool
+	// ToolArgsNestedTools are tools exempt from ToolArgsFlat
+	// (AGENT_TOOL_ARGS_NESTED_TOOLS, comma-separated).
+	ToolArgsNestedTools []string
+	// MaxParallelTools caps concurrent executions of a multi-tool turn whose envelope
+	// sets "parallel": true (AGENT_MAX_PARALLEL_TOOLS, default 4, 1 = sequential).
+	// Per-session tool concurrency (AGENT_SESSION_TOOL_CONCURRENCY) still applies.
+	MaxParallelTools int
+	// CitationsEnabled asks the model to cite the RAG matches it used and attaches
+	// the cited sources to the result (AGENT_CITATIONS_ENABLED).
+	CitationsEnabled bool
+	// EmptyGenerationRetries is how many times a plan call is retried when the
+	// gateway reports an empty generation before the run fails with
+	// ErrEmptyGeneration (AGENT_EMPTY_GENERATION_RETRIES).
+	EmptyGenerationRetries int
+	// TurnTimeout bounds each turn's RAG, planning and tool work
+	// (AGENT_TURN_TIMEOUT_SECONDS, 0 = no limit besides the request context).
+	TurnTimeout time.Duration
+	// TurnTimeoutAction is what happens when a turn times out
+	// (AGENT_TURN_TIMEOUT_ACTION): "retry" moves on to the next turn, "abort"
+	// fails the run with ErrTurnTimeout.
+	TurnTimeoutAction string
+	// ToolAllowlist restricts the tools the model may call (AGENT_TOOL_ALLOWLIST,
+	// comma-separated; empty allows all).
+	ToolAllowlist []string
+	// ToolDenylist lists tools the model may never call, even when allowlisted
+	// (AGENT_TOOL_DENYLIST, comma-separated).
+	ToolDenylist []string
+	// RejectUnknownTools answers calls to tools missing from the sandbox catalog
+	// (the tools declared in AGENT_SANDBOXES) with the list of available tools
+	// instead of sending them to the fallback sandbox (AGENT_REJECT_UNKNOWN_TOOLS).
+	// It has no effect while some sandbox declares no tools.
+	RejectUnknownTools bool
+	// ToolLoopThreshold is how many identical tool calls (same name and arguments)
+	// a run may request before a loop is detected (AGENT_TOOL_LOOP_THRESHOLD,
+	// default 2, 0 = off).
+	ToolLoopThreshold int
+	// ToolLoopAction is what happens on a detected loop (AGENT_TOOL_LOOP_ACTION):
+	// "warn" skips the call and tells the model it is repeating itself, "abort"
+	// ends the run with OutcomeToolLoop.
+	ToolLoopAction string
+	// ApprovalRequiredTools pause the run before executing these tools until the
+	// client confirms or approves them, per ApprovalMode
+	// (AGENT_APPROVAL_REQUIRED_TOOLS, comma-separated).
+	ApprovalRequiredTools []string
+	// ConfirmationTTL expires pending tool confirmations (AGENT_CONFIRMATION_TTL, seconds).
+	ConfirmationTTL time.Duration
+	// IdempotencyTTL is how long a /plan Idempotency-Key and its response are kept
+	// (AGENT_IDEMPOTENCY_TTL_SECONDS, default 86400; 0 disables idempotency keys).
+	IdempotencyTTL time.Duration
+	// ApprovalMode selects how approval-required tools are confirmed
+	// (AGENT_APPROVAL_MODE): "token" pauses the run and returns a confirmation
+	// token for /plan/confirm; "wait" keeps the run waiting for a decision sent
+	// to /plan/approve (or pushed to its Redis reply key).
+	ApprovalMode string
+	// ApprovalTimeout bounds the wait for a decision in "wait" mode; no decision
+	// counts as a denial (AGENT_APPROVAL_TIMEOUT_SECONDS).
+	ApprovalTimeout time.Duration
+	// EmptyToolOutputMessage is shown to the model instead of empty stdout/stderr
+	// from a successful tool (AGENT_EMPTY_TOOL_OUTPUT_MSG).
+	EmptyToolOutputMessage string
+	// MaxToolOutputBytes caps a tool's stdout and stderr each; the middle of
+	// longer output is replaced by a "[truncated N bytes]" marker
+	// (AGENT_MAX_TOOL_OUTPUT_BYTES, 0 = unlimited).
+	MaxToolOutputBytes int
+	// AuditFullToolOutput records the untruncated output of truncated tool calls
+	// as a TOOL_OUTPUT_FULL audit step (AGENT_AUDIT_FULL_TOOL_OUTPUT).
+	AuditFullToolOutput bool
+	// SandboxHealthInterval is how often pooled sandbox connections are health-checked
+	// (AGENT_SANDBOX_HEALTH_INTERVAL_SECONDS); only used when SandboxPoolSize > 1.
+	SandboxHealthInterval time.Duration
+
+	// PublishMaxRetries bounds retries of a failed Redis publish (AGENT_PUBLISH_MAX_RETRIES).
+	PublishMaxRetries int
+	// RichNotifications adds outcome, turns_used, tools_used and latency_ms to
+	// published result notifications (AGENT_RICH_NOTIFICATIONS).
+	RichNotifications bool
+	// PublishBufferSize caps the local queue of undelivered notifications
+	// (AGENT_PUBLISH_BUFFER_SIZE); 0 disables buffering.
+	PublishBufferSize int
+
+	// MinPlaybookSteps is the fewest messages (prompt, tool steps and answer) a run
+	// needs to be stored as a playbook (AGENT_MIN_PLAYBOOK_STEPS, default 3; 1 stores all).
+	MinPlaybookSteps int
+	// PlaybookRequireTool only stores playbooks of runs that used a tool
+	// (AGENT_PLAYBOOK_REQUIRE_TOOL, default true).
+	PlaybookRequireTool bool
+	// PlaybookDedupSimilarity skips storing a playbook whose tool sequence matches a
+	// recently stored one and whose prompt words are at least this similar
+	// (AGENT_PLAYBOOK_DEDUP_SIMILARITY, Jaccard, default 0.9; 0 disables).
+	PlaybookDedupSimilarity float64
+	// PlaybookDedupCacheSize is how many stored playbooks are remembered for
+	// deduplication (AGENT_PLAYBOOK_DEDUP_CACHE_SIZE, default 256).
+	PlaybookDedupCacheSize int
+
+	// RedisHealthInterval is how often Redis availability is checked
+	// (REDIS_HEALTH_INTERVAL_SECONDS, default 10; 0 disables the check).
+	RedisHealthInterval time.Duration
+	// NotifyBackend selects how notifications are sent (NOTIFY_BACKEND): pubsub
+	// (default, PUBLISH) or streams (XADD, so offline consumers can replay).
+	NotifyBackend string
+	// NotifyStreamMaxLen approximately caps the notifications stream
+	// (NOTIFY_STREAM_MAXLEN, default 10000; 0 = unbounded).
+	NotifyStreamMaxLen int64
+	// NotifyVerbose also publishes turn starts, model responses and tool calls,
+	// results and errors as they happen (NOTIFY_VERBOSE).
+	NotifyVerbose bool
+	// NotifyChannel is the Redis channel notifications are published on
+	// (NOTIFY_CHANNEL, default pagi_notifications); the stream name derives from it.
+	NotifyChannel string
+	// NotifyPrefix is prepended to every Redis key and channel the planner uses
+	// (NOTIFY_PREFIX), so environments can share one Redis.
+	NotifyPrefix string
+
+	// SessionPromptGuard detects a session ID reused by an unrelated concurrent
+	// conversation (AGENT_SESSION_PROMPT_GUARD: off, warn, reject).
+	SessionPromptGuard string
+
+	// GatewayReadyTimeout makes NewPlanner wait until the Model Gateway Health RPC
+	// reports SERVING (AGENT_WAIT_FOR_GATEWAY_SECONDS, 0 = don't wait).
+	GatewayReadyTimeout time.Duration
+
+	// TenantModelsJSON maps tenant IDs to the models they may select per request
+	// (AGENT_TENANT_MODELS, JSON object of arrays). Empty allows any override.
+	TenantModelsJSON string
+
+	// RateLimitRPS limits /plan requests per session (or per client IP without a
+	// session) with a token bucket refilling at this rate (AGENT_RATE_LIMIT_RPS,
+	// 0 = unlimited). RateLimitBurst is the bucket size (AGENT_RATE_LIMIT_BURST,
+	// default 5).
+	RateLimitRPS   float64
+	RateLimitBurst int
+
+	// PlanCandidates asks the model gateway for best-of-N planning (AGENT_PLAN_CANDIDATES,
+	// default 1). The gateway selects the first candidate that is valid JSON.
+	PlanCandidates int
+	// StreamPlans uses the gateway's GetPlanStream so a TOOL_SELECTED status can be
+	// published before the tool arguments finish streaming (AGENT_STREAM_PLANS).
+	StreamPlans bool
+
+	// RepromptBrokenToolCall re-asks the model once when its output looks like a
+	// malformed tool call instead of treating it as the final answer
+	// (AGENT_REPROMPT_BROKEN_TOOLCALL).
+	RepromptBrokenToolCall bool
+
+	// ToolsOptional tells the model it may answer directly instead of calling a tool,
+	// and ends the run on an explicit {"final_answer": ...} action (AGENT_TOOLS_OPTIONAL).
+	ToolsOptional bool
+
+	// ForceFinalOnMaxTurns makes one extra no-tools synthesis call when every turn
+	// produced a tool call (AGENT_FORCE_FINAL_ON_MAX_TURNS).
+	ForceFinalOnMaxTurns bool
+
+	// MaxTotalTokens caps the model tokens one run may use across all GetPlan
+	// calls (AGENT_MAX_TOTAL_TOKENS, 0 = unlimited). Each call asks for at most the
+	// remaining budget minus its estimated prompt tokens as max_tokens; once
+	// nothing is left the run stops with OutcomeTokenBudget. The prompt estimate
+	// is approximate, so the cap is best-effort.
+	MaxTotalTokens int
+
+	// CompressRepeatedContext replaces a tool result that is identical to one already
+	// in the run's prompt with a short reference to it, so repeated observations are
+	// not re-sent in full on every later turn (AGENT_COMPRESS_REPEATED_CONTEXT).
+	CompressRepeatedContext bool
+
 	MaxTurns int
 	TopK     int
 	KBs      []string
+	// KnownKBs are the knowledge bases a request's knowledge_bases may select
+	// (AGENT_KNOWN_KBS, comma-separated; default: KnownKnowledgeBases). KBs
+	// defaults to the same list.
+	KnownKBs []string
+	// MaxTopK bounds per-request top_k overrides (AGENT_RAG_MAX_TOP_K).
+	MaxTopK int
+	// RAGAdaptiveTopK scales top_k with prompt length/complexity between
+	// RAGAdaptiveMinTopK and RAGAdaptiveMaxTopK instead of using TopK
+	// (AGENT_RAG_ADAPTIVE_TOPK, AGENT_RAG_ADAPTIVE_MIN_TOP_K, AGENT_RAG_ADAPTIVE_MAX_TOP_K).
+	// A per-request top_k still takes precedence.
+	RAGAdaptiveTopK    bool
+	RAGAdaptiveMinTopK int
+	RAGAdaptiveMaxTopK int
+	// MaxHistoryTokens and MaxHistoryMessages bound the session history included
+	// in the planner prompt; the oldest entries beyond either limit are replaced by
+	// an omission marker (AGENT_MAX_HISTORY_TOKENS, estimated at 4 characters per
+	// token, and AGENT_MAX_HISTORY_MESSAGES; 0 = unlimited).
+	MaxHistoryTokens   int
+	MaxHistoryMessages int
+	// HistorySummarize replaces the trimmed history entries with a model-written
+	// summary instead of the omission marker (AGENT_HISTORY_SUMMARIZE). Summaries
+	// are cached per session in Redis for SummaryTTL and use SummaryModel.
+	HistorySummarize bool
+	// RAGDedup collapses near-duplicate matches returned by overlapping knowledge
+	// bases, keeping the closest one (AGENT_RAG_DEDUP). Matches are duplicates when
+	// their normalized texts are equal or their word-shingle similarity is at least
+	// RAGDedupSimilarity (AGENT_RAG_DEDUP_SIMILARITY, 0-1). RAGRerank orders the
+	// remaining matches by score, best first (AGENT_RAG_RERANK).
+	RAGDedup           bool
+	RAGDedupSimilarity float64
+	RAGRerank          bool
+	// RAGMinScore drops RAG matches whose relevance score, 1/(1+distance), is
+	// below it before they reach the prompt (AGENT_RAG_MIN_SCORE, 0 = keep all).
+	RAGMinScore float64
+	// RAGMaxMatches caps the matches included in the prompt after filtering,
+	// closest first (AGENT_RAG_MAX_MATCHES, 0 = unlimited).
+	RAGMaxMatches int
+	// RAGFallbackKBs are queried when the primary retrieval returns no matches
+	// (AGENT_RAG_FALLBACK_KBS, comma-separated; empty disables the fallback).
+	RAGFallbackKBs []string
+
+	// SessionToolCallCap caps cumulative tool calls per session across runs
+	// (AGENT_SESSION_TOOL_CALL_CAP, 0 = unlimited). Counts are kept in Redis.
+	SessionToolCallCap int
+	// SessionToolCallTTL expires a session's tool-call count (AGENT_SESSION_TOOL_CALL_TTL_SECONDS).
+	SessionToolCallTTL time.Duration
+
+	// SummarizeOnComplete stores a one-line session summary in Redis after each
+	// completed run, generated in the background (AGENT_SUMMARIZE_ON_COMPLETE).
+	SummarizeOnComplete bool
+	// SummaryModel overrides the gateway model used for summaries, e.g. a cheaper
+	// one (AGENT_SUMMARY_MODEL; empty uses the gateway default).
+	SummaryModel string
+	// SummaryTTL expires stored summaries (AGENT_SUMMARY_TTL_SECONDS, 0 = never).
+	SummaryTTL time.Duration
+
+	// ResultStore offloads large final answers to object storage and returns a
+	// presigned URL instead (AGENT_RESULT_STORE=s3://bucket/prefix; empty keeps
+	// results inline). Credentials come from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY.
+	ResultStore string
+	// ResultStoreEndpoint is the S3-compatible endpoint, e.g. MinIO
+	// (AGENT_RESULT_STORE_ENDPOINT; empty uses AWS S3 for ResultStoreRegion).
+	ResultStoreEndpoint string
+	// ResultStoreRegion is the signing region (AGENT_RESULT_STORE_REGION, default us-east-1).
+	ResultStoreRegion string
+	// ResultInlineMaxBytes is the largest answer returned inline when ResultStore
+	// is set (AGENT_RESULT_INLINE_MAX_BYTES).
+	ResultInlineMaxBytes int
+	// ResultURLTTL is the validity of presigned result URLs (AGENT_RESULT_URL_TTL_SECONDS).
+	ResultURLTTL time.Duration
+
+	// ResponseLocaleMode controls the response-language instruction
+	// (AGENT_RESPONSE_LOCALE_MODE: off, fixed, auto).
+	ResponseLocaleMode string
+	// ResponseLocale is the locale used in fixed mode without a request locale (AGENT_RESPONSE_LOCALE).
+	ResponseLocale string
+
+	// InjectionScan checks the user prompt and retrieved RAG text for
+	// prompt-injection patterns (AGENT_INJECTION_SCAN).
+	InjectionScan bool
+	// InjectionPatterns are the regexes to scan for (AGENT_INJECTION_PATTERNS,
+	// ";"-separated; empty uses a built-in list).
+	InjectionPatterns []string
+	// InjectionAction is applied on a match (AGENT_INJECTION_ACTION): "strip"
+	// removes the matching text, "flag" marks it for the model, "reject" fails
+	// the request (prompt) or drops the match (RAG).
+	InjectionAction string
+
+	// OutputFilterEnabled turns on the final-answer compliance filter (AGENT_OUTPUT_FILTER_ENABLED).
+	OutputFilterEnabled bool
+	// OutputDenyPatterns are regexes that must not match a final answer
+	// (AGENT_OUTPUT_DENY_PATTERNS, ";"-separated).
+	OutputDenyPatterns []string
+	// OutputFilterMessage replaces a filtered answer (AGENT_OUTPUT_FILTER_MESSAGE).
+	OutputFilterMessage string
 }
 
 // Resource represents a structured, optional multi-modal input reference.
@@ -116,18 +471,133 @@ func ConfigFromEnv() Config {
 		fmt.Sscanf(v, "%d", &topK)
 	}
 
+	knownKBs := getenvList("AGENT_KNOWN_KBS")
+	if len(knownKBs) == 0 {
+		knownKBs = append([]string(nil), KnownKnowledgeBases...)
+	}
+
 	return Config{
-		ModelGatewayAddr:    getenv("MODEL_GATEWAY_ADDR", "localhost:50051"),
-		MemoryServiceAddr:   getenv("MEMORY_GRPC_ADDR", "localhost:50052"),
-		MemoryServiceHTTP:   getenv("MEMORY_URL", "http://localhost:8003"),
-		RustSandboxGRPCAddr: getenv("RUST_SANDBOX_GRPC_ADDR", "localhost:50053"),
-		RustSandboxHTTPURL:  getenv("RUST_SANDBOX_URL", "http://localhost:8001"),
-		AuditDBPath:         getenv("PAGI_AUDIT_DB_PATH", "./pagi_audit.db"),
-		RedisAddr:           getenv("REDIS_ADDR", "localhost:6379"),
-		MaxTurns:            maxTurns,
-		TopK:                topK,
+		ModelGatewayAddr:         getenv("MODEL_GATEWAY_ADDR", "localhost:50051"),
+		MemoryServiceAddr:        getenv("MEMORY_GRPC_ADDR", "localhost:50052"),
+		MemoryServiceHTTP:        getenv("MEMORY_URL", "http://localhost:8003"),
+		RustSandboxGRPCAddr:      getenv("RUST_SANDBOX_GRPC_ADDR", "localhost:50053"),
+		RustSandboxHTTPURL:       getenv("RUST_SANDBOX_URL", "http://localhost:8001"),
+		AuditDBPath:              getenv("PAGI_AUDIT_DB_PATH", "./pagi_audit.db"),
+		RedisAddr:                getenv("REDIS_ADDR", "localhost:6379"),
+		GatewayAPIKey:            firstListItem(os.Getenv("GATEWAY_API_KEY")),
+		GRPCTLSCA:                os.Getenv("GRPC_TLS_CA"),
+		GRPCDialTimeout:          time.Duration(getenvInt("GRPC_DIAL_TIMEOUT_SECONDS", 10)) * time.Second,
+		GRPCMaxRetries:           getenvInt("AGENT_GRPC_MAX_RETRIES", 2),
+		MemoryBreakerThreshold:   getenvInt("AGENT_MEMORY_BREAKER_THRESHOLD", 5),
+		MemoryBreakerCooldown:    time.Duration(getenvInt("AGENT_MEMORY_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
+		GRPCAllowInsecure:        getenvBool("GRPC_ALLOW_INSECURE", false),
+		AuditMaxDataBytes:        getenvInt("AUDIT_MAX_DATA_BYTES", 0),
+		AuditMaxDataExemptEvents: getenvList("AUDIT_MAX_DATA_EXEMPT_EVENTS"),
+		AuditRedact:              getenvBool("AUDIT_REDACT", false),
+		AuditAsync:               getenvBool("AUDIT_ASYNC", true),
+		AuditQueueSize:           getenvInt("AUDIT_QUEUE_SIZE", 1000),
+		AuditBatchSize:           getenvInt("AUDIT_BATCH_SIZE", 100),
+		AuditFlushInterval:       time.Duration(getenvInt("AUDIT_FLUSH_INTERVAL_MS", 200)) * time.Millisecond,
+		AuditSlowWrite:           time.Duration(getenvInt("AUDIT_SLOW_WRITE_MS", 500)) * time.Millisecond,
+		AuditDBDriver:            getenv("PAGI_AUDIT_DB_DRIVER", audit.DriverSQLite),
+		AuditDBDSN:               getenv("PAGI_AUDIT_DB_DSN", ""),
+		AuditRetention:           time.Duration(getenvInt("AUDIT_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		AuditPruneInterval:       time.Duration(getenvInt("AUDIT_PRUNE_INTERVAL_MINUTES", 60)) * time.Minute,
+		SandboxesJSON:            os.Getenv("AGENT_SANDBOXES"),
+		ToolNameConflict:         getenv("AGENT_TOOL_NAME_CONFLICT", toolConflictError),
+		ToolTransport:            getenv("TOOL_TRANSPORT", toolTransportGRPC),
+		SandboxPoolSize:          getenvInt("AGENT_SANDBOX_POOL_SIZE", 1),
+		ToolConcurrency:          getenvInt("AGENT_TOOL_CONCURRENCY", 0),
+		SessionToolConcurrency:   getenvInt("AGENT_SESSION_TOOL_CONCURRENCY", 2),
+		ToolArgsFlat:             getenvBool("AGENT_TOOL_ARGS_FLAT", false),
+		ToolArgsNestedTools:      getenvList("AGENT_TOOL_ARGS_NESTED_TOOLS"),
+		MaxParallelTools:         getenvInt("AGENT_MAX_PARALLEL_TOOLS", 4),
+		CitationsEnabled:         getenvBool("AGENT_CITATIONS_ENABLED", false),
+		EmptyGenerationRetries:   getenvInt("AGENT_EMPTY_GENERATION_RETRIES", 1),
+		TurnTimeout:              time.Duration(getenvInt("AGENT_TURN_TIMEOUT_SECONDS", 0)) * time.Second,
+		TurnTimeoutAction:        strings.ToLower(getenv("AGENT_TURN_TIMEOUT_ACTION", turnTimeoutRetry)),
+		ToolAllowlist:            getenvList("AGENT_TOOL_ALLOWLIST"),
+		ToolDenylist:             getenvList("AGENT_TOOL_DENYLIST"),
+		RejectUnknownTools:       getenvBool("AGENT_REJECT_UNKNOWN_TOOLS", false),
+		ToolLoopThreshold:        getenvInt("AGENT_TOOL_LOOP_THRESHOLD", 2),
+		ToolLoopAction:           strings.ToLower(getenv("AGENT_TOOL_LOOP_ACTION", toolLoopWarn)),
+		ApprovalRequiredTools:    getenvList("AGENT_APPROVAL_REQUIRED_TOOLS"),
+		ConfirmationTTL:          time.Duration(getenvInt("AGENT_CONFIRMATION_TTL", 900)) * time.Second,
+		IdempotencyTTL:           time.Duration(getenvInt("AGENT_IDEMPOTENCY_TTL_SECONDS", 86400)) * time.Second,
+		ApprovalMode:             strings.ToLower(getenv("AGENT_APPROVAL_MODE", approvalModeToken)),
+		ApprovalTimeout:          time.Duration(getenvInt("AGENT_APPROVAL_TIMEOUT_SECONDS", 300)) * time.Second,
+		EmptyToolOutputMessage:   getenv("AGENT_EMPTY_TOOL_OUTPUT_MSG", defaultEmptyToolOutputMessage),
+		MaxToolOutputBytes:       getenvInt("AGENT_MAX_TOOL_OUTPUT_BYTES", 65536),
+		AuditFullToolOutput:      getenvBool("AGENT_AUDIT_FULL_TOOL_OUTPUT", false),
+		SandboxHealthInterval:    time.Duration(getenvInt("AGENT_SANDBOX_HEALTH_INTERVAL_SECONDS", 15)) * time.Second,
+		PublishMaxRetries:        getenvInt("AGENT_PUBLISH_MAX_RETRIES", 2),
+		PublishBufferSize:        getenvInt("AGENT_PUBLISH_BUFFER_SIZE", 100),
+		MinPlaybookSteps:         getenvInt("AGENT_MIN_PLAYBOOK_STEPS", 3),
+		PlaybookRequireTool:      getenvBool("AGENT_PLAYBOOK_REQUIRE_TOOL", true),
+		PlaybookDedupSimilarity:  getenvFloat("AGENT_PLAYBOOK_DEDUP_SIMILARITY", 0.9),
+		PlaybookDedupCacheSize:   getenvInt("AGENT_PLAYBOOK_DEDUP_CACHE_SIZE", 256),
+		RedisHealthInterval:      time.Duration(getenvInt("REDIS_HEALTH_INTERVAL_SECONDS", 10)) * time.Second,
+		NotifyBackend:            getenv("NOTIFY_BACKEND", notifyBackendPubSub),
+		NotifyStreamMaxLen:       int64(getenvInt("NOTIFY_STREAM_MAXLEN", 10000)),
+		NotifyVerbose:            getenvBool("NOTIFY_VERBOSE", false),
+		NotifyChannel:            getenv("NOTIFY_CHANNEL", defaultNotificationsChannel),
+		NotifyPrefix:             os.Getenv("NOTIFY_PREFIX"),
+		RichNotifications:        getenvBool("AGENT_RICH_NOTIFICATIONS", false),
+		TenantModelsJSON:         os.Getenv("AGENT_TENANT_MODELS"),
+		RateLimitRPS:             getenvFloat("AGENT_RATE_LIMIT_RPS", 0),
+		RateLimitBurst:           getenvInt("AGENT_RATE_LIMIT_BURST", 5),
+		SessionPromptGuard:       strings.ToLower(getenv("AGENT_SESSION_PROMPT_GUARD", sessionGuardOff)),
+		GatewayReadyTimeout:      time.Duration(getenvInt("AGENT_WAIT_FOR_GATEWAY_SECONDS", 0)) * time.Second,
+		PlanCandidates:           getenvInt("AGENT_PLAN_CANDIDATES", 1),
+		StreamPlans:              getenvBool("AGENT_STREAM_PLANS", false),
+		RepromptBrokenToolCall:   getenvBool("AGENT_REPROMPT_BROKEN_TOOLCALL", false),
+		ToolsOptional:            getenvBool("AGENT_TOOLS_OPTIONAL", false),
+		ForceFinalOnMaxTurns:     getenvBool("AGENT_FORCE_FINAL_ON_MAX_TURNS", false),
+		MaxTotalTokens:           getenvInt("AGENT_MAX_TOTAL_TOKENS", 0),
+		CompressRepeatedContext:  getenvBool("AGENT_COMPRESS_REPEATED_CONTEXT", false),
+		MaxTurns:                 maxTurns,
+		TopK:                     topK,
 		// Include Mind-KB so the planner can retrieve evolving playbooks via the existing RAG call.
-		KBs: []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"},
+		KBs:                knownKBs,
+		KnownKBs:           knownKBs,
+		MaxTopK:            getenvInt("AGENT_RAG_MAX_TOP_K", 20),
+		RAGFallbackKBs:     getenvList("AGENT_RAG_FALLBACK_KBS"),
+		MaxHistoryTokens:   getenvInt("AGENT_MAX_HISTORY_TOKENS", 0),
+		MaxHistoryMessages: getenvInt("AGENT_MAX_HISTORY_MESSAGES", 0),
+		HistorySummarize:   getenvBool("AGENT_HISTORY_SUMMARIZE", false),
+		RAGDedup:           getenvBool("AGENT_RAG_DEDUP", false),
+		RAGDedupSimilarity: getenvFloat("AGENT_RAG_DEDUP_SIMILARITY", 0.9),
+		RAGRerank:          getenvBool("AGENT_RAG_RERANK", false),
+		RAGMinScore:        getenvFloat("AGENT_RAG_MIN_SCORE", 0),
+		RAGMaxMatches:      getenvInt("AGENT_RAG_MAX_MATCHES", 0),
+
+		RAGAdaptiveTopK:    getenvBool("AGENT_RAG_ADAPTIVE_TOPK", false),
+		RAGAdaptiveMinTopK: getenvInt("AGENT_RAG_ADAPTIVE_MIN_TOP_K", 1),
+		RAGAdaptiveMaxTopK: getenvInt("AGENT_RAG_ADAPTIVE_MAX_TOP_K", 8),
+
+		SessionToolCallCap: getenvInt("AGENT_SESSION_TOOL_CALL_CAP", 0),
+		SessionToolCallTTL: time.Duration(getenvInt("AGENT_SESSION_TOOL_CALL_TTL_SECONDS", 86400)) * time.Second,
+
+		SummarizeOnComplete: getenvBool("AGENT_SUMMARIZE_ON_COMPLETE", false),
+		SummaryModel:        os.Getenv("AGENT_SUMMARY_MODEL"),
+		SummaryTTL:          time.Duration(getenvInt("AGENT_SUMMARY_TTL_SECONDS", 7*86400)) * time.Second,
+
+		ResultStore:          os.Getenv("AGENT_RESULT_STORE"),
+		ResultStoreEndpoint:  os.Getenv("AGENT_RESULT_STORE_ENDPOINT"),
+		ResultStoreRegion:    getenv("AGENT_RESULT_STORE_REGION", defaultResultStoreRegion),
+		ResultInlineMaxBytes: getenvInt("AGENT_RESULT_INLINE_MAX_BYTES", 64*1024),
+		ResultURLTTL:         time.Duration(getenvInt("AGENT_RESULT_URL_TTL_SECONDS", 3600)) * time.Second,
+
+		ResponseLocaleMode: strings.ToLower(getenv("AGENT_RESPONSE_LOCALE_MODE", localeModeOff)),
+		ResponseLocale:     getenv("AGENT_RESPONSE_LOCALE", "en"),
+
+		InjectionScan:     getenvBool("AGENT_INJECTION_SCAN", false),
+		InjectionPatterns: splitPatterns(os.Getenv("AGENT_INJECTION_PATTERNS")),
+		InjectionAction:   strings.ToLower(getenv("AGENT_INJECTION_ACTION", injectionFlag)),
+
+		OutputFilterEnabled: getenvBool("AGENT_OUTPUT_FILTER_ENABLED", false),
+		OutputDenyPatterns:  splitPatterns(os.Getenv("AGENT_OUTPUT_DENY_PATTERNS")),
+		OutputFilterMessage: getenv("AGENT_OUTPUT_FILTER_MESSAGE", defaultOutputFilterMessage),
 	}
 }
 
@@ -138,28 +608,122 @@ func getenv(key, fallback string) string {
 	return fallback
 }
 
+// getenvList parses a comma-separated env var, dropping empty entries.
+func getenvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// firstListItem returns the first non-empty entry of a comma-separated value.
+func firstListItem(v string) string {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			return item
+		}
+	}
+	return ""
+}
+
+// getenvBool parses a boolean env var ("true", "1", ...), returning fallback when unset or invalid.
+func getenvBool(key string, fallback bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// getenvFloat parses a non-negative float env var, returning fallback when unset or invalid.
+func getenvFloat(key string, fallback float64) float64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return fallback
+	}
+	return f
+}
+
+// getenvInt parses a non-negative integer env var, returning fallback when unset or invalid.
+func getenvInt(key string, fallback int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return fallback
+	}
+	return i
+}
+
 type Planner struct {
 	cfg Config
 
 	modelConn  *grpc.ClientConn
 	memoryConn *grpc.ClientConn
-	rustConn   *grpc.ClientConn
 
 	modelClient  pb.ModelGatewayClient
 	memoryClient pb.ModelGatewayClient
-	toolClient   pb.ToolServiceClient
+
+	// sandboxes are the dialed tool sandboxes; toolRouter maps tool names onto them.
+	sandboxes  []*sandbox
+	toolRouter *toolRouter
+	// toolLimiter enforces the global and per-session tool concurrency limits.
+	toolLimiter *toolLimiter
 
 	// Circuit breakers to prevent cascading failures when downstream dependencies
 	// are unhealthy or slow.
 	modelBreaker  *gobreaker.CircuitBreaker
 	memoryBreaker *gobreaker.CircuitBreaker
 
+	outputFilter *outputFilter
+	// injectionScanner checks prompts and RAG text; nil when disabled.
+	injectionScanner *injectionScanner
+	// activeRuns tracks in-flight runs per session.
+	activeRuns *activeRuns
+	// runCancels holds the cancel functions of in-flight runs, for CancelRun.
+	runCancels *runCancels
+	// tenantModels is the per-tenant model allowlist; nil when unrestricted.
+	tenantModels map[string]map[string]bool
+	// resultStore offloads large results; nil keeps them inline.
+	resultStore *resultStore
+	// recentPlaybooks deduplicates stored playbooks; nil when disabled.
+	recentPlaybooks *recentPlaybooks
+	// rateLimiter limits /plan requests per session; nil when disabled.
+	rateLimiter *rateLimiter
+
 	httpClient *http.Client
-	auditDB    *audit.AuditDB
+	auditDB    audit.Store
 	redis      *redis.Client
+	// redisUp is the result of the last Redis health check.
+	redisUp atomic.Bool
+
+	// stopBackground stops background goroutines (e.g. sandbox health checks,
+	// session summaries, audit pruning); bgCtx is their context and bgWG tracks
+	// summaries and audit pruning.
+	stopBackground context.CancelFunc
+	bgCtx          context.Context
+	bgWG           sync.WaitGroup
+
+	// pubMu guards undelivered, the queue of notifications awaiting Redis recovery.
+	pubMu       sync.Mutex
+	undelivered []undeliveredEvent
 }
 
-const notificationsChannel = "pagi_notifications"
+// defaultNotificationsChannel is the notifications channel when NOTIFY_CHANNEL is unset.
+const defaultNotificationsChannel = "pagi_notifications"
 
 var (
 	metricsOnce   sync.Once
@@ -190,32 +754,139 @@ func initMetrics() {
 	})
 }
 
+// registerAuditMetrics exposes the audit DB's dropped-event count as agent_audit_dropped_total.
+func registerAuditMetrics(db *audit.AuditDB) {
+	m := otel.Meter("backend-go-agent-planner")
+	_, _ = m.Int64ObservableCounter(
+		"agent_audit_dropped_total",
+		metric.WithDescription("Count of audit events dropped because the audit queue was full or the audit circuit was open."),
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(db.Dropped())
+			return nil
+		}),
+	)
+}
+
+// dialWithTimeout dials addr and, when timeout is positive, blocks until the
+// connection is ready or the timeout expires.
+func dialWithTimeout(ctx context.Context, addr string, timeout time.Duration, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if timeout <= 0 {
+		return grpc.DialContext(ctx, addr, opts...)
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	opts = append(opts, grpc.WithBlock(), grpc.WithReturnConnectionError())
+	conn, err := grpc.DialContext(dialCtx, addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s not reachable within %s (GRPC_DIAL_TIMEOUT_SECONDS): %w", addr, timeout, err)
+	}
+	return conn, nil
+}
+
 func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 	lg := logger.NewContextLogger(ctx)
 
-	dialInsecure := func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-		return grpc.DialContext(
+	switch cfg.ResponseLocaleMode {
+	case "", localeModeOff, localeModeFixed, localeModeAuto:
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_RESPONSE_LOCALE_MODE=%q (supported: off, fixed, auto)", cfg.ResponseLocaleMode)
+	}
+	switch cfg.SessionPromptGuard {
+	case "", sessionGuardOff, sessionGuardWarn, sessionGuardReject:
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_SESSION_PROMPT_GUARD=%q (supported: off, warn, reject)", cfg.SessionPromptGuard)
+	}
+	switch cfg.ApprovalMode {
+	case "", approvalModeToken, approvalModeWait:
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_APPROVAL_MODE=%q (supported: token, wait)", cfg.ApprovalMode)
+	}
+	switch cfg.TurnTimeoutAction {
+	case "", turnTimeoutRetry, turnTimeoutAbort:
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_TURN_TIMEOUT_ACTION=%q (supported: retry, abort)", cfg.TurnTimeoutAction)
+	}
+	switch cfg.ToolLoopAction {
+	case "", toolLoopWarn, toolLoopAbort:
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_TOOL_LOOP_ACTION=%q (supported: warn, abort)", cfg.ToolLoopAction)
+	}
+	switch cfg.ToolTransport {
+	case "", toolTransportGRPC, toolTransportHTTP:
+	default:
+		return nil, fmt.Errorf("unsupported TOOL_TRANSPORT=%q (supported: grpc, http)", cfg.ToolTransport)
+	}
+	switch cfg.NotifyBackend {
+	case "", notifyBackendPubSub, notifyBackendStreams:
+	default:
+		return nil, fmt.Errorf("unsupported NOTIFY_BACKEND=%q (supported: pubsub, streams)", cfg.NotifyBackend)
+	}
+	if strings.TrimSpace(cfg.NotifyPrefix+cfg.NotifyChannel) == "" {
+		return nil, fmt.Errorf("NOTIFY_PREFIX and NOTIFY_CHANNEL give an empty notifications channel name")
+	}
+	switch cfg.AuditDBDriver {
+	case "", audit.DriverSQLite:
+	case audit.DriverPostgres:
+		if cfg.AuditDBDSN == "" {
+			return nil, fmt.Errorf("PAGI_AUDIT_DB_DSN is required when PAGI_AUDIT_DB_DRIVER=postgres")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PAGI_AUDIT_DB_DRIVER=%q (supported: sqlite, postgres)", cfg.AuditDBDriver)
+	}
+	tenantModels, err := parseTenantModels(cfg.TenantModelsJSON)
+	if err != nil {
+		return nil, err
+	}
+	injection, err := newInjectionScanner(cfg.InjectionScan, cfg.InjectionPatterns, cfg.InjectionAction)
+	if err != nil {
+		return nil, err
+	}
+	resultStore, err := newResultStore(cfg, &http.Client{Timeout: 60 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)})
+	if err != nil {
+		return nil, err
+	}
+
+	transportCreds, err := grpcClientCreds(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dial := func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		return dialWithTimeout(
 			ctx,
 			addr,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			cfg.GRPCDialTimeout,
+			grpc.WithTransportCredentials(transportCreds),
 			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		)
 	}
 
 	dialModelGateway := func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-		if creds, enabled, err := loadMTLSClientCredsForAddr(addr); err != nil {
+		creds := transportCreds
+		if mtlsCreds, enabled, err := loadMTLSClientCredsForAddr(addr); err != nil {
 			return nil, err
 		} else if enabled {
 			lg.Info("mtls_enabled_for_model_gateway", "addr", addr)
-			return grpc.DialContext(
-				ctx,
-				addr,
-				grpc.WithTransportCredentials(creds),
-				grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+			creds = mtlsCreds
+		} else {
+			lg.Warn("mtls_not_enabled_for_model_gateway", "addr", addr)
+		}
+		opts := []grpc.DialOption{
+			grpc.WithTransportCredentials(creds),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		}
+		// The API key is only sent to the gateway, never to other dependencies.
+		if key := cfg.GatewayAPIKey; key != "" {
+			opts = append(opts,
+				grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+					return invoker(metadata.AppendToOutgoingContext(ctx, "x-api-key", key), method, req, reply, cc, callOpts...)
+				}),
+				grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+					return streamer(metadata.AppendToOutgoingContext(ctx, "x-api-key", key), desc, cc, method, callOpts...)
+				}),
 			)
 		}
-		lg.Warn("mtls_not_enabled_for_model_gateway", "addr", addr)
-		return dialInsecure(ctx, addr)
+		return dialWithTimeout(ctx, addr, cfg.GRPCDialTimeout, opts...)
 	}
 
 	modelConn, err := dialModelGateway(ctx, cfg.ModelGatewayAddr)
@@ -223,44 +894,112 @@ func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 		return nil, fmt.Errorf("dial model gateway: %w", err)
 	}
 
-	memoryConn, err := dialInsecure(ctx, cfg.MemoryServiceAddr)
+	memoryConn, err := dial(ctx, cfg.MemoryServiceAddr)
 	if err != nil {
 		_ = modelConn.Close()
 		return nil, fmt.Errorf("dial memory service: %w", err)
 	}
 
-	rustConn, err := dialInsecure(ctx, cfg.RustSandboxGRPCAddr)
+	sandboxCfgs, err := parseSandboxes(cfg.SandboxesJSON, cfg.RustSandboxGRPCAddr, cfg.RustSandboxHTTPURL, cfg.ToolTransport)
+	if err != nil {
+		_ = memoryConn.Close()
+		_ = modelConn.Close()
+		return nil, err
+	}
+	closeSandboxes := func(sbs []*sandbox) {
+		for _, sb := range sbs {
+			_ = sb.Close()
+		}
+	}
+	sandboxes := make([]*sandbox, 0, len(sandboxCfgs))
+	for _, sc := range sandboxCfgs {
+		if cfg.ToolTransport == toolTransportHTTP {
+			// Tool calls go over HTTP, so the gRPC port may not be reachable at all.
+			sandboxes = append(sandboxes, newHTTPSandbox(sc))
+			continue
+		}
+		sb, err := newSandbox(ctx, sc, cfg.SandboxPoolSize, dial)
+		if err != nil {
+			closeSandboxes(sandboxes)
+			_ = memoryConn.Close()
+			_ = modelConn.Close()
+			return nil, fmt.Errorf("dial tool sandbox %q: %w", sc.Name, err)
+		}
+		sandboxes = append(sandboxes, sb)
+	}
+
+	router, err := buildToolRouter(sandboxes, cfg.ToolNameConflict)
+	if err != nil {
+		closeSandboxes(sandboxes)
+		_ = memoryConn.Close()
+		_ = modelConn.Close()
+		return nil, fmt.Errorf("build tool routing table: %w", err)
+	}
+	router.logRoutes(lg)
+	if cfg.RejectUnknownTools && router.catalog() == nil {
+		lg.Warn("tool_catalog_incomplete", "reason", "a sandbox declares no tools; AGENT_REJECT_UNKNOWN_TOOLS has no effect")
+	}
+
+	filter, err := newOutputFilter(cfg.OutputFilterEnabled, cfg.OutputDenyPatterns, cfg.OutputFilterMessage)
 	if err != nil {
+		closeSandboxes(sandboxes)
 		_ = memoryConn.Close()
 		_ = modelConn.Close()
-		return nil, fmt.Errorf("dial rust sandbox: %w", err)
+		return nil, err
 	}
 
-	auditDB, err := audit.NewAuditDB(cfg.AuditDBPath)
+	auditOpts := audit.Options{
+		Driver:              cfg.AuditDBDriver,
+		MaxDataBytes:        cfg.AuditMaxDataBytes,
+		MaxDataExemptEvents: cfg.AuditMaxDataExemptEvents,
+		Redact:              cfg.AuditRedact,
+		SlowWriteThreshold:  cfg.AuditSlowWrite,
+	}
+	if cfg.AuditAsync {
+		auditOpts.QueueSize = cfg.AuditQueueSize
+		auditOpts.BatchSize = cfg.AuditBatchSize
+		auditOpts.FlushInterval = cfg.AuditFlushInterval
+	}
+	auditDSN := cfg.AuditDBPath
+	if cfg.AuditDBDriver == audit.DriverPostgres {
+		auditDSN = cfg.AuditDBDSN
+	}
+	auditDB, err := audit.NewAuditDB(auditDSN, auditOpts)
 	if err != nil {
-		_ = rustConn.Close()
+		closeSandboxes(sandboxes)
 		_ = memoryConn.Close()
 		_ = modelConn.Close()
 		return nil, fmt.Errorf("init audit db: %w", err)
 	}
+	registerAuditMetrics(auditDB)
 
+	// The client is kept even when Redis is down at startup: it reconnects on
+	// use, and the Redis health check tracks when it becomes available.
 	redisClient := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		lg.Warn("redis_unavailable", "addr", cfg.RedisAddr, "error", err)
-		_ = redisClient.Close()
-		redisClient = nil
+	redisErr := redisClient.Ping(ctx).Err()
+	if redisErr != nil {
+		lg.Warn("redis_unavailable", "addr", cfg.RedisAddr, "error", redisErr)
 	}
 
-	// Circuit breaker defaults (production-like):
-	// - Open after 5 consecutive failures.
-	// - Stay open for 30s, then allow 1 request (half-open) to probe recovery.
-	newBreaker := func(name string) *gobreaker.CircuitBreaker {
+	// Circuit breakers open after threshold consecutive failures, stay open for
+	// cooldown, then allow 1 request (half-open) to probe recovery. The model
+	// gateway uses production-like defaults (5 failures, 30s); the memory service
+	// uses AGENT_MEMORY_BREAKER_THRESHOLD and AGENT_MEMORY_BREAKER_COOLDOWN_SECONDS.
+	newBreaker := func(name string, threshold int, cooldown time.Duration) *gobreaker.CircuitBreaker {
+		if threshold < 1 {
+			threshold = 1
+		}
 		return gobreaker.NewCircuitBreaker(gobreaker.Settings{
 			Name:        name,
 			MaxRequests: 1,
-			Timeout:     30 * time.Second,
+			Timeout:     cooldown,
 			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				return counts.ConsecutiveFailures >= 5
+				return counts.ConsecutiveFailures >= uint32(threshold)
+			},
+			// A content-policy refusal or an empty generation is a healthy response,
+			// not a dependency failure.
+			IsSuccessful: func(err error) bool {
+				return err == nil || isContentBlocked(err) || isEmptyGeneration(err)
 			},
 			OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 				logger.LogCircuitBreakerStateChange(lg, name, from.String(), to.String())
@@ -268,23 +1007,76 @@ func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 		})
 	}
 
-	return &Planner{
-		cfg:           cfg,
-		modelConn:     modelConn,
-		memoryConn:    memoryConn,
-		rustConn:      rustConn,
-		modelClient:   pb.NewModelGatewayClient(modelConn),
-		memoryClient:  pb.NewModelGatewayClient(memoryConn),
-		toolClient:    pb.NewToolServiceClient(rustConn),
-		modelBreaker:  newBreaker("model_gateway"),
-		memoryBreaker: newBreaker("memory_service"),
-		httpClient:    &http.Client{Timeout: 10 * time.Second},
-		auditDB:       auditDB,
-		redis:         redisClient,
-	}, nil
+	var recentPlaybooks *recentPlaybooks
+	if cfg.PlaybookDedupSimilarity > 0 {
+		recentPlaybooks = newRecentPlaybooks(cfg.PlaybookDedupCacheSize)
+	}
+
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+
+	// otelhttp injects trace context and baggage into Memory HTTP calls, matching
+	// what otelgrpc does for the gRPC dependencies.
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+	p := &Planner{
+		cfg:              cfg,
+		stopBackground:   stopBackground,
+		bgCtx:            bgCtx,
+		modelConn:        modelConn,
+		memoryConn:       memoryConn,
+		modelClient:      pb.NewModelGatewayClient(modelConn),
+		memoryClient:     pb.NewModelGatewayClient(memoryConn),
+		sandboxes:        sandboxes,
+		toolRouter:       router,
+		toolLimiter:      newToolLimiter(cfg.ToolConcurrency, cfg.SessionToolConcurrency),
+		outputFilter:     filter,
+		injectionScanner: injection,
+		tenantModels:     tenantModels,
+		resultStore:      resultStore,
+		recentPlaybooks:  recentPlaybooks,
+		rateLimiter:      newRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst),
+		activeRuns:       newActiveRuns(),
+		runCancels:       newRunCancels(),
+		modelBreaker:     newBreaker("model_gateway", 5, 30*time.Second),
+		memoryBreaker:    newBreaker("memory_service", cfg.MemoryBreakerThreshold, cfg.MemoryBreakerCooldown),
+		httpClient:       httpClient,
+		auditDB:          auditDB,
+		redis:            redisClient,
+	}
+	p.redisUp.Store(redisErr == nil)
+	if cfg.SandboxPoolSize > 1 && cfg.SandboxHealthInterval > 0 {
+		p.bgWG.Add(1)
+		go func() {
+			defer p.bgWG.Done()
+			runSandboxHealthChecks(bgCtx, sandboxes, cfg.SandboxHealthInterval, lg)
+		}()
+	}
+	if cfg.RedisHealthInterval > 0 {
+		go p.runRedisHealthChecks(bgCtx, cfg.RedisHealthInterval)
+	}
+	if p.rateLimiter != nil {
+		go p.rateLimiter.runSweeper(bgCtx)
+	}
+	if cfg.AuditRetention > 0 && cfg.AuditPruneInterval > 0 {
+		p.bgWG.Add(1)
+		go func() {
+			defer p.bgWG.Done()
+			runAuditPruning(bgCtx, auditDB, cfg.AuditRetention, cfg.AuditPruneInterval, lg)
+		}()
+	}
+	if cfg.GatewayReadyTimeout > 0 {
+		if err := p.waitForGateway(ctx, cfg.GatewayReadyTimeout); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+	return p, nil
 }
 
-func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, resources []Resource) (*pb.PlanResponse, error) {
+// callModelGatewayGetPlan requests a plan from the Model Gateway. With StreamPlans
+// enabled the plan is streamed and onToolName (optional) is invoked as soon as the
+// partial output names a tool, before the full tool arguments arrive.
+func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, resources []Resource, model string, seed, maxTokens *int32, onToolName func(name string)) (*pb.PlanResponse, error) {
 	if p == nil || p.modelClient == nil {
 		return nil, fmt.Errorf("model client is nil")
 	}
@@ -306,8 +1098,19 @@ func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, re
 		logger.NewContextLogger(ctx).Info("grpc_timeout_applied", "dependency", "model_gateway", "timeout_seconds", int(timeout.Seconds()))
 		ctx2, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
-		return p.modelClient.GetPlan(ctx2, &pb.PlanRequest{Prompt: prompt, Resources: pbResources})
+		req := &pb.PlanRequest{Prompt: prompt, Resources: pbResources, Model: model, Seed: seed, MaxTokens: maxTokens}
+		if p.cfg.StreamPlans {
+			// Streaming only supports a single candidate.
+			return p.streamPlan(ctx2, req, onToolName)
+		}
+		if p.cfg.PlanCandidates > 1 {
+			req.N = int32(p.cfg.PlanCandidates)
+		}
+		var trailer metadata.MD
+		resp, err := p.modelClient.GetPlan(ctx2, req, grpc.Trailer(&trailer))
+		return resp, withRetryAfter(err, trailer)
 	}
+	call = retried(ctx, p, "model_gateway", isTransientGRPC, call)
 
 	if p.modelBreaker == nil {
 		return call()
@@ -329,7 +1132,7 @@ func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, re
 	return resp, nil
 }
 
-func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string) (*pb.RAGContextResponse, error) {
+func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string, kbs []string, topK int) (*pb.RAGContextResponse, error) {
 	if p == nil || p.memoryClient == nil {
 		return nil, fmt.Errorf("memory client is nil")
 	}
@@ -344,10 +1147,11 @@ func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string) (*p
 		defer cancel()
 		return p.memoryClient.GetRAGContext(ctx2, &pb.RAGContextRequest{
 			Query:          query,
-			TopK:           int32(p.cfg.TopK),
-			KnowledgeBases: p.cfg.KBs,
+			TopK:           int32(topK),
+			KnowledgeBases: kbs,
 		})
 	}
+	call = retried(ctx, p, "memory_service", isTransientGRPC, call)
 
 	if p.memoryBreaker == nil {
 		return call()
@@ -373,14 +1177,18 @@ func (p *Planner) Close() {
 	if p == nil {
 		return
 	}
+	if p.stopBackground != nil {
+		p.stopBackground()
+	}
+	p.bgWG.Wait()
 	if p.modelConn != nil {
 		_ = p.modelConn.Close()
 	}
 	if p.memoryConn != nil {
 		_ = p.memoryConn.Close()
 	}
-	if p.rustConn != nil {
-		_ = p.rustConn.Close()
+	for _, sb := range p.sandboxes {
+		_ = sb.Close()
 	}
 	if p.auditDB != nil {
 		_ = p.auditDB.Close()
@@ -407,6 +1215,8 @@ func injectTraceIDToOutgoingGRPC(ctx context.Context) context.Context {
 }
 
 func (p *Planner) RecordStep(ctx context.Context, sessionID, eventType string, data any) error {
+	emitStepEvent(ctx, eventType, data)
+	_ = p.publishStep(ctx, sessionID, eventType, data)
 	if p == nil || p.auditDB == nil {
 		return nil
 	}
@@ -415,40 +1225,196 @@ func (p *Planner) RecordStep(ctx context.Context, sessionID, eventType string, d
 }
 
 func (p *Planner) PublishStatus(ctx context.Context, sessionID string, status string) error {
+	return p.publishStatus(ctx, sessionID, status, nil)
+}
+
+// PublishToolSelected announces the tool the model is about to call, ahead of execution.
+func (p *Planner) PublishToolSelected(ctx context.Context, sessionID string, tool string) error {
+	return p.publishStatus(ctx, sessionID, "TOOL_SELECTED", map[string]any{"tool": tool})
+}
+
+func (p *Planner) publishStatus(ctx context.Context, sessionID string, status string, extra map[string]any) error {
 	if p == nil || p.redis == nil {
 		return nil
 	}
-	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
-	payload := map[string]any{
-		"trace_id":   traceID,
-		"session_id": sessionID,
-		"status":     status,
-		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
-	}
-	b, _ := json.Marshal(payload)
-	return p.redis.Publish(ctx, notificationsChannel, string(b)).Err()
+	return p.publish(ctx, eventTypeStatus, notificationPayload(ctx, sessionID, map[string]any{"status": status}, extra))
 }
 
 func (p *Planner) PublishNotification(ctx context.Context, sessionID string, result string) error {
+	return p.publishNotification(ctx, sessionID, result, nil)
+}
+
+// publishNotification publishes a result notification; extra fields (run metadata)
+// are added alongside the standard ones.
+func (p *Planner) publishNotification(ctx context.Context, sessionID string, result string, extra map[string]any) error {
 	if p == nil || p.redis == nil {
 		return nil
 	}
-	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
-	payload := map[string]any{
-		"trace_id":   traceID,
-		"session_id": sessionID,
-		"result":     result,
-		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
+	return p.publish(ctx, eventTypeResult, notificationPayload(ctx, sessionID, map[string]any{"result": result}, extra))
+}
+
+// RunRequest is the input to a single AgentLoop run.
+type RunRequest struct {
+	Prompt    string
+	SessionID string
+	Resources []Resource
+	// KnowledgeBases overrides Config.KBs for this run when non-empty.
+	KnowledgeBases []string
+	// TopK overrides Config.TopK for this run when positive.
+	TopK int
+	// Locale is the requested response locale (used unless the locale mode is off).
+	Locale string
+	// Model overrides the gateway's primary model; callers check AuthorizeModel first.
+	Model string
+	// Seed makes every plan call of the run use this sampling seed, for
+	// reproducible runs on providers that support it. Nil leaves sampling unseeded.
+	Seed *int32
+	// RawResult returns the final plan JSON as RunResult.Result instead of the
+	// answer extracted from it.
+	RawResult bool
+}
+
+// Tool outcome statuses recorded in ToolOutcome.Status.
+const (
+	ToolOutcomeOK    = "ok"
+	ToolOutcomeError = "error"
+)
+
+// ToolOutcome is the result of a single tool execution attempted during a run.
+type ToolOutcome struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Run outcomes recorded in RunResult.Outcome.
+const (
+	OutcomeCompleted = "completed"
+	OutcomeMaxTurns  = "max_turns"
+	OutcomeError     = "error"
+	// OutcomeForcedFinal means the turns ran out and a final answer was synthesized
+	// from the gathered tool results (Config.ForceFinalOnMaxTurns).
+	OutcomeForcedFinal = "forced_final"
+	// OutcomeTokenBudget means the run used up Config.MaxTotalTokens.
+	OutcomeTokenBudget = "token_budget"
+	// OutcomeContentBlocked means the LLM provider refused the request for
+	// content-policy reasons (the gateway's CONTENT_BLOCKED status).
+	OutcomeContentBlocked = "content_blocked"
+	// OutcomePendingConfirmation means the run paused before a tool listed in
+	// Config.ApprovalRequiredTools; resume it with ConfirmRun.
+	OutcomePendingConfirmation = "pending_confirmation"
+	// OutcomeToolLoop means the run stopped after repeating an identical tool call
+	// (Config.ToolLoopThreshold with Config.ToolLoopAction "abort").
+	OutcomeToolLoop = "tool_loop"
+	// OutcomeCanceled means the run was stopped with CancelRun.
+	OutcomeCanceled = "canceled"
+)
+
+// RunResult is the outcome of a single AgentLoop run.
+type RunResult struct {
+	// Result is the final answer (or the max-turns message). It is empty when the
+	// answer was offloaded to the result store; ResultURL then points to it.
+	Result    string
+	ResultURL string
+	// Completed is true when the model produced a final (non-tool-call) answer.
+	Completed bool
+	// Outcome summarizes how the run ended (see Outcome* constants).
+	Outcome string
+	// TurnsUsed is the number of planning turns started.
+	TurnsUsed int
+	// ToolOutcomes lists every tool execution attempted during the run, in order.
+	ToolOutcomes []ToolOutcome
+	// ToolCallsUsed is the session's cumulative tool-call count (nil when Redis is unavailable).
+	ToolCallsUsed *int
+	// ToolCallsRemaining is the session's remaining tool-call budget (nil without a cap).
+	ToolCallsRemaining *int
+	// ConfirmationToken and PendingTools are set with OutcomePendingConfirmation.
+	ConfirmationToken string
+	PendingTools      []ToolCall
+
+	// Sources are the RAG matches cited by the final answer; HallucinatedCitations
+	// are cited IDs that were never injected (AGENT_CITATIONS_ENABLED).
+	Sources               []Source
+	HallucinatedCitations []string
+
+	// SystemFingerprint is the provider's backend fingerprint from the last plan
+	// call that reported one; seeded runs are only reproducible while it is unchanged.
+	SystemFingerprint string
+
+	// Cumulative model usage across all turns (zero when the provider omits usage).
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	EstimatedCostUSD float64
+}
+
+// addUsage accumulates the token usage and cost of a single plan response.
+func (r *RunResult) addUsage(resp *pb.PlanResponse) {
+	if r == nil || resp == nil {
+		return
 	}
-	b, _ := json.Marshal(payload)
-	return p.redis.Publish(ctx, notificationsChannel, string(b)).Err()
+	r.PromptTokens += int(resp.GetPromptTokens())
+	r.CompletionTokens += int(resp.GetCompletionTokens())
+	r.TotalTokens += int(resp.GetTotalTokens())
+	r.EstimatedCostUSD += resp.GetEstimatedCostUsd()
+}
+
+// ToolsUsed returns the names of the tools executed during the run, in order.
+func (r *RunResult) ToolsUsed() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.ToolOutcomes))
+	for _, o := range r.ToolOutcomes {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// ToolFailed reports whether any tool execution in the run failed.
+func (r *RunResult) ToolFailed() bool {
+	if r == nil {
+		return false
+	}
+	for _, o := range r.ToolOutcomes {
+		if o.Status != ToolOutcomeOK {
+			return true
+		}
+	}
+	return false
 }
 
 // AgentLoop orchestrates Memory -> Plan -> (Tool?) -> Persist, repeating up to MaxTurns.
+//
+// The returned RunResult is non-nil even when err is non-nil, so callers can report
+// partial progress (e.g. tool outcomes) for failed runs.
+func (p *Planner) AgentLoop(ctx context.Context, req RunRequest) (*RunResult, error) {
+	return p.agentLoop(ctx, req, nil)
+}
 
-func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string, resources []Resource) (result string, err error) {
+// agentLoop runs AgentLoop, or resumes a run paused for tool confirmation when
+// resume is non-nil.
+func (p *Planner) agentLoop(ctx context.Context, req RunRequest, resume *resumeState) (res *RunResult, err error) {
 	initMetrics()
 
+	prompt, sessionID, resources := req.Prompt, req.SessionID, req.Resources
+	res = &RunResult{}
+	if resume != nil {
+		// Carry over tool outcomes and token usage from before the pause.
+		prev := resume.pending.Result
+		res.TurnsUsed = prev.TurnsUsed
+		res.ToolOutcomes = prev.ToolOutcomes
+		res.PromptTokens = prev.PromptTokens
+		res.CompletionTokens = prev.CompletionTokens
+		res.TotalTokens = prev.TotalTokens
+		res.EstimatedCostUSD = prev.EstimatedCostUSD
+	}
+
+	// Runs can be canceled by session ID (CancelRun).
+	ctx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+	defer p.runCancels.register(sessionID, cancelRun)()
+
 	tracer := otel.Tracer("backend-go-agent-planner")
 	ctx, span := tracer.Start(ctx, "AgentLoopExecution")
 	span.SetAttributes(
@@ -457,6 +1423,22 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 	)
 	start := time.Now()
 	defer func() {
+		if err != nil {
+			res.Outcome = OutcomeError
+		}
+		p.fillToolCallBudget(ctx, sessionID, res)
+		logger.LogRunSummary(logger.NewContextLogger(ctx), logger.RunSummary{
+			SessionID: sessionID,
+			TurnsUsed: res.TurnsUsed,
+			ToolsUsed: res.ToolsUsed(),
+			Outcome:   res.Outcome,
+			LatencyMs: time.Since(start).Milliseconds(),
+
+			PromptTokens:     res.PromptTokens,
+			CompletionTokens: res.CompletionTokens,
+			TotalTokens:      res.TotalTokens,
+			EstimatedCostUSD: res.EstimatedCostUSD,
+		})
 		if loopDurationS != nil {
 			loopDurationS.Record(ctx, time.Since(start).Seconds())
 		}
@@ -480,8 +1462,50 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 	ctx = injectTraceIDToOutgoingGRPC(ctx)
 	lg := logger.NewContextLogger(ctx)
 
+	kbs := p.cfg.KBs
+	if len(req.KnowledgeBases) > 0 {
+		kbs = req.KnowledgeBases
+	}
+	topK, topKSource := p.cfg.TopK, "config"
+	switch {
+	case req.TopK > 0:
+		topK, topKSource = req.TopK, "request"
+	case p.cfg.RAGAdaptiveTopK:
+		topK, topKSource = adaptiveTopK(prompt, p.cfg.RAGAdaptiveMinTopK, p.cfg.RAGAdaptiveMaxTopK), "adaptive"
+	}
+
+	if guard := p.cfg.SessionPromptGuard; guard == sessionGuardWarn || guard == sessionGuardReject {
+		similarity, collision, endRun := p.activeRuns.begin(sessionID, prompt)
+		defer endRun()
+		if collision {
+			_ = p.RecordStep(ctx, sessionID, "SESSION_COLLISION_SUSPECTED", map[string]any{"prompt": prompt, "similarity": similarity, "action": guard})
+			lg.Warn("session_collision_suspected", "session_id", sessionID, "similarity", similarity, "action", guard)
+			if guard == sessionGuardReject {
+				return res, ErrSessionCollision
+			}
+		}
+	}
+
+	if resume == nil {
+		if prompt, err = p.scanPrompt(ctx, sessionID, prompt); err != nil {
+			lg.Warn("prompt_injection_rejected", "session_id", sessionID)
+			return res, err
+		}
+	}
 	basePrompt := prompt
-	_ = p.RecordStep(ctx, sessionID, "PLAN_START", map[string]any{"prompt": basePrompt, "resources": resources, "max_turns": p.cfg.MaxTurns, "top_k": p.cfg.TopK, "kbs": p.cfg.KBs})
+	locale, localeSource := resolveLocale(p.cfg.ResponseLocaleMode, req.Locale, p.cfg.ResponseLocale, basePrompt)
+	if resume == nil {
+		_ = p.RecordStep(ctx, sessionID, "PLAN_START", map[string]any{
+			"prompt":        basePrompt,
+			"resources":     resources,
+			"max_turns":     p.cfg.MaxTurns,
+			"top_k":         topK,
+			"top_k_source":  topKSource,
+			"kbs":           kbs,
+			"locale":        locale,
+			"locale_source": localeSource,
+		})
+	}
 	_ = p.PublishStatus(ctx, sessionID, "STARTED")
 	// Collect a per-run playbook sequence (user prompt + tool-plan/tool-result pairs + final answer).
 	// This is persisted to Mind-KB only on successful completion.
@@ -493,96 +1517,582 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 		maxTurns = 3
 	}
 
-	for turn := 1; turn <= maxTurns; turn++ {
+	// complete records, persists and publishes a final answer and stores it in res.
+	complete := func(final, outcome string) {
+		filtered := false
+		if pattern, ok := p.outputFilter.match(final); ok {
+			_ = p.RecordStep(ctx, sessionID, "CONTENT_FILTERED", map[string]any{"pattern": pattern, "original_length": len(final)})
+			lg.Warn("final_answer_filtered", "session_id", sessionID, "pattern", pattern)
+			final = p.outputFilter.message
+			filtered = true
+		}
+		// Large answers are offloaded; history, audit and notifications then carry
+		// the reference instead of the full text.
+		stored := final
+		endEvent := map[string]any{"result": final, "tool_used": len(res.ToolOutcomes) > 0}
+		if u := p.offloadResult(ctx, sessionID, final); u != "" {
+			res.ResultURL = u
+			stored = resultReference(u, len(final))
+			endEvent = map[string]any{"result_url": u, "result_bytes": len(final), "tool_used": len(res.ToolOutcomes) > 0}
+		} else {
+			res.Result = final
+		}
+		playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": stored})
+		_ = p.RecordStep(ctx, sessionID, "PLAN_END", endEvent)
+		p.savePlaybook(ctx, sessionID, basePrompt, playbookSeq, res.ToolsUsed(), hadToolStep, filtered)
+		_ = p.storeSessionDelta(ctx, sessionID, prompt, stored)
+		res.Completed = true
+		res.Outcome = outcome
+		var meta map[string]any
+		if p.cfg.RichNotifications {
+			meta = map[string]any{
+				"outcome":    res.Outcome,
+				"turns_used": res.TurnsUsed,
+				"tools_used": res.ToolsUsed(),
+				"latency_ms": time.Since(start).Milliseconds(),
+			}
+		}
+		_ = p.publishNotification(ctx, sessionID, stored, meta)
+		_ = p.PublishStatus(ctx, sessionID, "COMPLETED")
+		p.summarizeAsync(ctx, sessionID, basePrompt, final)
+	}
+
+	// generatePlan calls the Model Gateway and records the response (or error).
+	generatePlan := func(plannerInput string) (*pb.PlanResponse, error) {
+		// Every GetPlan (including repair and forced-final calls) is capped at the
+		// remaining token budget. max_tokens only bounds the completion, so the
+		// prompt's estimated tokens are taken out first; the gateway rejects
+		// max_tokens <= 0.
+		var maxTokens *int32
+		if p.cfg.MaxTotalTokens > 0 {
+			promptTokens := estimateTokens(plannerInput)
+			remaining := p.cfg.MaxTotalTokens - res.TotalTokens - promptTokens
+			if remaining <= 0 {
+				_ = p.RecordStep(ctx, sessionID, "TOKEN_BUDGET_EXCEEDED", map[string]any{"turn": res.TurnsUsed, "total_tokens": res.TotalTokens, "estimated_prompt_tokens": promptTokens, "max_total_tokens": p.cfg.MaxTotalTokens})
+				lg.Warn("token_budget_exceeded", "session_id", sessionID, "turn", res.TurnsUsed, "total_tokens", res.TotalTokens, "estimated_prompt_tokens", promptTokens, "max_total_tokens", p.cfg.MaxTotalTokens)
+				return nil, errTokenBudgetExceeded
+			}
+			capped := int32(remaining)
+			maxTokens = &capped
+		}
+		ctxStep, stepSpan := tracer.Start(ctx, "PlanGeneration")
+		var planResp *pb.PlanResponse
+		var err error
+		for attempt := 0; ; attempt++ {
+			planResp, err = p.callModelGatewayGetPlan(ctxStep, plannerInput, resources, req.Model, req.Seed, maxTokens, func(name string) {
+				_ = p.PublishToolSelected(ctx, sessionID, name)
+			})
+			if err == nil || !isEmptyGeneration(err) {
+				break
+			}
+			retry := attempt < p.cfg.EmptyGenerationRetries && ctx.Err() == nil
+			_ = p.RecordStep(ctx, sessionID, "EMPTY_GENERATION", map[string]any{"turn": res.TurnsUsed, "attempt": attempt + 1, "retry": retry})
+			lg.Warn("empty_generation", "session_id", sessionID, "turn", res.TurnsUsed, "attempt", attempt+1, "retry", retry)
+			if !retry {
+				break
+			}
+		}
+		if err != nil {
+			stepSpan.RecordError(err)
+		}
+		stepSpan.End()
+		if err != nil {
+			if isEmptyGeneration(err) {
+				return nil, fmt.Errorf("GetPlan: %w (%v)", ErrEmptyGeneration, err)
+			}
+			if isContentBlocked(err) {
+				_ = p.RecordStep(ctx, sessionID, "CONTENT_BLOCKED", map[string]any{"turn": res.TurnsUsed, "error": err.Error()})
+			} else {
+				_ = p.RecordStep(ctx, sessionID, "PLAN_ERROR", map[string]any{"error": err.Error()})
+			}
+			return nil, fmt.Errorf("GetPlan: %w", err)
+		}
+		res.addUsage(planResp)
+		planEvent := map[string]any{"plan": planResp.GetPlan(), "total_tokens": planResp.GetTotalTokens()}
+		if fp := planResp.GetSystemFingerprint(); fp != "" {
+			planEvent["system_fingerprint"] = fp
+			res.SystemFingerprint = fp
+		}
+		if len(planResp.GetCandidates()) > 1 {
+			planEvent["candidates"] = planResp.GetCandidates()
+		}
+		_ = p.RecordStep(ctx, sessionID, "PLAN_MODEL_RESPONSE", planEvent)
+		return planResp, nil
+	}
+
+	// canceled ends a run stopped with CancelRun. The run's context is canceled,
+	// so the final audit step and status use a context without cancellation.
+	canceled := func() (*RunResult, error) {
+		doneCtx := context.WithoutCancel(ctx)
+		_ = p.RecordStep(doneCtx, sessionID, "PLAN_CANCELED", map[string]any{"turn": res.TurnsUsed})
+		_ = p.PublishStatus(doneCtx, sessionID, "CANCELED")
+		lg.Info("plan_canceled", "session_id", sessionID, "turn", res.TurnsUsed)
+		res.Result = "Run canceled."
+		res.Outcome = OutcomeCanceled
+		return res, nil
+	}
+
+	// planFailed ends the run after a failed plan call; a content-policy refusal is
+	// reported as OutcomeContentBlocked and an exhausted token budget as
+	// OutcomeTokenBudget rather than as an error.
+	planFailed := func(planErr error) (*RunResult, error) {
+		if runCanceled(ctx) {
+			return canceled()
+		}
+		if errors.Is(planErr, errTokenBudgetExceeded) {
+			res.Result = "Token budget exhausted; unable to complete request."
+			res.Outcome = OutcomeTokenBudget
+			return res, nil
+		}
+		if isContentBlocked(planErr) {
+			res.Result = contentBlockedMessage
+			res.Outcome = OutcomeContentBlocked
+			return res, nil
+		}
+		return res, planErr
+	}
+
+	// finalAnswer is the result reported for a final plan; the raw plan stays in
+	// the PLAN_MODEL_RESPONSE audit step.
+	finalAnswer := func(plan string) string {
+		if req.RawResult {
+			return plan
+		}
+		return extractAnswer(plan)
+	}
+
+	// injectedMatches are the RAG matches put into the prompt during the run, by
+	// ID; cite resolves the final answer's citations against them.
+	injectedMatches := map[string]*pb.RAGMatch{}
+	cite := func(plan string) {
+		if p.cfg.CitationsEnabled {
+			res.Sources, res.HallucinatedCitations = p.resolveCitations(ctx, sessionID, plan, injectedMatches)
+		}
+	}
+
+	// executedCalls counts executed tool calls by fingerprint, for loop detection.
+	executedCalls := map[string]int{}
+
+	// runToolSteps executes a turn's tool calls in order and feeds their outputs
+	// (or errors) into the prompt. A single call keeps the original prompt format.
+	runToolSteps := func(plan string, calls []ToolCall) {
+		// Audit events of multi-tool turns carry the call's index.
+		indexed := func(event map[string]any, i int) map[string]any {
+			if len(calls) > 1 {
+				event["index"] = i
+			}
+			return event
+		}
+		recordCall := func(i int) {
+			executedCalls[toolCallFingerprint(calls[i])]++
+			_ = p.RecordStep(ctx, sessionID, "TOOL_CALL", indexed(map[string]any{"tool": calls[i].Name, "args": calls[i].Args}, i))
+			if _, incErr := p.incrSessionToolCalls(ctx, sessionID); incErr != nil && !errors.Is(incErr, errRedisUnavailable) {
+				lg.Warn("session_tool_calls_incr_failed", "session_id", sessionID, "error", incErr)
+			}
+		}
+		// 4) Tool execution via Rust sandbox ToolService over gRPC.
+		execCall := func(ctx context.Context, i int) (string, error) {
+			ctxStep, stepSpan := tracer.Start(ctx, "ToolCallExecution")
+			defer stepSpan.End()
+			stepSpan.SetAttributes(attribute.String("tool.name", calls[i].Name))
+			out, err := p.executeTool(ctxStep, sessionID, calls[i].Name, calls[i].Args)
+			if err != nil {
+				stepSpan.RecordError(err)
+			}
+			return out, err
+		}
+		var results []toolStepResult
+		recordResult := func(i int, toolOut string, err error) {
+			name := calls[i].Name
+			if err != nil {
+				_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", indexed(map[string]any{"tool": name, "error": err.Error()}, i))
+				res.ToolOutcomes = append(res.ToolOutcomes, ToolOutcome{Name: name, Status: ToolOutcomeError, Error: err.Error()})
+				results = append(results, toolStepResult{index: i, name: name, output: "Tool error: " + err.Error(), failed: true})
+				return
+			}
+			_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT", indexed(map[string]any{"tool": name, "output": toolOut}, i))
+			res.ToolOutcomes = append(res.ToolOutcomes, ToolOutcome{Name: name, Status: ToolOutcomeOK})
+			results = append(results, toolStepResult{index: i, name: name, output: p.toolOutputForModel(toolOut)})
+		}
+
+		parallel, failFast := toolBatchOptions(calls)
+		if parallel && len(calls) > 1 && p.cfg.MaxParallelTools > 1 {
+			for i := range calls {
+				recordCall(i)
+			}
+			outs, errs := p.executeToolsParallel(ctx, len(calls), failFast, execCall)
+			for i := range calls {
+				recordResult(i, outs[i], errs[i])
+			}
+		} else {
+			failed := false
+			for i := range calls {
+				if failed && failFast {
+					recordResult(i, "", errToolSkipped)
+					continue
+				}
+				recordCall(i)
+				out, err := execCall(ctx, i)
+				recordResult(i, out, err)
+				failed = failed || err != nil
+			}
+		}
+		if len(calls) == 1 && results[0].failed {
+			// Feed tool error back into the loop.
+			prompt = prompt + "\n\n" + results[0].output
+			return
+		}
+
+		var succeeded []toolStepResult
+		for _, r := range results {
+			if !r.failed {
+				succeeded = append(succeeded, r)
+			}
+		}
+		if len(succeeded) > 0 {
+			hadToolStep = true
+			playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": plan})
+			for _, r := range succeeded {
+				playbookSeq = append(playbookSeq, map[string]string{"role": "tool_result", "content": r.output})
+			}
+		}
+
+		// 5) Loop/feedback.
+		shown := results
+		if p.cfg.CompressRepeatedContext {
+			var repeated int
+			if shown, repeated = compressRepeatedToolResults(prompt, results); repeated > 0 {
+				_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT_COMPRESSED", map[string]any{"turn": res.TurnsUsed, "repeated": repeated})
+			}
+		}
+		if len(calls) == 1 {
+			prompt = buildFollowupPrompt(prompt, plan, shown[0].output)
+		} else {
+			prompt = buildMultiToolFollowupPrompt(prompt, plan, shown)
+		}
+		_ = p.storeSessionDelta(ctx, sessionID, "[tool-plan]", plan)
+		for _, r := range results {
+			_ = p.storeSessionDelta(ctx, sessionID, "[tool-output]", r.output)
+		}
+	}
+
+	firstTurn := 1
+	if resume != nil {
+		pc := resume.pending
+		prompt, playbookSeq, hadToolStep = pc.Prompt, pc.Playbook, pc.HadToolStep
+		if pc.ExecutedCalls != nil {
+			executedCalls = pc.ExecutedCalls
+		}
+		decision := "denied"
+		if resume.approved {
+			decision = "approved"
+		}
+		names := toolCallNames(pc.Tools)
+		_ = p.RecordStep(ctx, sessionID, "TOOL_CONFIRMATION_RESOLVED", map[string]any{"tools": names, "turn": pc.Turn, "decision": decision})
+		lg.Info("tool_confirmation_resolved", "session_id", sessionID, "tools", names, "decision", decision)
+		if resume.approved {
+			runToolSteps(pc.Plan, pc.Tools)
+		} else {
+			prompt = prompt + "\n\nTool error: the user denied running " + strings.Join(names, ", ") + "; answer without it."
+		}
+		firstTurn = pc.Turn + 1
+	}
+
+	// Each turn runs under its own deadline (Config.TurnTimeout) derived from the
+	// request context; the closures above see the turn's ctx while it runs.
+	runCtx := ctx
+	cancelTurn := context.CancelFunc(func() {})
+	defer func() { cancelTurn() }()
+	turnTimedOut := func() bool {
+		return errors.Is(ctx.Err(), context.DeadlineExceeded) && runCtx.Err() == nil
+	}
+	// onTurnTimeout records a timed-out turn and returns ErrTurnTimeout in abort mode.
+	onTurnTimeout := func(turn int, stage string) error {
+		action := p.cfg.TurnTimeoutAction
+		if action == "" {
+			action = turnTimeoutRetry
+		}
+		_ = p.RecordStep(runCtx, sessionID, "TURN_TIMEOUT", map[string]any{"turn": turn, "stage": stage, "timeout_ms": p.cfg.TurnTimeout.Milliseconds(), "action": action})
+		lg.Warn("turn_timeout", "session_id", sessionID, "turn", turn, "stage", stage, "timeout", p.cfg.TurnTimeout, "action", action)
+		if action == turnTimeoutAbort {
+			return fmt.Errorf("turn %d exceeded AGENT_TURN_TIMEOUT_SECONDS (%s) during %s: %w", turn, p.cfg.TurnTimeout, stage, ErrTurnTimeout)
+		}
+		return nil
+	}
+
+	for turn := firstTurn; turn <= maxTurns; turn++ {
+		cancelTurn()
+		ctx = runCtx
+		if runCanceled(runCtx) {
+			return canceled()
+		}
+		if err := runCtx.Err(); err != nil {
+			// The client went away or the request deadline passed; stop promptly.
+			return res, fmt.Errorf("agent loop canceled before turn %d: %w", turn, err)
+		}
+		if p.cfg.TurnTimeout > 0 {
+			turnCtx, cancel := context.WithTimeout(runCtx, p.cfg.TurnTimeout)
+			ctx, cancelTurn = turnCtx, cancel
+		}
 		span.SetAttributes(attribute.Int("turn", turn))
+		res.TurnsUsed = turn
+		ctx = withNotifyTurn(ctx, turn)
+		_ = p.publishStep(ctx, sessionID, "TURN_START", map[string]any{"turn": turn})
+
+		// While the memory circuit is open, skip history and RAG rather than wait
+		// on calls that would fail anyway.
+		memoryOpen := p.memoryCircuitOpen()
+		if memoryOpen {
+			_ = p.RecordStep(ctx, sessionID, "MEMORY_CIRCUIT_OPEN", map[string]any{"turn": turn, "cooldown_seconds": int(p.cfg.MemoryBreakerCooldown.Seconds())})
+			lg.Warn("memory_circuit_open", "session_id", sessionID, "turn", turn, "state", p.memoryBreaker.State().String())
+		}
 
 		// 1) Session history (Episodic/Heart) via Memory HTTP API.
 		var history []map[string]any
-		{
+		if !memoryOpen {
 			ctxStep, stepSpan := tracer.Start(ctx, "MemoryAccess.SessionHistory")
 			history, _ = p.fetchSessionHistory(ctxStep, sessionID)
 			stepSpan.End()
 		}
+		if p.cfg.MaxHistoryTokens > 0 || p.cfg.MaxHistoryMessages > 0 {
+			kept, omitted := trimHistory(history, p.cfg.MaxHistoryTokens, p.cfg.MaxHistoryMessages, historyEntryTokens)
+			switch {
+			case omitted == 0:
+			case p.cfg.HistorySummarize:
+				ctxStep, stepSpan := tracer.Start(ctx, "HistorySummarization")
+				summary, cached, sumErr := p.summarizeHistory(ctxStep, sessionID, history[:omitted])
+				stepSpan.End()
+				if sumErr == nil {
+					_ = p.RecordStep(ctx, sessionID, "HISTORY_SUMMARIZED", map[string]any{"turn": turn, "kept": len(kept), "compressed": omitted, "cached": cached})
+					history = withHistorySummary(kept, summary)
+					break
+				}
+				lg.Warn("history_summary_failed", "session_id", sessionID, "error", sumErr)
+				fallthrough
+			default:
+				_ = p.RecordStep(ctx, sessionID, "HISTORY_TRIMMED", map[string]any{"turn": turn, "kept": len(kept), "omitted": omitted})
+				history = withOmittedMarker(kept, omitted)
+			}
+		}
 
 		// 2) RAG context (Domain/Body/Soul) via Memory gRPC.
 		var rag *pb.RAGContextResponse
-		{
+		if !memoryOpen {
 			ctxStep, stepSpan := tracer.Start(ctx, "MemoryAccess.RAGContext")
-			rag, err = p.callMemoryGetRAGContext(ctxStep, prompt)
+			rag, err = p.callMemoryGetRAGContext(ctxStep, prompt, kbs, topK)
 			if err != nil {
 				stepSpan.RecordError(err)
 			}
 			stepSpan.End()
 		}
-		if err != nil {
+		if memoryOpen {
+			// Skipped above; the prompt gets an empty <rag_context>.
+		} else if err != nil {
 			lg.Warn("rag_context_unavailable", "error", err)
 			rag = nil
+		} else if len(rag.GetMatches()) == 0 && len(p.cfg.RAGFallbackKBs) > 0 {
+			// Cascade to the broader fallback KBs only when the primary retrieval came back empty.
+			ctxStep, stepSpan := tracer.Start(ctx, "MemoryAccess.RAGContextFallback")
+			fallback, fbErr := p.callMemoryGetRAGContext(ctxStep, prompt, p.cfg.RAGFallbackKBs, topK)
+			if fbErr != nil {
+				stepSpan.RecordError(fbErr)
+			}
+			stepSpan.End()
+			found := len(fallback.GetMatches())
+			event := map[string]any{"kbs": p.cfg.RAGFallbackKBs, "matches": found}
+			if fbErr != nil {
+				event["error"] = fbErr.Error()
+				lg.Warn("rag_fallback_unavailable", "kbs", p.cfg.RAGFallbackKBs, "error", fbErr)
+			} else if found > 0 {
+				rag = fallback
+			}
+			_ = p.RecordStep(ctx, sessionID, "RAG_FALLBACK", event)
 		}
 
-		plannerInput := buildPlannerPrompt(prompt, history, rag)
+		if rag != nil && p.cfg.RAGDedup {
+			found := len(rag.GetMatches())
+			rag.Matches = dedupeRAGMatches(rag.GetMatches(), p.cfg.RAGDedupSimilarity)
+			if n := found - len(rag.GetMatches()); n > 0 {
+				_ = p.RecordStep(ctx, sessionID, "RAG_DEDUPED", map[string]any{"turn": turn, "kept": len(rag.GetMatches()), "duplicates": n})
+			}
+		}
+		if rag != nil && p.cfg.RAGRerank {
+			rag.Matches = rerankRAGMatches(rag.GetMatches())
+		}
+		if dropped := filterRAGMatches(rag, p.cfg.RAGMinScore, p.cfg.RAGMaxMatches); dropped > 0 {
+			_ = p.RecordStep(ctx, sessionID, "RAG_FILTERED", map[string]any{"turn": turn, "kept": len(rag.GetMatches()), "dropped": dropped, "min_score": p.cfg.RAGMinScore, "max_matches": p.cfg.RAGMaxMatches})
+		}
+		rag = p.scanRAGContext(ctx, sessionID, rag)
+		rememberMatches(injectedMatches, rag)
+		plannerInput := p.withCitationsInstruction(p.withToolsOptionalInstruction(withLocaleInstruction(buildPlannerPrompt(prompt, history, rag), locale)))
 
 		// 3) Planning via Model Gateway.
 		var planResp *pb.PlanResponse
-		{
-			ctxStep, stepSpan := tracer.Start(ctx, "PlanGeneration")
-			planResp, err = p.callModelGatewayGetPlan(ctxStep, plannerInput, resources)
-			if err != nil {
-				stepSpan.RecordError(err)
-			}
-			stepSpan.End()
-		}
+		planResp, err = generatePlan(plannerInput)
 		if err != nil {
-			_ = p.RecordStep(ctx, sessionID, "PLAN_ERROR", map[string]any{"error": err.Error()})
-			return "", fmt.Errorf("GetPlan: %w", err)
+			if turnTimedOut() {
+				if abortErr := onTurnTimeout(turn, "plan"); abortErr != nil {
+					return res, abortErr
+				}
+				continue
+			}
+			return planFailed(err)
 		}
-		_ = p.RecordStep(ctx, sessionID, "PLAN_MODEL_RESPONSE", map[string]any{"plan": planResp.GetPlan()})
 
-		toolCall := tryParseToolCall(planResp.GetPlan())
-		if toolCall == nil {
-			// Successful completion path (non-tool-call final answer).
-			playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": planResp.GetPlan()})
-			_ = p.RecordStep(ctx, sessionID, "PLAN_END", map[string]any{"result": planResp.GetPlan()})
-			if hadToolStep {
-				_ = p.storePlaybook(ctx, sessionID, basePrompt, playbookSeq)
+		if p.cfg.ToolsOptional {
+			if answer, ok := parseFinalAnswer(planResp.GetPlan()); ok {
+				_ = p.RecordStep(ctx, sessionID, "DIRECT_ANSWER", map[string]any{"turn": turn, "tool_used": len(res.ToolOutcomes) > 0})
+				lg.Info("direct_answer", "session_id", sessionID, "turn", turn)
+				cite(planResp.GetPlan())
+				complete(answer, OutcomeCompleted)
+				return res, nil
 			}
-			_ = p.storeSessionDelta(ctx, sessionID, prompt, planResp.GetPlan())
-			_ = p.PublishNotification(ctx, sessionID, planResp.GetPlan())
-			_ = p.PublishStatus(ctx, sessionID, "COMPLETED")
-			return planResp.GetPlan(), nil
 		}
 
-		_ = p.RecordStep(ctx, sessionID, "TOOL_CALL", map[string]any{"tool": toolCall.Name, "args": toolCall.Args})
-
-		// 4) Tool execution via Rust sandbox ToolService over gRPC.
-		var toolOut string
-		{
-			ctxStep, stepSpan := tracer.Start(ctx, "ToolCallExecution")
-			stepSpan.SetAttributes(attribute.String("tool.name", toolCall.Name))
-			toolOut, err = p.executeTool(ctxStep, toolCall.Name, toolCall.Args)
+		toolCalls := tryParseToolCalls(planResp.GetPlan())
+		if len(toolCalls) == 0 && p.cfg.RepromptBrokenToolCall && looksLikeBrokenToolCall(planResp.GetPlan()) {
+			// Ask once for a corrected tool call instead of accepting it as the final answer.
+			_ = p.RecordStep(ctx, sessionID, "TOOLCALL_REPAIR", map[string]any{"turn": turn, "plan": planResp.GetPlan()})
+			lg.Warn("broken_tool_call_reprompt", "session_id", sessionID, "turn", turn)
+			planResp, err = generatePlan(buildToolCallRepairPrompt(plannerInput, planResp.GetPlan()))
 			if err != nil {
-				stepSpan.RecordError(err)
+				if turnTimedOut() {
+					if abortErr := onTurnTimeout(turn, "plan"); abortErr != nil {
+						return res, abortErr
+					}
+					continue
+				}
+				return planFailed(err)
 			}
-			stepSpan.End()
+			toolCalls = tryParseToolCalls(planResp.GetPlan())
 		}
-		if err != nil {
-			_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": err.Error()})
-			// Feed tool error back into the loop.
-			prompt = prompt + "\n\nTool error: " + err.Error()
+		if len(toolCalls) == 0 {
+			// Successful completion path (non-tool-call final answer).
+			cite(planResp.GetPlan())
+			complete(finalAnswer(planResp.GetPlan()), OutcomeCompleted)
+			return res, nil
+		}
+		names := toolCallNames(toolCalls)
+
+		if p.toolCallBudgetExhausted(ctx, sessionID) {
+			_ = p.RecordStep(ctx, sessionID, "TOOL_BUDGET_EXHAUSTED", map[string]any{"tool": names[0], "cap": p.cfg.SessionToolCallCap})
+			prompt = prompt + "\n\nTool error: the tool-call budget for this session is exhausted; answer without using tools."
+			continue
+		}
+		var argErr error
+		for i := range toolCalls {
+			if argErr = p.checkFlatToolArgs(&toolCalls[i]); argErr != nil {
+				_ = p.RecordStep(ctx, sessionID, "TOOL_ARGS_REJECTED", map[string]any{"tool": toolCalls[i].Name, "args": toolCalls[i].Args, "error": argErr.Error()})
+				break
+			}
+		}
+		if argErr != nil {
+			prompt = prompt + "\n\nTool error: " + argErr.Error()
+			continue
+		}
+		if blockedTool, blockReason := p.blockedToolCall(toolCalls); blockedTool != "" {
+			_ = p.RecordStep(ctx, sessionID, "TOOL_BLOCKED", map[string]any{"turn": turn, "tool": blockedTool, "reason": blockReason})
+			lg.Warn("tool_blocked", "session_id", sessionID, "turn", turn, "tool", blockedTool, "reason", blockReason)
+			prompt = prompt + "\n\n" + buildToolBlockedMessage(blockedTool, blockReason)
+			continue
+		}
+		if unknown, available := p.unknownTool(toolCalls); unknown != "" {
+			_ = p.RecordStep(ctx, sessionID, "TOOL_HALLUCINATED", map[string]any{"turn": turn, "tool": unknown, "available": available})
+			lg.Warn("tool_hallucinated", "session_id", sessionID, "turn", turn, "tool", unknown)
+			prompt = prompt + "\n\n" + buildUnknownToolMessage(unknown, available)
+			continue
+		}
+
+		if fp, count, loop := repeatedToolCall(executedCalls, toolCalls, p.cfg.ToolLoopThreshold); loop {
+			action := p.cfg.ToolLoopAction
+			if action == "" {
+				action = toolLoopWarn
+			}
+			_ = p.RecordStep(ctx, sessionID, "TOOL_LOOP_DETECTED", map[string]any{"turn": turn, "fingerprint": fp, "count": count, "action": action})
+			lg.Warn("tool_loop_detected", "session_id", sessionID, "turn", turn, "fingerprint", fp, "count", count, "action", action)
+			if action == toolLoopAbort {
+				res.Result = "Detected tool-call loop: " + fp + " was requested " + strconv.Itoa(count) + " times; unable to complete request."
+				res.Outcome = OutcomeToolLoop
+				return res, nil
+			}
+			prompt = prompt + "\n\n" + buildToolLoopMessage(fp, count)
 			continue
 		}
-		_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT", map[string]any{"tool": toolCall.Name, "output": toolOut})
 
-		hadToolStep = true
-		playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": planResp.GetPlan()})
-		playbookSeq = append(playbookSeq, map[string]string{"role": "tool_result", "content": toolOut})
+		if p.requiresConfirmation(toolCalls) && p.cfg.ApprovalMode == approvalModeWait {
+			lg.Info("tool_approval_requested", "session_id", sessionID, "tools", names)
+			if decision := p.awaitApproval(ctx, sessionID, turn, toolCalls); decision != approvalApproved {
+				lg.Warn("tool_approval_rejected", "session_id", sessionID, "tools", names, "decision", decision)
+				prompt = prompt + "\n\n" + buildApprovalRejectedMessage(names, decision)
+				continue
+			}
+		} else if p.requiresConfirmation(toolCalls) {
+			pending := &pendingConfirmation{
+				Request:       req,
+				Turn:          turn,
+				Plan:          planResp.GetPlan(),
+				Tools:         toolCalls,
+				Prompt:        prompt,
+				Playbook:      playbookSeq,
+				HadToolStep:   hadToolStep,
+				ExecutedCalls: executedCalls,
+				Result:        *res,
+			}
+			token, saveErr := p.savePendingConfirmation(ctx, pending)
+			if saveErr != nil {
+				// Fail closed: without stored state the tools cannot be confirmed later.
+				_ = p.RecordStep(ctx, sessionID, "TOOL_CONFIRMATION_UNAVAILABLE", map[string]any{"tools": names, "error": saveErr.Error()})
+				lg.Warn("tool_confirmation_unavailable", "session_id", sessionID, "tools", names, "error", saveErr)
+				prompt = prompt + "\n\nTool error: " + strings.Join(names, ", ") + " requires confirmation, which is currently unavailable; answer without it."
+				continue
+			}
+			_ = p.RecordStep(ctx, sessionID, "TOOL_CONFIRMATION_REQUESTED", map[string]any{"tools": toolCalls, "turn": turn})
+			_ = p.PublishStatus(ctx, sessionID, "PENDING_CONFIRMATION")
+			res.Result = "Awaiting confirmation to run " + strings.Join(names, ", ") + "."
+			res.Outcome = OutcomePendingConfirmation
+			res.ConfirmationToken = token
+			res.PendingTools = toolCalls
+			return res, nil
+		}
 
-		// 5) Loop/feedback.
-		prompt = buildFollowupPrompt(prompt, planResp.GetPlan(), toolOut)
-		_ = p.storeSessionDelta(ctx, sessionID, "[tool-plan]", planResp.GetPlan())
-		_ = p.storeSessionDelta(ctx, sessionID, "[tool-output]", toolOut)
+		runToolSteps(planResp.GetPlan(), toolCalls)
+		if turnTimedOut() {
+			// The timed-out tool's error is already in the prompt for the next turn.
+			if abortErr := onTurnTimeout(turn, "tools"); abortErr != nil {
+				return res, abortErr
+			}
+		}
 	}
+	cancelTurn()
+	ctx = runCtx
+
+	if p.cfg.ForceFinalOnMaxTurns && hadToolStep {
+		// Every turn asked for a tool: synthesize a best-effort answer from the
+		// tool results gathered so far instead of discarding them.
+		_ = p.RecordStep(ctx, sessionID, "FORCED_FINAL", map[string]any{"turns": maxTurns, "tools": res.ToolsUsed()})
+		lg.Info("forced_final_answer", "session_id", sessionID, "turns", maxTurns)
+		var planResp *pb.PlanResponse
+		planResp, err = generatePlan(buildForcedFinalPrompt(prompt))
+		if err != nil {
+			return planFailed(err)
+		}
+		if len(tryParseToolCalls(planResp.GetPlan())) == 0 {
+			cite(planResp.GetPlan())
+			complete(finalAnswer(planResp.GetPlan()), OutcomeForcedFinal)
+			return res, nil
+		}
+		lg.Warn("forced_final_returned_tool_call", "session_id", sessionID)
+	}
+
+	res.Result = "Max turns reached; unable to complete request."
+	res.Outcome = OutcomeMaxTurns
+	return res, nil
+}
 
-	return "Max turns reached; unable to complete request.", nil
+// buildForcedFinalPrompt asks for a final answer from the accumulated tool results, without tools.
+func buildForcedFinalPrompt(prompt string) string {
+	return prompt + "\n\n<instruction>\nNo more tool calls are allowed. Using only the tool results above, " +
+		"produce the final answer now as STRICT JSON with 'steps' (array of strings). Do not return a 'tool' object.\n</instruction>\n"
 }
 
 func buildPlannerPrompt(userPrompt string, history []map[string]any, rag *pb.RAGContextResponse) string {
@@ -617,17 +2127,131 @@ func buildFollowupPrompt(originalPrompt, plan, toolResult string) string {
 	return originalPrompt + "\n\n<plan>\n" + plan + "\n</plan>\n\n<tool_result>\n" + toolResult + "\n</tool_result>\n"
 }
 
-func tryParseToolCall(planJSON string) *ToolCall {
-	// Minimal parsing strategy:
-	// - if JSON contains {"tool": {"name": ..., "args": {...}}} treat it as tool call.
+// toolStepResult is the model-facing output of one tool call in a multi-tool turn.
+type toolStepResult struct {
+	index  int
+	name   string
+	output string
+	failed bool
+}
+
+// buildMultiToolFollowupPrompt is buildFollowupPrompt for a turn with several tool
+// calls; each result is tagged with the call's index and tool name.
+func buildMultiToolFollowupPrompt(originalPrompt, plan string, results []toolStepResult) string {
+	var b strings.Builder
+	b.WriteString(originalPrompt + "\n\n<plan>\n" + plan + "\n</plan>\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n<tool_result index=\"%d\" name=\"%s\">\n%s\n</tool_result>\n", r.index, r.name, r.output)
+	}
+	return b.String()
+}
+
+// toolCallNames returns the names of calls, in order.
+func toolCallNames(calls []ToolCall) []string {
+	names := make([]string, 0, len(calls))
+	for _, c := range calls {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// brokenToolCallRe matches JSON-ish "tool"/"args" keys in model output.
+var brokenToolCallRe = regexp.MustCompile(`"(tool|args)"\s*:`)
+
+// looksLikeBrokenToolCall reports whether a plan that did not parse as a tool call
+// was nevertheless meant to be one. The gateway wraps unparseable output as a
+// single step, so that step's text is inspected as well as the plan itself.
+func looksLikeBrokenToolCall(planJSON string) bool {
 	var raw map[string]any
 	if err := json.Unmarshal([]byte(planJSON), &raw); err != nil {
+		return brokenToolCallRe.MatchString(planJSON)
+	}
+	if _, ok := raw["tool"]; ok {
+		// A "tool" key that tryParseToolCalls rejected (wrong type or missing name).
+		return true
+	}
+	if _, ok := raw["tools"]; ok {
+		return true
+	}
+	if steps, ok := raw["steps"].([]any); ok && len(steps) == 1 {
+		if text, ok := steps[0].(string); ok {
+			return brokenToolCallRe.MatchString(text)
+		}
+	}
+	return false
+}
+
+// buildToolCallRepairPrompt asks the model to re-emit a malformed tool call as strict JSON.
+func buildToolCallRepairPrompt(plannerInput, brokenPlan string) string {
+	return plannerInput + "\n<invalid_tool_call>\n" + brokenPlan + "\n</invalid_tool_call>\n\n" +
+		"Your previous response looked like a tool call but was not valid JSON. " +
+		"Return the corrected tool call as STRICT JSON of the form {\"tool\":{\"name\":\"...\",\"args\":{...}}}, " +
+		"or a final plan if no tool is needed.\n"
+}
+
+// tryParseToolCall returns the first tool call in the model's output, if any.
+func tryParseToolCall(planJSON string) *ToolCall {
+	calls := tryParseToolCalls(planJSON)
+	if len(calls) == 0 {
+		return nil
+	}
+	return &calls[0]
+}
+
+// tryParseToolCalls returns the tool calls in the model's output: a single
+// {"tool": {"name": ..., "args": {...}}} or a {"tools": [{"name": ..., "args": {...}}, ...]}
+// array. Besides a bare JSON object it accepts a fenced (```json) object and an
+// object embedded in explanatory prose; the first object with tool calls wins.
+func tryParseToolCalls(planJSON string) []ToolCall {
+	candidates := []string{planJSON, jsonextract.StripFences(planJSON)}
+	candidates = append(candidates, jsonextract.Candidates(planJSON)...)
+	for _, c := range candidates {
+		if calls := parseToolCallsObject(c); len(calls) > 0 {
+			return calls
+		}
+	}
+	return nil
+}
+
+// parseToolCallsObject parses s as a "tool" or "tools" object; "tool" takes
+// precedence when both are present.
+func parseToolCallsObject(s string) []ToolCall {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &raw); err != nil {
 		return nil
 	}
-	toolObj, ok := raw["tool"].(map[string]any)
+	if toolObj, ok := raw["tool"].(map[string]any); ok {
+		tc := toolCallFrom(toolObj, raw)
+		if tc == nil {
+			return nil
+		}
+		return []ToolCall{*tc}
+	}
+	toolsAny, ok := raw["tools"].([]any)
 	if !ok {
 		return nil
 	}
+	calls := make([]ToolCall, 0, len(toolsAny))
+	for _, t := range toolsAny {
+		toolObj, ok := t.(map[string]any)
+		if !ok {
+			return nil
+		}
+		// Accept both {"name": ...} and {"tool": {"name": ...}} entries.
+		if nested, ok := toolObj["tool"].(map[string]any); ok {
+			toolObj = nested
+		}
+		tc := toolCallFrom(toolObj, raw)
+		if tc == nil {
+			return nil
+		}
+		calls = append(calls, *tc)
+	}
+	return calls
+}
+
+// toolCallFrom builds a ToolCall from a {"name": ..., "args": {...}} object.
+func toolCallFrom(toolObj, raw map[string]any) *ToolCall {
 	name, _ := toolObj["name"].(string)
 	args, _ := toolObj["args"].(map[string]any)
 	if strings.TrimSpace(name) == "" {
@@ -637,22 +2261,27 @@ func tryParseToolCall(planJSON string) *ToolCall {
 }
 
 func (p *Planner) fetchSessionHistory(ctx context.Context, sessionID string) ([]map[string]any, error) {
-	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/latest?session_id=" + sessionID
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("memory/latest: %s", string(b))
-	}
-	var payload struct {
-		Messages []map[string]any `json:"messages"`
-	}
-	_ = json.NewDecoder(resp.Body).Decode(&payload)
-	return payload.Messages, nil
+	var messages []map[string]any
+	err := p.withMemoryBreaker(func() error {
+		url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/latest?session_id=" + sessionID
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			b, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("memory/latest: %s", string(b))
+		}
+		var payload struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&payload)
+		messages = payload.Messages
+		return nil
+	})
+	return messages, err
 }
 
 func (p *Planner) storeSessionDelta(ctx context.Context, sessionID, userPrompt, assistantText string) error {
@@ -667,14 +2296,19 @@ func (p *Planner) storeSessionDelta(ctx context.Context, sessionID, userPrompt,
 		"llm_response": map[string]any{"text": assistantText},
 	}
 	b, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	return p.withMemoryBreaker(func() error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("memory/store: status %d", resp.StatusCode)
+		}
+		return nil
+	})
 }
 
 func (p *Planner) storePlaybook(
@@ -687,11 +2321,6 @@ func (p *Planner) storePlaybook(
 	// The Memory Service is responsible for converting this into a Chroma document.
 	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/playbook"
 
-	// Skip storing trivial 1-step sessions (no tool use), but keep the call-site simple.
-	if len(historySequence) < 3 {
-		return nil
-	}
-
 	payload := map[string]any{
 		"session_id":       sessionID,
 		"prompt":           prompt,
@@ -713,20 +2342,33 @@ func (p *Planner) storePlaybook(
 	return nil
 }
 
-func (p *Planner) executeTool(ctx context.Context, toolName string, args map[string]any) (string, error) {
-	return p.executeToolGRPC(ctx, toolName, args)
+func (p *Planner) executeTool(ctx context.Context, sessionID, toolName string, args map[string]any) (string, error) {
+	release, err := p.toolLimiter.acquire(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("waiting for a tool execution slot: %w", err)
+	}
+	defer release()
+	if p.cfg.ToolTransport == toolTransportHTTP {
+		return p.executeToolHTTP(ctx, sessionID, toolName, args)
+	}
+	return p.executeToolGRPC(ctx, sessionID, toolName, args)
 }
 
-func (p *Planner) executeToolGRPC(ctx context.Context, toolName string, args map[string]any) (string, error) {
-	if p.toolClient == nil {
-		return "", fmt.Errorf("rust sandbox tool client is nil")
+func (p *Planner) executeToolGRPC(ctx context.Context, sessionID, toolName string, args map[string]any) (string, error) {
+	sb, sandboxTool, err := p.toolRouter.resolve(toolName)
+	if err != nil {
+		return "", err
+	}
+	client := sb.client()
+	if client == nil {
+		return "", fmt.Errorf("tool sandbox client is nil")
 	}
 
 	if args == nil {
 		args = map[string]any{}
 	}
 
-	argsJSON, err := json.Marshal(args)
+	argsJSON, err := canonicalArgsJSON(args)
 	if err != nil {
 		return "", fmt.Errorf("marshal tool args: %w", err)
 	}
@@ -739,24 +2381,43 @@ func (p *Planner) executeToolGRPC(ctx context.Context, toolName string, args map
 	const defaultMemoryLimitMB int32 = 512
 	const defaultTimeoutSeconds int32 = 30
 
-	resp, err := p.toolClient.ExecuteTool(ctx, &pb.ToolRequest{
-		ToolName:             toolName,
-		ArgsJson:             string(argsJSON),
-		ExecutionEnvironment: defaultExecutionEnvironment,
-		CpuLimitMhz:          defaultCPULimitMHz,
-		MemoryLimitMb:        defaultMemoryLimitMB,
-		TimeoutSeconds:       defaultTimeoutSeconds,
+	resp, err := withGRPCRetry(ctx, p, "rust_sandbox", isTransportGRPC, func() (*pb.ToolResponse, error) {
+		return client.ExecuteTool(ctx, &pb.ToolRequest{
+			ToolName:             sandboxTool,
+			ArgsJson:             string(argsJSON),
+			ExecutionEnvironment: defaultExecutionEnvironment,
+			CpuLimitMhz:          defaultCPULimitMHz,
+			MemoryLimitMb:        defaultMemoryLimitMB,
+			TimeoutSeconds:       defaultTimeoutSeconds,
+		})
 	})
 	if err != nil {
-		return "", fmt.Errorf("ExecuteTool(%q): %w", toolName, err)
+		return "", fmt.Errorf("ExecuteTool(%q) on sandbox %q: %w", sandboxTool, sb.name, err)
+	}
+
+	// A failure status is a tool error, so the model gets the error feedback
+	// instead of treating the output as a result.
+	if err := checkToolStatus(toolName, resp.GetStatus(), resp.GetStdout(), resp.GetStderr()); err != nil {
+		return "", err
 	}
+	return p.toolOutput(ctx, sessionID, toolName, resp.GetStatus(), resp.GetStdout(), resp.GetStderr()), nil
+}
 
+// toolOutput encodes a sandbox response as the tool result given to the model.
+func (p *Planner) toolOutput(ctx context.Context, sessionID, toolName, status, fullStdout, fullStderr string) string {
 	// Keep the tool output structured (LLM-friendly) and consistent across tools.
+	// Oversized stdout/stderr is cut in the middle (AGENT_MAX_TOOL_OUTPUT_BYTES) so
+	// it does not flood the prompt, playbook and audit trail.
+	stdout, stdoutCut := truncateMiddle(fullStdout, p.cfg.MaxToolOutputBytes)
+	stderr, stderrCut := truncateMiddle(fullStderr, p.cfg.MaxToolOutputBytes)
 	out := map[string]any{
-		"status": resp.GetStatus(),
-		"stdout": resp.GetStdout(),
-		"stderr": resp.GetStderr(),
+		"status": status,
+		"stdout": stdout,
+		"stderr": stderr,
+	}
+	if stdoutCut+stderrCut > 0 && p.cfg.AuditFullToolOutput {
+		_ = p.RecordStep(ctx, sessionID, "TOOL_OUTPUT_FULL", map[string]any{"tool": toolName, "stdout": fullStdout, "stderr": fullStderr})
 	}
 	encoded, _ := json.Marshal(out)
-	return string(encoded), nil
+	return string(encoded)
 }
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Reasons recorded in PLAYBOOK_SKIPPED.
+const (
+	playbookSkipFiltered    = "content_filtered"
+	playbookSkipNoTool      = "no_tool_step"
+	playbookSkipTooShort    = "too_few_steps"
+	playbookSkipStoreFailed = "store_failed"
+)
+
+// savePlaybook stores a completed run's sequence as a Mind-KB playbook, or
+// records why it was not stored.
+//
+// Storing only multi-step tool runs (the default) keeps Mind-KB focused on
+// reusable procedures; AGENT_MIN_PLAYBOOK_STEPS=1 with
+// AGENT_PLAYBOOK_REQUIRE_TOOL=false also captures single-shot answers, at the
+// cost of more, less distinctive documents competing in RAG results. A run
+// whose prompt and tool sequence match a recently stored playbook is skipped.
+func (p *Planner) savePlaybook(ctx context.Context, sessionID, prompt string, seq []map[string]string, tools []string, hadToolStep, filtered bool) {
+	minSteps := p.cfg.MinPlaybookSteps
+	skip := func(reason string, extra map[string]any) {
+		data := map[string]any{"reason": reason, "steps": len(seq), "min_steps": minSteps, "tool_used": hadToolStep}
+		for k, v := range extra {
+			data[k] = v
+		}
+		_ = p.RecordStep(ctx, sessionID, "PLAYBOOK_SKIPPED", data)
+	}
+	switch {
+	case filtered:
+		skip(playbookSkipFiltered, nil)
+		return
+	case p.cfg.PlaybookRequireTool && !hadToolStep:
+		skip(playbookSkipNoTool, nil)
+		return
+	case len(seq) < minSteps:
+		skip(playbookSkipTooShort, nil)
+		return
+	}
+	fp := newPlaybookFingerprint(prompt, tools)
+	if p.recentPlaybooks != nil {
+		if similarity, ok := p.recentPlaybooks.match(fp, p.cfg.PlaybookDedupSimilarity); ok {
+			_ = p.RecordStep(ctx, sessionID, "PLAYBOOK_DUPLICATE_SKIPPED", map[string]any{
+				"similarity": similarity,
+				"threshold":  p.cfg.PlaybookDedupSimilarity,
+				"tools":      tools,
+			})
+			return
+		}
+	}
+	if err := p.storePlaybook(ctx, sessionID, prompt, seq); err != nil {
+		skip(playbookSkipStoreFailed, map[string]any{"error": err.Error()})
+		return
+	}
+	if p.recentPlaybooks != nil {
+		p.recentPlaybooks.add(fp)
+	}
+	_ = p.RecordStep(ctx, sessionID, "PLAYBOOK_STORED", map[string]any{"steps": len(seq), "min_steps": minSteps, "tool_used": hadToolStep})
+}
+
+// playbookFingerprint identifies a playbook by its prompt's words and the tools
+// it called, in order.
+type playbookFingerprint struct {
+	tools string
+	words map[string]bool
+}
+
+func newPlaybookFingerprint(prompt string, tools []string) playbookFingerprint {
+	return playbookFingerprint{tools: strings.Join(tools, ","), words: promptFingerprint(prompt)}
+}
+
+// recentPlaybooks remembers the fingerprints of the most recently stored
+// playbooks, so near-identical runs are not stored in Mind-KB again.
+type recentPlaybooks struct {
+	mu   sync.Mutex
+	size int
+	// items is ordered least recently used first.
+	items []playbookFingerprint
+}
+
+func newRecentPlaybooks(size int) *recentPlaybooks {
+	if size <= 0 {
+		return nil
+	}
+	return &recentPlaybooks{size: size}
+}
+
+// match reports the best prompt similarity among remembered playbooks with the
+// same tool sequence, if it reaches threshold. A match counts as a use.
+func (r *recentPlaybooks) match(fp playbookFingerprint, threshold float64) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	best, bestIdx := 0.0, -1
+	for i, it := range r.items {
+		if it.tools != fp.tools {
+			continue
+		}
+		if s := jaccard(it.words, fp.words); s >= threshold && s > best {
+			best, bestIdx = s, i
+		}
+	}
+	if bestIdx < 0 {
+		return 0, false
+	}
+	it := r.items[bestIdx]
+	r.items = append(append(r.items[:bestIdx], r.items[bestIdx+1:]...), it)
+	return best, true
+}
+
+// add remembers fp, evicting the least recently used fingerprint when full.
+func (r *recentPlaybooks) add(fp playbookFingerprint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.items) >= r.size {
+		r.items = r.items[1:]
+	}
+	r.items = append(r.items, fp)
+}
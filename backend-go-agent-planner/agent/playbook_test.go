@@ -0,0 +1,29 @@
+package agent
+
+import "testing"
+
+func TestRecentPlaybooks_Match(t *testing.T) {
+	r := newRecentPlaybooks(2)
+	r.add(newPlaybookFingerprint("list the files in the project directory", []string{"list_dir"}))
+	r.add(newPlaybookFingerprint("read the config file", []string{"read_file"}))
+
+	if _, ok := r.match(newPlaybookFingerprint("list the files in the project directory", []string{"read_file"}), 0.9); ok {
+		t.Fatal("different tool sequence matched")
+	}
+	if _, ok := r.match(newPlaybookFingerprint("summarize the quarterly sales report", []string{"list_dir"}), 0.9); ok {
+		t.Fatal("unrelated prompt matched")
+	}
+	s, ok := r.match(newPlaybookFingerprint("List the files in the project directory!", []string{"list_dir"}), 0.9)
+	if !ok || s != 1 {
+		t.Fatalf("match = %v, %v; want 1, true", s, ok)
+	}
+
+	// The match made list_dir the most recently used, so the next add evicts read_file.
+	r.add(newPlaybookFingerprint("search the web for news", []string{"web_search"}))
+	if _, ok := r.match(newPlaybookFingerprint("read the config file", []string{"read_file"}), 0.9); ok {
+		t.Fatal("evicted fingerprint still matched")
+	}
+	if _, ok := r.match(newPlaybookFingerprint("list the files in the project directory", []string{"list_dir"}), 0.9); !ok {
+		t.Fatal("recently used fingerprint was evicted")
+	}
+}
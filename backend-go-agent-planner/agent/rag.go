@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// KnownKnowledgeBases are the standard knowledge bases served by the memory
+// service, used when AGENT_KNOWN_KBS is not set.
+//
+// Per-request knowledge base overrides are validated against Config.KnownKBs.
+var KnownKnowledgeBases = []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"}
+
+// knownKBs returns the knowledge bases requests may select.
+func (p *Planner) knownKBs() []string {
+	if p != nil && len(p.cfg.KnownKBs) > 0 {
+		return p.cfg.KnownKBs
+	}
+	return KnownKnowledgeBases
+}
+
+// ValidateRAGOverrides checks per-request knowledge base and top_k overrides.
+//
+// An empty kbs slice and a zero topK mean "use the configured default".
+func (p *Planner) ValidateRAGOverrides(kbs []string, topK int) error {
+	knownKBs := p.knownKBs()
+	known := make(map[string]bool, len(knownKBs))
+	for _, kb := range knownKBs {
+		known[kb] = true
+	}
+	for _, kb := range kbs {
+		if !known[kb] {
+			return fmt.Errorf("unknown knowledge base %q (known: %s)", kb, strings.Join(knownKBs, ", "))
+		}
+	}
+
+	maxTopK := 20
+	if p != nil && p.cfg.MaxTopK > 0 {
+		maxTopK = p.cfg.MaxTopK
+	}
+	if topK < 0 || topK > maxTopK {
+		return fmt.Errorf("top_k must be between 1 and %d", maxTopK)
+	}
+	return nil
+}
+
+const (
+	adaptiveTopKFullWords = 200
+	adaptiveTopKItemBonus = 20
+)
+
+// adaptiveTopK scales top_k with prompt complexity within [minK, maxK]
+// (AGENT_RAG_ADAPTIVE_TOPK). Complexity is the word count plus a bonus for each
+// additional question or list item, since those usually need separate context.
+// A prompt of adaptiveTopKFullWords or more gets maxK.
+func adaptiveTopK(prompt string, minK, maxK int) int {
+	if minK < 1 {
+		minK = 1
+	}
+	if maxK < minK {
+		maxK = minK
+	}
+	score := len(strings.Fields(prompt))
+	if q := strings.Count(prompt, "?"); q > 1 {
+		score += (q - 1) * adaptiveTopKItemBonus
+	}
+	for _, line := range strings.Split(prompt, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || (len(line) > 1 && line[0] >= '0' && line[0] <= '9' && (line[1] == '.' || line[1] == ')')) {
+			score += adaptiveTopKItemBonus
+		}
+	}
+	if score >= adaptiveTopKFullWords {
+		return maxK
+	}
+	return minK + (maxK-minK)*score/adaptiveTopKFullWords
+}
+
+// ragMatchScore converts a match's distance (lower is closer) into a relevance
+// score in (0, 1], so AGENT_RAG_MIN_SCORE works regardless of the distance metric.
+func ragMatchScore(m *pb.RAGMatch) float64 {
+	d := m.GetDistance()
+	if d < 0 {
+		d = 0
+	}
+	return 1 / (1 + d)
+}
+
+// filterRAGMatches drops matches scoring below minScore and keeps at most
+// maxMatches of the rest, closest first (0 disables either limit). Matches from
+// different knowledge bases are ranked together. It returns the number dropped.
+func filterRAGMatches(rag *pb.RAGContextResponse, minScore float64, maxMatches int) int {
+	if rag == nil || (minScore <= 0 && maxMatches <= 0) {
+		return 0
+	}
+	kept := rag.GetMatches()[:0:0]
+	for _, m := range rag.GetMatches() {
+		if minScore <= 0 || ragMatchScore(m) >= minScore {
+			kept = append(kept, m)
+		}
+	}
+	if maxMatches > 0 && len(kept) > maxMatches {
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].GetDistance() < kept[j].GetDistance() })
+		kept = kept[:maxMatches]
+	}
+	dropped := len(rag.GetMatches()) - len(kept)
+	rag.Matches = kept
+	return dropped
+}
+
+// dedupeRAGMatches collapses matches whose texts are identical after
+// normalization or whose word-shingle Jaccard similarity is at least similarity,
+// keeping the highest-scoring (closest) instance of each group in its original
+// position.
+func dedupeRAGMatches(matches []*pb.RAGMatch, similarity float64) []*pb.RAGMatch {
+	best := rerankRAGMatches(matches)
+	type keptMatch struct {
+		norm     string
+		shingles map[string]bool
+	}
+	var kept []keptMatch
+	keep := map[*pb.RAGMatch]bool{}
+	for _, m := range best {
+		norm := normalizeMatchText(m.GetText())
+		shingles := textShingles(norm)
+		duplicate := false
+		for _, k := range kept {
+			if norm == k.norm || jaccard(shingles, k.shingles) >= similarity {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, keptMatch{norm: norm, shingles: shingles})
+			keep[m] = true
+		}
+	}
+	out := make([]*pb.RAGMatch, 0, len(kept))
+	for _, m := range matches {
+		if keep[m] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// rerankRAGMatches returns matches ordered by score, best (closest) first.
+func rerankRAGMatches(matches []*pb.RAGMatch) []*pb.RAGMatch {
+	out := append([]*pb.RAGMatch(nil), matches...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].GetDistance() < out[j].GetDistance() })
+	return out
+}
+
+// normalizeMatchText lowercases text and reduces it to space-separated words.
+func normalizeMatchText(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// textShingles returns the set of 3-word shingles of normalized text (the text
+// itself when it is shorter).
+func textShingles(norm string) map[string]bool {
+	const size = 3
+	words := strings.Fields(norm)
+	set := map[string]bool{}
+	if len(words) < size {
+		set[norm] = true
+		return set
+	}
+	for i := 0; i+size <= len(words); i++ {
+		set[strings.Join(words[i:i+size], " ")] = true
+	}
+	return set
+}
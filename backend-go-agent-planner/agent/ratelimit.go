@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle rate limit buckets are dropped.
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens left for one key as of last.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per key (session ID or client IP) refilling at
+// rps up to burst tokens.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rps     float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rps: rps, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token for key at now. When the bucket is empty it returns false
+// and how long until a token is available.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rps)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / l.rps * float64(time.Second)), false
+}
+
+// sweep drops buckets idle long enough to have refilled completely; a new
+// bucket for the same key would be identical.
+func (l *rateLimiter) sweep(now time.Time) {
+	full := time.Duration(l.burst / l.rps * float64(time.Second))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimiter) runSweeper(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.sweep(now)
+		}
+	}
+}
+
+// AllowRequest applies the per-session rate limit (Config.RateLimitRPS) to a
+// /plan request. Requests are keyed on sessionID, or on clientIP when there is
+// no session. A limited request records a RATE_LIMITED audit step, and the
+// returned duration is when the client may retry.
+func (p *Planner) AllowRequest(ctx context.Context, sessionID, clientIP string) (time.Duration, bool) {
+	if p == nil || p.rateLimiter == nil {
+		return 0, true
+	}
+	key := "session:" + sessionID
+	if sessionID == "" {
+		key = "ip:" + clientIP
+	}
+	retryAfter, ok := p.rateLimiter.allow(key, time.Now())
+	if !ok {
+		_ = p.RecordStep(ctx, sessionID, "RATE_LIMITED", map[string]any{
+			"key":            key,
+			"retry_after_ms": retryAfter.Milliseconds(),
+			"rps":            p.cfg.RateLimitRPS,
+			"burst":          p.cfg.RateLimitBurst,
+		})
+	}
+	return retryAfter, ok
+}
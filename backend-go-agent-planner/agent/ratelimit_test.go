@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 2)
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow("a", now); !ok {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+	retry, ok := l.allow("a", now)
+	if ok {
+		t.Fatal("request beyond burst was allowed")
+	}
+	if retry != 500*time.Millisecond {
+		t.Fatalf("retry after = %v, want 500ms", retry)
+	}
+	if _, ok := l.allow("b", now); !ok {
+		t.Fatal("other key was limited")
+	}
+	if _, ok := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("request after refill was limited")
+	}
+
+	// "a" is empty again and "b" has a token left; after a full refill both are dropped.
+	l.sweep(now.Add(900 * time.Millisecond))
+	if len(l.buckets) != 2 {
+		t.Fatalf("swept %d buckets before they refilled", 2-len(l.buckets))
+	}
+	l.sweep(now.Add(2 * time.Second))
+	if len(l.buckets) != 0 {
+		t.Fatalf("%d idle buckets left after sweep", len(l.buckets))
+	}
+}
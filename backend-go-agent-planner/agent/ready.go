@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+const gatewayReadyPollInterval = time.Second
+
+// waitForGateway polls the Model Gateway Health RPC until it reports SERVING
+// (its LLM provider is reachable) or timeout elapses.
+func (p *Planner) waitForGateway(ctx context.Context, timeout time.Duration) error {
+	lg := logger.NewContextLogger(ctx)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(gatewayReadyPollInterval)
+	defer ticker.Stop()
+	var last string
+	for {
+		probeCtx, probeCancel := context.WithTimeout(ctx, 3*time.Second)
+		resp, err := p.modelClient.Health(probeCtx, &pb.HealthRequest{})
+		probeCancel()
+		switch {
+		case err != nil:
+			last = err.Error()
+		case resp.GetStatus() == "SERVING":
+			lg.Info("model_gateway_ready", "provider", resp.GetProvider(), "model", resp.GetModel())
+			return nil
+		default:
+			last = fmt.Sprintf("%s (provider=%s): %s", resp.GetStatus(), resp.GetProvider(), resp.GetError())
+		}
+		lg.Warn("model_gateway_not_ready", "status", last)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("model gateway not ready after %s: %s", timeout, last)
+		case <-ticker.C:
+		}
+	}
+}
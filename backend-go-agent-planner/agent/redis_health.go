@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+)
+
+// redisPingTimeout bounds a single Redis health check.
+const redisPingTimeout = 2 * time.Second
+
+// redisAvailable reports whether Redis answered the last health check.
+func (p *Planner) redisAvailable() bool {
+	return p.redisUp.Load()
+}
+
+// setRedisAvailable records the result of a Redis health check and logs
+// transitions between available and unavailable.
+func (p *Planner) setRedisAvailable(ctx context.Context, up bool, err error) {
+	if p.redisUp.Swap(up) == up {
+		return
+	}
+	lg := logger.NewContextLogger(ctx)
+	if up {
+		lg.Info("redis_available", "addr", p.cfg.RedisAddr)
+		return
+	}
+	lg.Warn("redis_unavailable", "addr", p.cfg.RedisAddr, "error", err)
+}
+
+// runRedisHealthChecks pings Redis every interval until ctx is done. The client
+// reconnects on its own; the checks track availability and, on recovery, send
+// the notifications buffered while Redis was down.
+func (p *Planner) runRedisHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, redisPingTimeout)
+		err := p.redis.Ping(pingCtx).Err()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		wasUp := p.redisAvailable()
+		p.setRedisAvailable(ctx, err == nil, err)
+		if err == nil && !wasUp {
+			if err := p.flushUndelivered(ctx); err != nil {
+				logger.NewContextLogger(ctx).Warn("redis_publish_failed", "stage", "recovery_flush", "buffered", p.undeliveredLen(), "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+)
+
+const (
+	defaultResultStoreRegion = "us-east-1"
+	sigV4Algorithm           = "AWS4-HMAC-SHA256"
+	unsignedPayload          = "UNSIGNED-PAYLOAD"
+	amzDateFormat            = "20060102T150405Z"
+)
+
+// resultStore persists large final answers to an S3-compatible bucket
+// (AGENT_RESULT_STORE=s3://bucket/prefix) and returns presigned GET URLs.
+// Requests are signed with AWS Signature V4 and use path-style addressing, so
+// MinIO and other S3-compatible services work as well as AWS.
+type resultStore struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	urlTTL    time.Duration
+	client    *http.Client
+}
+
+// newResultStore parses Config.ResultStore; it returns nil when the store is disabled.
+// Credentials come from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY.
+func newResultStore(cfg Config, client *http.Client) (*resultStore, error) {
+	if cfg.ResultStore == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.ResultStore)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("AGENT_RESULT_STORE must look like s3://bucket/prefix, got %q", cfg.ResultStore)
+	}
+	region := cfg.ResultStoreRegion
+	if region == "" {
+		region = defaultResultStoreRegion
+	}
+	endpoint := cfg.ResultStoreEndpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	ep, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || ep.Host == "" {
+		return nil, fmt.Errorf("invalid AGENT_RESULT_STORE_ENDPOINT %q", endpoint)
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AGENT_RESULT_STORE requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &resultStore{
+		endpoint:  ep,
+		bucket:    u.Host,
+		prefix:    prefix,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		urlTTL:    cfg.ResultURLTTL,
+		client:    client,
+	}, nil
+}
+
+// put uploads a session's result and returns a presigned URL to download it.
+func (s *resultStore) put(ctx context.Context, sessionID, result string) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	key := s.prefix + url.PathEscape(sessionID) + "/" + time.Now().UTC().Format(amzDateFormat) + "-" + hex.EncodeToString(suffix) + ".txt"
+	u := s.objectURL(key)
+
+	body := []byte(result)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	sum := sha256.Sum256(body)
+	s.signHeaders(req, hex.EncodeToString(sum[:]), time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("put result object: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("put result object: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return s.presign(http.MethodGet, u, s.urlTTL, time.Now()), nil
+}
+
+// objectURL returns the path-style URL of key.
+func (s *resultStore) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket + "/" + key
+	return &u
+}
+
+// signHeaders adds SigV4 Authorization headers to req for a payload with the given hash.
+func (s *resultStore) signHeaders(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers["content-type"] = ct
+	}
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(headers)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := s.scope(now)
+	signature := s.sign(now, scope, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.accessKey, scope, signedHeaders, signature))
+}
+
+// presign returns a SigV4 query-signed URL for method on u, valid for ttl.
+func (s *resultStore) presign(method string, u *url.URL, ttl time.Duration, now time.Time) string {
+	scope := s.scope(now)
+	query := map[string]string{
+		"X-Amz-Algorithm":     sigV4Algorithm,
+		"X-Amz-Credential":    s.accessKey + "/" + scope,
+		"X-Amz-Date":          now.UTC().Format(amzDateFormat),
+		"X-Amz-Expires":       strconv.Itoa(int(ttl.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	canonicalQuery := canonicalizeQuery(query)
+	canonicalRequest := strings.Join([]string{
+		method,
+		awsURIEncode(u.Path, false),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signature := s.sign(now, scope, canonicalRequest)
+	signed := *u
+	signed.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+func (s *resultStore) scope(now time.Time) string {
+	return now.UTC().Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// sign computes the SigV4 signature of canonicalRequest.
+func (s *resultStore) sign(now time.Time, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigV4Algorithm + "\n" + now.UTC().Format(amzDateFormat) + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalizeHeaders returns the SigV4 signed-headers list and canonical header block.
+func canonicalizeHeaders(headers map[string]string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// canonicalizeQuery returns the sorted, URI-encoded SigV4 canonical query string.
+func canonicalizeQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(query[k], true))
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, as SigV4
+// requires; "/" is kept unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// offloadResult uploads final when a result store is configured and the answer
+// exceeds Config.ResultInlineMaxBytes, returning its URL. It returns "" to keep the
+// result inline, including when the upload fails.
+func (p *Planner) offloadResult(ctx context.Context, sessionID, final string) string {
+	if p.resultStore == nil || len(final) <= p.cfg.ResultInlineMaxBytes {
+		return ""
+	}
+	u, err := p.resultStore.put(ctx, sessionID, final)
+	if err != nil {
+		logger.NewContextLogger(ctx).Warn("result_store_put_failed", "session_id", sessionID, "bytes", len(final), "error", err)
+		return ""
+	}
+	return u
+}
+
+// resultReference is the text stored in history and notifications for an offloaded result.
+func resultReference(u string, size int) string {
+	return fmt.Sprintf("[result of %d bytes stored at %s]", size, u)
+}
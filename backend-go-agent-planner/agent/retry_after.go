@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// retryAfterTrailer is the model gateway's trailer carrying the LLM provider's
+// Retry-After, in seconds, on throttled calls.
+const retryAfterTrailer = "retry-after"
+
+// retryAfterError is a model gateway error that came with a retry-after trailer.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.err, e.after)
+}
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// withRetryAfter wraps err with the retry-after trailer, if present.
+func withRetryAfter(err error, trailer metadata.MD) error {
+	if err == nil {
+		return nil
+	}
+	for _, v := range trailer.Get(retryAfterTrailer) {
+		if secs, convErr := strconv.Atoi(v); convErr == nil && secs >= 0 {
+			return &retryAfterError{err: err, after: time.Duration(secs) * time.Second}
+		}
+	}
+	return err
+}
+
+// RetryAfter returns how long the LLM provider asked callers to wait before
+// retrying, when err was caused by provider throttling.
+func RetryAfter(err error) (time.Duration, bool) {
+	var ra *retryAfterError
+	if errors.As(err, &ra) {
+		return ra.after, true
+	}
+	return 0, false
+}
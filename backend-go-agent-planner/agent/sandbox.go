@@ -0,0 +1,392 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Tool name conflict policies (AGENT_TOOL_NAME_CONFLICT).
+//
+// They decide what happens when two sandboxes declare a tool with the same name:
+//   - error:      refuse to start (default).
+//   - first:      the first sandbox (in AGENT_SANDBOXES order) owns the bare name.
+//   - namespaced: the bare name is ambiguous; the model must call "sandbox:tool".
+const (
+	toolConflictError      = "error"
+	toolConflictFirst      = "first"
+	toolConflictNamespaced = "namespaced"
+)
+
+// toolNamespaceSep separates the sandbox name from the tool name ("sandbox:tool").
+const toolNamespaceSep = ":"
+
+// SandboxConfig describes a single tool sandbox the planner can route tool calls to.
+//
+// Tools is the list of tool names the sandbox exposes. A sandbox with no declared
+// tools still receives namespaced calls ("sandbox:tool"), and the first sandbox
+// additionally receives any tool call that does not match the routing table.
+//
+// Addr is the sandbox's gRPC address; HTTPURL is the base URL of its HTTP API,
+// used instead with TOOL_TRANSPORT=http.
+type SandboxConfig struct {
+	Name    string   `json:"name"`
+	Addr    string   `json:"addr"`
+	HTTPURL string   `json:"http_url"`
+	Tools   []string `json:"tools"`
+}
+
+// parseSandboxes decodes AGENT_SANDBOXES (a JSON array of SandboxConfig).
+//
+// When unset, a single "default" sandbox at defaultAddr (defaultHTTPURL over
+// HTTP) is returned so that single-sandbox deployments keep routing every tool
+// call to RUST_SANDBOX_GRPC_ADDR (RUST_SANDBOX_URL). Each declared sandbox needs
+// the address of the transport in use.
+func parseSandboxes(spec, defaultAddr, defaultHTTPURL, transport string) ([]SandboxConfig, error) {
+	if strings.TrimSpace(spec) == "" {
+		return []SandboxConfig{{Name: "default", Addr: defaultAddr, HTTPURL: defaultHTTPURL}}, nil
+	}
+
+	var sandboxes []SandboxConfig
+	if err := json.Unmarshal([]byte(spec), &sandboxes); err != nil {
+		return nil, fmt.Errorf("parse AGENT_SANDBOXES: %w", err)
+	}
+	if len(sandboxes) == 0 {
+		return nil, fmt.Errorf("AGENT_SANDBOXES must declare at least one sandbox")
+	}
+
+	seen := make(map[string]bool, len(sandboxes))
+	for i, sb := range sandboxes {
+		name := strings.TrimSpace(sb.Name)
+		if transport == toolTransportHTTP {
+			if name == "" || strings.TrimSpace(sb.HTTPURL) == "" {
+				return nil, fmt.Errorf("AGENT_SANDBOXES[%d] must include non-empty name and http_url", i)
+			}
+		} else if name == "" || strings.TrimSpace(sb.Addr) == "" {
+			return nil, fmt.Errorf("AGENT_SANDBOXES[%d] must include non-empty name and addr", i)
+		}
+		if strings.Contains(name, toolNamespaceSep) {
+			return nil, fmt.Errorf("AGENT_SANDBOXES[%d]: sandbox name %q must not contain %q", i, name, toolNamespaceSep)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("AGENT_SANDBOXES: duplicate sandbox name %q", name)
+		}
+		seen[name] = true
+		sandboxes[i].Name = name
+	}
+	return sandboxes, nil
+}
+
+// sandbox is a dialed tool sandbox backed by a pool of gRPC connections.
+//
+// Tool executions are round-robined across the pool. With a pool size of 1 (the
+// default) this is equivalent to a single shared connection.
+type sandbox struct {
+	name    string
+	addr    string
+	httpURL string
+	tools   []string
+
+	dial func(ctx context.Context, addr string) (*grpc.ClientConn, error)
+
+	mu    sync.RWMutex
+	conns []*grpc.ClientConn
+	next  atomic.Uint64
+}
+
+// newSandbox dials poolSize connections to the sandbox.
+func newSandbox(ctx context.Context, cfg SandboxConfig, poolSize int, dial func(ctx context.Context, addr string) (*grpc.ClientConn, error)) (*sandbox, error) {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	sb := &sandbox{name: cfg.Name, addr: cfg.Addr, httpURL: cfg.HTTPURL, tools: cfg.Tools, dial: dial}
+	for i := 0; i < poolSize; i++ {
+		conn, err := dial(ctx, cfg.Addr)
+		if err != nil {
+			_ = sb.Close()
+			return nil, err
+		}
+		sb.conns = append(sb.conns, conn)
+	}
+	return sb, nil
+}
+
+// client returns a ToolService client on the next pooled connection, preferring
+// connections that are not in a failure state.
+func (s *sandbox) client() pb.ToolServiceClient {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := len(s.conns)
+	if n == 0 {
+		return nil
+	}
+	start := s.next.Add(1) - 1
+	for i := 0; i < n; i++ {
+		conn := s.conns[(start+uint64(i))%uint64(n)]
+		if st := conn.GetState(); st != connectivity.TransientFailure && st != connectivity.Shutdown {
+			return pb.NewToolServiceClient(conn)
+		}
+	}
+	return pb.NewToolServiceClient(s.conns[start%uint64(n)])
+}
+
+// healthCheck replaces pooled connections that are shut down or failing.
+func (s *sandbox) healthCheck(ctx context.Context, lg *slog.Logger) {
+	s.mu.RLock()
+	var dead []int
+	for i, conn := range s.conns {
+		if st := conn.GetState(); st == connectivity.TransientFailure || st == connectivity.Shutdown {
+			dead = append(dead, i)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, i := range dead {
+		conn, err := s.dial(ctx, s.addr)
+		if err != nil {
+			lg.Warn("sandbox_conn_replace_failed", "sandbox", s.name, "addr", s.addr, "slot", i, "error", err)
+			continue
+		}
+		s.mu.Lock()
+		if i >= len(s.conns) {
+			// The pool was closed while dialing.
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		old := s.conns[i]
+		s.conns[i] = conn
+		s.mu.Unlock()
+		_ = old.Close()
+		lg.Info("sandbox_conn_replaced", "sandbox", s.name, "addr", s.addr, "slot", i)
+	}
+}
+
+func (s *sandbox) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+	return nil
+}
+
+// runSandboxHealthChecks periodically health-checks every sandbox pool until ctx is done.
+func runSandboxHealthChecks(ctx context.Context, sandboxes []*sandbox, interval time.Duration, lg *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, sb := range sandboxes {
+				sb.healthCheck(ctx, lg)
+			}
+		}
+	}
+}
+
+// toolRoute is a single entry of the tool routing table.
+type toolRoute struct {
+	sandbox *sandbox
+	// tool is the tool name sent to the sandbox (never namespaced).
+	tool string
+}
+
+// toolRouter resolves tool names emitted by the model to a sandbox.
+//
+// It is built once at startup and is read-only afterwards.
+type toolRouter struct {
+	policy string
+	routes map[string]toolRoute
+	// ambiguous maps a colliding bare tool name to the sandboxes exposing it
+	// (only populated for the namespaced policy).
+	ambiguous map[string][]string
+	// fallback receives tool calls that are not in the routing table.
+	fallback  *sandbox
+	byName    map[string]*sandbox
+	sandboxes []*sandbox
+}
+
+func normalizeToolConflictPolicy(policy string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+	case "":
+		return toolConflictError, nil
+	case toolConflictError, toolConflictFirst, toolConflictNamespaced:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported AGENT_TOOL_NAME_CONFLICT=%q (supported: error, first, namespaced)", policy)
+	}
+}
+
+// buildToolRouter builds the tool routing table for the given sandboxes,
+// applying the configured conflict policy to tools declared by more than one sandbox.
+func buildToolRouter(sandboxes []*sandbox, policy string) (*toolRouter, error) {
+	policy, err := normalizeToolConflictPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	if len(sandboxes) == 0 {
+		return nil, fmt.Errorf("no tool sandboxes configured")
+	}
+
+	r := &toolRouter{
+		policy:    policy,
+		routes:    map[string]toolRoute{},
+		ambiguous: map[string][]string{},
+		fallback:  sandboxes[0],
+		byName:    make(map[string]*sandbox, len(sandboxes)),
+		sandboxes: sandboxes,
+	}
+
+	// owners preserves registration order per bare tool name.
+	owners := map[string][]*sandbox{}
+	var order []string
+	for _, sb := range sandboxes {
+		r.byName[sb.name] = sb
+		for _, tool := range sb.tools {
+			tool = strings.TrimSpace(tool)
+			if tool == "" {
+				continue
+			}
+			if _, ok := owners[tool]; !ok {
+				order = append(order, tool)
+			}
+			owners[tool] = append(owners[tool], sb)
+			// Namespaced names are always routable, regardless of policy.
+			r.routes[sb.name+toolNamespaceSep+tool] = toolRoute{sandbox: sb, tool: tool}
+		}
+	}
+
+	var conflicts []string
+	for _, tool := range order {
+		sbs := owners[tool]
+		if len(sbs) == 1 {
+			r.routes[tool] = toolRoute{sandbox: sbs[0], tool: tool}
+			continue
+		}
+		names := make([]string, 0, len(sbs))
+		for _, sb := range sbs {
+			names = append(names, sb.name)
+		}
+		switch policy {
+		case toolConflictFirst:
+			r.routes[tool] = toolRoute{sandbox: sbs[0], tool: tool}
+		case toolConflictNamespaced:
+			r.ambiguous[tool] = names
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", tool, strings.Join(names, ", ")))
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("tool name conflicts across sandboxes: %s; set AGENT_TOOL_NAME_CONFLICT=first or namespaced", strings.Join(conflicts, "; "))
+	}
+
+	return r, nil
+}
+
+// resolve returns the sandbox and sandbox-local tool name for a tool call.
+func (r *toolRouter) resolve(name string) (*sandbox, string, error) {
+	if r == nil {
+		return nil, "", fmt.Errorf("tool router is nil")
+	}
+	if route, ok := r.routes[name]; ok {
+		return route.sandbox, route.tool, nil
+	}
+	if sbs, ok := r.ambiguous[name]; ok {
+		return nil, "", fmt.Errorf("tool %q is exposed by multiple sandboxes (%s); call it as <sandbox>%s%s", name, strings.Join(sbs, ", "), toolNamespaceSep, name)
+	}
+	// Namespaced call to an undeclared tool: trust the explicit sandbox choice.
+	if prefix, tool, ok := strings.Cut(name, toolNamespaceSep); ok {
+		if sb, ok := r.byName[prefix]; ok && tool != "" {
+			return sb, tool, nil
+		}
+	}
+	return r.fallback, name, nil
+}
+
+// catalog returns the sorted names the model may call, or nil when some sandbox
+// declares no tools and the catalog is therefore incomplete. Tools that are
+// ambiguous under the namespaced policy are listed as "sandbox:tool".
+func (r *toolRouter) catalog() []string {
+	if r == nil {
+		return nil
+	}
+	for _, sb := range r.sandboxes {
+		if len(sb.tools) == 0 {
+			return nil
+		}
+	}
+	var names []string
+	for name, route := range r.routes {
+		// Namespaced aliases of unambiguous tools are routable but not listed.
+		if strings.Contains(name, toolNamespaceSep) {
+			if _, ambiguous := r.ambiguous[route.tool]; !ambiguous {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// known reports whether name is a declared tool (bare, namespaced or ambiguous).
+func (r *toolRouter) known(name string) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := r.routes[name]; ok {
+		return true
+	}
+	_, ok := r.ambiguous[name]
+	return ok
+}
+
+// logRoutes logs the resolved routing table (one line per route) for transparency.
+func (r *toolRouter) logRoutes(lg *slog.Logger) {
+	if r == nil || lg == nil {
+		return
+	}
+	names := make([]string, 0, len(r.routes))
+	for name := range r.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		route := r.routes[name]
+		lg.Info("tool_route", "tool", name, "sandbox", route.sandbox.name, "sandbox_tool", route.tool, "addr", route.sandbox.addr)
+	}
+	ambiguous := make([]string, 0, len(r.ambiguous))
+	for name := range r.ambiguous {
+		ambiguous = append(ambiguous, name)
+	}
+	sort.Strings(ambiguous)
+	lg.Info(
+		"tool_routing_table",
+		"policy", r.policy,
+		"sandboxes", len(r.sandboxes),
+		"routes", len(r.routes),
+		"ambiguous", ambiguous,
+		"fallback_sandbox", r.fallback.name,
+	)
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestToolRouterCatalog_Namespaced(t *testing.T) {
+	a := &sandbox{name: "a", tools: []string{"web_search", "read_file"}}
+	b := &sandbox{name: "b", tools: []string{"read_file"}}
+	r, err := buildToolRouter([]*sandbox{a, b}, toolConflictNamespaced)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	want := []string{"a:read_file", "b:read_file", "web_search"}
+	if got := r.catalog(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("catalog = %v, want %v", got, want)
+	}
+	if !r.known("read_file") || !r.known("a:web_search") || r.known("send_email") {
+		t.Fatalf("unexpected known() results")
+	}
+
+	open := &sandbox{name: "open"}
+	r, _ = buildToolRouter([]*sandbox{a, open}, toolConflictError)
+	if got := r.catalog(); got != nil {
+		t.Fatalf("expected nil catalog when a sandbox declares no tools, got %v", got)
+	}
+}
+
+func TestSandboxHealthCheck_ClosedWhileDialing(t *testing.T) {
+	newConn := func() *grpc.ClientConn {
+		conn, err := grpc.NewClient("passthrough:///sandbox", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return conn
+	}
+	dead := newConn()
+	_ = dead.Close()
+
+	var replacement *grpc.ClientConn
+	sb := &sandbox{name: "default", conns: []*grpc.ClientConn{dead}}
+	sb.dial = func(context.Context, string) (*grpc.ClientConn, error) {
+		// Planner.Close runs while the replacement is being dialed.
+		_ = sb.Close()
+		replacement = newConn()
+		return replacement, nil
+	}
+	sb.healthCheck(context.Background(), slog.Default())
+
+	if len(sb.conns) != 0 {
+		t.Fatalf("conns = %d after Close, want 0", len(sb.conns))
+	}
+	if st := replacement.GetState(); st != connectivity.Shutdown {
+		t.Fatalf("replacement state = %s, want Shutdown", st)
+	}
+}
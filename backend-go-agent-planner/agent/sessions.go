@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"backend-go-agent-planner/audit"
+)
+
+// errAuditUnavailable is returned by audit queries when no audit database is open.
+var errAuditUnavailable = errors.New("audit database unavailable")
+
+// ListSessions returns the sessions recorded in the audit trail, most recently
+// active first.
+func (p *Planner) ListSessions(ctx context.Context, limit, offset int) ([]audit.Session, error) {
+	if p == nil || p.auditDB == nil {
+		return nil, errAuditUnavailable
+	}
+	return p.auditDB.ListSessions(ctx, limit, offset)
+}
+
+// QueryAuditSteps returns the audit steps matching filter in recorded order.
+func (p *Planner) QueryAuditSteps(ctx context.Context, filter audit.StepFilter) ([]audit.Step, error) {
+	if p == nil || p.auditDB == nil {
+		return nil, errAuditUnavailable
+	}
+	return p.auditDB.QuerySteps(ctx, filter)
+}
+
+// ExportSession streams a session's audit trail to w as JSON or CSV (audit.ExportJSON, audit.ExportCSV).
+func (p *Planner) ExportSession(ctx context.Context, sessionID, format string, w io.Writer) error {
+	if p == nil || p.auditDB == nil {
+		return errAuditUnavailable
+	}
+	return p.auditDB.ExportSession(ctx, sessionID, format, w)
+}
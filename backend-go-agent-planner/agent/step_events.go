@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// StepEvent is an audit step recorded while a run progresses, as delivered to
+// a channel registered with WithStepEvents.
+type StepEvent struct {
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+type stepEventsKey struct{}
+
+// stepEvents is the receiver registered with WithStepEvents; done is the
+// receiver's context, after which events are dropped.
+type stepEvents struct {
+	ch   chan<- StepEvent
+	done <-chan struct{}
+}
+
+// WithStepEvents returns a context whose runs send every audit step recorded with
+// it to ch, in order. Sends block until ch has room or ctx is done, so the
+// receiver must drain ch until the run returns or cancel ctx.
+func WithStepEvents(ctx context.Context, ch chan<- StepEvent) context.Context {
+	return context.WithValue(ctx, stepEventsKey{}, stepEvents{ch: ch, done: ctx.Done()})
+}
+
+// emitStepEvent forwards a recorded step to the context's StepEvent channel, if any.
+func emitStepEvent(ctx context.Context, eventType string, data any) {
+	recv, ok := ctx.Value(stepEventsKey{}).(stepEvents)
+	if !ok {
+		return
+	}
+	ev := StepEvent{Type: eventType, Timestamp: time.Now().UTC()}
+	if data != nil {
+		// Encode now: the caller may reuse data after RecordStep returns.
+		if b, err := json.Marshal(data); err == nil {
+			ev.Data = b
+		}
+	}
+	select {
+	case recv.ch <- ev:
+	case <-recv.done:
+	}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// streamPlan consumes GetPlanStream and returns the final normalized plan.
+//
+// While deltas arrive, the accumulated raw output is partially parsed; the first
+// time it names a tool, onToolName is called (at most once per stream).
+func (p *Planner) streamPlan(ctx context.Context, req *pb.PlanRequest, onToolName func(name string)) (*pb.PlanResponse, error) {
+	stream, err := p.modelClient.GetPlanStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw strings.Builder
+	signaled := onToolName == nil
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("plan stream ended without a final chunk")
+		}
+		if err != nil {
+			return nil, withRetryAfter(err, stream.Trailer())
+		}
+		if chunk.GetDone() {
+			return &pb.PlanResponse{
+				Plan:              chunk.GetPlan(),
+				ModelName:         chunk.GetModelName(),
+				LatencyMs:         chunk.GetLatencyMs(),
+				PromptTokens:      chunk.GetPromptTokens(),
+				CompletionTokens:  chunk.GetCompletionTokens(),
+				TotalTokens:       chunk.GetTotalTokens(),
+				EstimatedCostUsd:  chunk.GetEstimatedCostUsd(),
+				SystemFingerprint: chunk.GetSystemFingerprint(),
+			}, nil
+		}
+		raw.WriteString(chunk.GetDelta())
+		if !signaled {
+			if name, ok := partialToolName(raw.String()); ok {
+				signaled = true
+				onToolName(name)
+			}
+		}
+	}
+}
+
+// partialToolName extracts tool.name from a possibly incomplete JSON object such as
+// `{"tool":{"name":"web_search","args":{"que`.
+//
+// Leading prose or a code fence before the first "{" is ignored. It reports false
+// until the name string is complete, and for malformed input.
+func partialToolName(buf string) (string, bool) {
+	start := strings.Index(buf, "{")
+	if start < 0 {
+		return "", false
+	}
+
+	// frame is an open JSON container; key is the most recent key of an object.
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+	var stack []frame
+
+	dec := json.NewDecoder(strings.NewReader(buf[start:]))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				stack = append(stack, frame{object: d == '{', expectKey: d == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+				if len(stack) == 0 {
+					// The top-level object closed without a tool name.
+					return "", false
+				}
+				if top := &stack[len(stack)-1]; top.object {
+					top.expectKey = true
+				}
+			}
+			continue
+		}
+
+		top := &stack[len(stack)-1]
+		if top.object && top.expectKey {
+			top.key, _ = tok.(string)
+			top.expectKey = false
+			continue
+		}
+		if len(stack) == 2 && stack[0].key == "tool" && top.object && top.key == "name" {
+			name, _ := tok.(string)
+			if name = strings.TrimSpace(name); name != "" {
+				return name, true
+			}
+			return "", false
+		}
+		if top.object {
+			top.expectKey = true
+		}
+	}
+}
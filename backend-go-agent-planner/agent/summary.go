@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	summaryTimeout = 30 * time.Second
+	// summaryInputChars bounds the prompt and answer excerpts sent for summarization.
+	summaryInputChars = 2000
+	// summaryMaxChars bounds the stored one-line summary.
+	summaryMaxChars = 200
+)
+
+// ErrSessionSummaryNotFound is returned when no summary is stored for a session.
+var ErrSessionSummaryNotFound = errors.New("session summary not found")
+
+// sessionSummaryKey is the Redis key holding a session's one-line summary.
+func (p *Planner) sessionSummaryKey(sessionID string) string {
+	return p.cfg.NotifyPrefix + "pagi:session:" + sessionID + ":summary"
+}
+
+// summarizeAsync generates and stores a session summary in the background so the
+// run's response is not delayed. It is a no-op unless AGENT_SUMMARIZE_ON_COMPLETE
+// is set and Redis is available.
+func (p *Planner) summarizeAsync(ctx context.Context, sessionID, prompt, answer string) {
+	if !p.cfg.SummarizeOnComplete || p.redis == nil || p.bgCtx == nil {
+		return
+	}
+	lg := logger.NewContextLogger(ctx)
+	// Detach from the request (which ends with the response) but keep its trace ID,
+	// and stop when the planner shuts down.
+	bg, cancel := context.WithTimeout(p.bgCtx, summaryTimeout)
+	if traceID, ok := ctx.Value(logger.TraceIDKey).(string); ok {
+		bg = context.WithValue(bg, logger.TraceIDKey, traceID)
+	}
+	p.bgWG.Add(1)
+	go func() {
+		defer p.bgWG.Done()
+		defer cancel()
+		summary, err := p.summarizeSession(bg, prompt, answer)
+		if err == nil {
+			err = p.redis.Set(bg, p.sessionSummaryKey(sessionID), summary, p.cfg.SummaryTTL).Err()
+		}
+		if err != nil {
+			lg.Warn("session_summary_failed", "session_id", sessionID, "error", err)
+			return
+		}
+		lg.Info("session_summary_stored", "session_id", sessionID, "length", len(summary))
+	}()
+}
+
+// summarizeSession asks the Model Gateway for a one-line title of the exchange.
+func (p *Planner) summarizeSession(ctx context.Context, prompt, answer string) (string, error) {
+	if p.modelClient == nil {
+		return "", fmt.Errorf("model client is nil")
+	}
+	input := "Summarize this conversation as a single short line (at most 12 words) suitable as a title. " +
+		"Reply with the summary only.\n\nUser: " + truncateRunes(prompt, summaryInputChars) +
+		"\n\nAssistant: " + truncateRunes(answer, summaryInputChars)
+	resp, err := p.modelClient.GetPlan(injectTraceIDToOutgoingGRPC(ctx), &pb.PlanRequest{Prompt: input, Model: p.cfg.SummaryModel})
+	if err != nil {
+		return "", fmt.Errorf("summarize: %w", err)
+	}
+	summary := resp.GetPlan()
+	if steps := resp.GetSteps(); len(steps) > 0 {
+		summary = steps[0]
+	}
+	summary = strings.Join(strings.Fields(summary), " ")
+	summary = strings.Trim(summary, `"'`)
+	if summary == "" {
+		return "", fmt.Errorf("summarize: empty summary")
+	}
+	return truncateRunes(summary, summaryMaxChars), nil
+}
+
+// SessionSummary returns the stored one-line summary of a session.
+func (p *Planner) SessionSummary(ctx context.Context, sessionID string) (string, error) {
+	if p == nil || p.redis == nil {
+		return "", errRedisUnavailable
+	}
+	s, err := p.redis.Get(ctx, p.sessionSummaryKey(sessionID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrSessionSummaryNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("get session summary: %w", err)
+	}
+	return s, nil
+}
+
+// truncateRunes shortens s to at most n runes.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrModelNotAllowed is returned by AuthorizeModel when a tenant may not use the
+// requested model.
+var ErrModelNotAllowed = errors.New("model not allowed")
+
+// parseTenantModels parses AGENT_TENANT_MODELS, a JSON object mapping tenant IDs to
+// their allowed models, e.g. {"free":["llama3"],"pro":["llama3","gpt-4o"]}.
+// An empty value disables the restriction (nil map).
+func parseTenantModels(raw string) (map[string]map[string]bool, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var byTenant map[string][]string
+	if err := json.Unmarshal([]byte(raw), &byTenant); err != nil {
+		return nil, fmt.Errorf("parse AGENT_TENANT_MODELS: %w", err)
+	}
+	out := make(map[string]map[string]bool, len(byTenant))
+	for tenant, models := range byTenant {
+		allowed := make(map[string]bool, len(models))
+		for _, m := range models {
+			if m = strings.TrimSpace(m); m != "" {
+				allowed[m] = true
+			}
+		}
+		out[tenant] = allowed
+	}
+	return out, nil
+}
+
+// AuthorizeModel checks a per-request model override against the tenant's
+// allowlist. No override, or no AGENT_TENANT_MODELS configured, is always allowed;
+// otherwise an unknown (or missing) tenant is allowed no overrides.
+func (p *Planner) AuthorizeModel(tenant, model string) error {
+	if model == "" || p == nil || p.tenantModels == nil {
+		return nil
+	}
+	if tenant == "" {
+		return fmt.Errorf("%w: a tenant is required to select a model", ErrModelNotAllowed)
+	}
+	if !p.tenantModels[tenant][model] {
+		return fmt.Errorf("%w: tenant %q may not use model %q", ErrModelNotAllowed, tenant, model)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+)
+
+// Tool transports (TOOL_TRANSPORT).
+const (
+	toolTransportGRPC = "grpc"
+	toolTransportHTTP = "http"
+)
+
+// sandboxHTTPExecutePath is the sandbox's HTTP tool execution endpoint.
+const sandboxHTTPExecutePath = "/api/v1/execute_tool"
+
+// toolHTTPTimeout bounds one HTTP tool execution, matching the model gateway's
+// per-request timeout.
+const toolHTTPTimeout = 60 * time.Second
+
+// maxToolHTTPResponseBytes caps the sandbox response read into memory; output
+// beyond AGENT_MAX_TOOL_OUTPUT_BYTES is cut afterwards anyway.
+const maxToolHTTPResponseBytes = 16 << 20
+
+// newHTTPSandbox returns a sandbox reached over HTTP only; it holds no gRPC
+// connections.
+func newHTTPSandbox(cfg SandboxConfig) *sandbox {
+	return &sandbox{name: cfg.Name, addr: cfg.Addr, httpURL: strings.TrimRight(cfg.HTTPURL, "/"), tools: cfg.Tools}
+}
+
+// sandboxHTTPRequest is the HTTP tool execution request. The sandbox reads args;
+// args_json carries the same arguments as the gRPC ToolRequest does.
+type sandboxHTTPRequest struct {
+	ToolName string          `json:"tool_name"`
+	Args     json.RawMessage `json:"args"`
+	ArgsJSON string          `json:"args_json"`
+}
+
+// sandboxHTTPResponse is the HTTP tool execution response: stdout and stderr at
+// the top level, or nested under result as the Rust sandbox returns them.
+type sandboxHTTPResponse struct {
+	Status string          `json:"status"`
+	Stdout json.RawMessage `json:"stdout"`
+	Stderr json.RawMessage `json:"stderr"`
+	Result *struct {
+		Stdout json.RawMessage `json:"stdout"`
+		Stderr json.RawMessage `json:"stderr"`
+	} `json:"result"`
+}
+
+// outputText returns a stdout/stderr field as text: JSON strings are unquoted,
+// other JSON values are kept as encoded. The Rust sandbox wraps non-JSON stdout
+// as {"stdout": "..."}, which is unwrapped.
+func outputText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var wrapped map[string]json.RawMessage
+	if json.Unmarshal(raw, &wrapped) == nil && len(wrapped) == 1 {
+		if inner, ok := wrapped["stdout"]; ok && json.Unmarshal(inner, &s) == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// isDialError reports whether err means the HTTP request never reached the
+// sandbox. Like isTransportGRPC, only these are retried, so a tool is never run
+// twice.
+func isDialError(ctx context.Context, err error) bool {
+	var opErr *net.OpError
+	return ctx.Err() == nil && errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// executeToolHTTP runs a tool through the sandbox's HTTP API (TOOL_TRANSPORT=http)
+// and returns the same output shape as executeToolGRPC.
+func (p *Planner) executeToolHTTP(ctx context.Context, sessionID, toolName string, args map[string]any) (string, error) {
+	sb, sandboxTool, err := p.toolRouter.resolve(toolName)
+	if err != nil {
+		return "", err
+	}
+	if sb.httpURL == "" {
+		return "", fmt.Errorf("tool sandbox %q has no HTTP URL", sb.name)
+	}
+
+	if args == nil {
+		args = map[string]any{}
+	}
+	argsJSON, err := canonicalArgsJSON(args)
+	if err != nil {
+		return "", fmt.Errorf("marshal tool args: %w", err)
+	}
+	body, err := json.Marshal(sandboxHTTPRequest{ToolName: sandboxTool, Args: argsJSON, ArgsJSON: string(argsJSON)})
+	if err != nil {
+		return "", fmt.Errorf("marshal tool request: %w", err)
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, toolHTTPTimeout)
+	defer cancel()
+	// The shared client's timeout suits memory calls, not tools; ctx2 bounds this call.
+	client := *p.httpClient
+	client.Timeout = 0
+	resp, err := withGRPCRetry(ctx, p, "rust_sandbox", isDialError, func() (*sandboxHTTPResponse, error) {
+		req, err := http.NewRequestWithContext(ctx2, http.MethodPost, sb.httpURL+sandboxHTTPExecutePath, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if traceID, ok := ctx.Value(logger.TraceIDKey).(string); ok && traceID != "" {
+			req.Header.Set("X-Request-Id", traceID)
+		}
+		httpResp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer httpResp.Body.Close()
+		b, err := io.ReadAll(io.LimitReader(httpResp.Body, maxToolHTTPResponseBytes))
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		if httpResp.StatusCode >= 300 {
+			return nil, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(b)))
+		}
+		var out sandboxHTTPResponse
+		if err := json.Unmarshal(b, &out); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &out, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ExecuteTool(%q) over HTTP on sandbox %q: %w", sandboxTool, sb.name, err)
+	}
+
+	stdout, stderr := resp.Stdout, resp.Stderr
+	if resp.Result != nil {
+		stdout, stderr = resp.Result.Stdout, resp.Result.Stderr
+	}
+	if err := checkToolStatus(toolName, resp.Status, outputText(stdout), outputText(stderr)); err != nil {
+		return "", err
+	}
+	return p.toolOutput(ctx, sessionID, toolName, resp.Status, outputText(stdout), outputText(stderr)), nil
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExecuteToolHTTP(t *testing.T) {
+	var got sandboxHTTPRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != sandboxHTTPExecutePath {
+			t.Errorf("path = %s, want %s", r.URL.Path, sandboxHTTPExecutePath)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		// The Rust sandbox's response shape: output nested under result.
+		_, _ = w.Write([]byte(`{"status":"ok","tool_name":"web_search","result":{"stdout":{"stdout":"hello"},"stderr":""}}`))
+	}))
+	defer srv.Close()
+
+	sb := newHTTPSandbox(SandboxConfig{Name: "default", HTTPURL: srv.URL + "/"})
+	router, err := buildToolRouter([]*sandbox{sb}, toolConflictError)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	p := &Planner{cfg: Config{ToolTransport: toolTransportHTTP}, toolRouter: router, httpClient: &http.Client{}}
+
+	out, err := p.executeToolHTTP(context.Background(), "s1", "web_search", map[string]any{"query": "go"})
+	if err != nil {
+		t.Fatalf("executeToolHTTP: %v", err)
+	}
+	if got.ToolName != "web_search" || got.ArgsJSON != `{"query":"go"}` || string(got.Args) != `{"query":"go"}` {
+		t.Fatalf("request = %+v", got)
+	}
+	var res map[string]string
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("decode output %q: %v", out, err)
+	}
+	if res["status"] != "ok" || res["stdout"] != "hello" || res["stderr"] != "" {
+		t.Fatalf("output = %v", res)
+	}
+}
+
+func TestOutputText(t *testing.T) {
+	for raw, want := range map[string]string{
+		`"plain"`:            "plain",
+		`{"stdout":"x"}`:     "x",
+		`{"results":[1, 2]}`: `{"results":[1, 2]}`,
+		`null`:               "",
+	} {
+		if got := outputText(json.RawMessage(raw)); got != want {
+			t.Errorf("outputText(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
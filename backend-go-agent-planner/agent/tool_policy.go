@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// toolBlocked reports why a tool call is not permitted by AGENT_TOOL_DENYLIST or
+// AGENT_TOOL_ALLOWLIST. A namespaced call ("sandbox:tool") is matched on both its
+// full and its bare name; the denylist wins over the allowlist.
+func (p *Planner) toolBlocked(name string) (string, bool) {
+	names := []string{name}
+	if _, bare, ok := strings.Cut(name, toolNamespaceSep); ok && bare != "" {
+		names = append(names, bare)
+	}
+	for _, n := range names {
+		if containsString(p.cfg.ToolDenylist, n) {
+			return "denylisted", true
+		}
+	}
+	if len(p.cfg.ToolAllowlist) == 0 {
+		return "", false
+	}
+	for _, n := range names {
+		if containsString(p.cfg.ToolAllowlist, n) {
+			return "", false
+		}
+	}
+	return "not in allowlist", true
+}
+
+// blockedToolCall returns the first call that is not permitted and why.
+func (p *Planner) blockedToolCall(calls []ToolCall) (string, string) {
+	for _, c := range calls {
+		if reason, blocked := p.toolBlocked(c.Name); blocked {
+			return c.Name, reason
+		}
+	}
+	return "", ""
+}
+
+// buildToolBlockedMessage tells the model a tool may not be used.
+func buildToolBlockedMessage(name, reason string) string {
+	return fmt.Sprintf("Tool error: tool %q is not permitted (%s); use another tool or answer without it.", name, reason)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tool statuses reported by the sandbox in ToolResponse.status. Only the OK
+// statuses are successes; every other status (including unrecognized ones) is
+// a tool error that is fed back to the model.
+const (
+	toolStatusOK                  = "ok"
+	toolStatusSuccess             = "success"
+	toolStatusUnknownTool         = "unknown_tool"
+	toolStatusUnsupportedLanguage = "unsupported_language"
+	toolStatusCompileError        = "compile_error"
+	toolStatusRuntimeError        = "runtime_error"
+	toolStatusIOError             = "io_error"
+	toolStatusNetworkError        = "network_error"
+	toolStatusReadError           = "read_error"
+	// toolStatusHTTPPrefix prefixes upstream HTTP failures, e.g. "http_503".
+	toolStatusHTTPPrefix = "http_"
+)
+
+// toolStatusHints tell the model what a failure status means and what to do next.
+var toolStatusHints = map[string]string{
+	toolStatusUnknownTool:         "the sandbox does not provide this tool; use another tool or answer without it",
+	toolStatusUnsupportedLanguage: "the requested language is not supported by the sandbox",
+	toolStatusCompileError:        "the code did not compile; fix it before running it again",
+	toolStatusRuntimeError:        "the code failed while running; fix it before running it again",
+	toolStatusIOError:             "the sandbox could not run the tool",
+	toolStatusNetworkError:        "the tool could not reach its upstream service",
+	toolStatusReadError:           "the tool could not read its upstream service's response",
+}
+
+// maxToolErrorDetailBytes bounds the tool output quoted in a ToolStatusError.
+const maxToolErrorDetailBytes = 2000
+
+// ToolStatusError is returned when the sandbox ran a tool call but reported a
+// failure status.
+type ToolStatusError struct {
+	Tool   string
+	Status string
+	// Detail is the tool's stderr (or stdout when stderr is empty), truncated.
+	Detail string
+}
+
+func (e *ToolStatusError) Error() string {
+	msg := fmt.Sprintf("tool %q failed with status %q", e.Tool, e.Status)
+	if hint := toolStatusHint(e.Status); hint != "" {
+		msg += " (" + hint + ")"
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func toolStatusHint(status string) string {
+	if hint, ok := toolStatusHints[status]; ok {
+		return hint
+	}
+	if code, ok := strings.CutPrefix(status, toolStatusHTTPPrefix); ok && code != "" {
+		return "the tool's upstream service returned HTTP " + code
+	}
+	return ""
+}
+
+// isToolStatusOK reports whether status is a success. An empty status (from
+// sandboxes that predate it) counts as success.
+func isToolStatusOK(status string) bool {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "", toolStatusOK, toolStatusSuccess:
+		return true
+	}
+	return false
+}
+
+// checkToolStatus returns a *ToolStatusError for a failure status, nil otherwise.
+func checkToolStatus(tool, status, stdout, stderr string) error {
+	if isToolStatusOK(status) {
+		return nil
+	}
+	detail := strings.TrimSpace(stderr)
+	if detail == "" {
+		detail = strings.TrimSpace(stdout)
+	}
+	detail, _ = truncateMiddle(detail, maxToolErrorDetailBytes)
+	return &ToolStatusError{Tool: tool, Status: strings.ToLower(strings.TrimSpace(status)), Detail: detail}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeToolService answers every ExecuteTool with resp.
+type fakeToolService struct {
+	pb.UnimplementedToolServiceServer
+	resp *pb.ToolResponse
+}
+
+func (f *fakeToolService) ExecuteTool(context.Context, *pb.ToolRequest) (*pb.ToolResponse, error) {
+	return f.resp, nil
+}
+
+// grpcToolPlanner returns a planner whose only sandbox answers with resp.
+func grpcToolPlanner(t *testing.T, resp *pb.ToolResponse) *Planner {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterToolServiceServer(srv, &fakeToolService{resp: resp})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	sb := &sandbox{name: "default", conns: []*grpc.ClientConn{conn}}
+	t.Cleanup(func() { _ = sb.Close() })
+	router, err := buildToolRouter([]*sandbox{sb}, toolConflictError)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	return &Planner{toolRouter: router}
+}
+
+func TestExecuteToolGRPC_FailureStatus(t *testing.T) {
+	p := grpcToolPlanner(t, &pb.ToolResponse{Status: "runtime_error", Stderr: "ZeroDivisionError: division by zero"})
+
+	out, err := p.executeToolGRPC(context.Background(), "s1", "execute_code", map[string]any{"code": "1/0"})
+	var statusErr *ToolStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v (output %q), want *ToolStatusError", err, out)
+	}
+	if statusErr.Status != toolStatusRuntimeError || statusErr.Tool != "execute_code" {
+		t.Fatalf("status error = %+v", statusErr)
+	}
+	// The feedback the model gets names the failure, the fix and the tool's stderr.
+	for _, want := range []string{`status "runtime_error"`, "fix it", "ZeroDivisionError"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestExecuteToolGRPC_OKStatus(t *testing.T) {
+	p := grpcToolPlanner(t, &pb.ToolResponse{Status: "ok", Stdout: "42"})
+	out, err := p.executeToolGRPC(context.Background(), "s1", "execute_code", nil)
+	if err != nil {
+		t.Fatalf("executeToolGRPC: %v", err)
+	}
+	if !strings.Contains(out, `"stdout":"42"`) {
+		t.Fatalf("output = %q", out)
+	}
+}
+
+func TestCheckToolStatus(t *testing.T) {
+	for _, status := range []string{"", "ok", "OK", "success"} {
+		if err := checkToolStatus("t", status, "", ""); err != nil {
+			t.Errorf("status %q: unexpected error %v", status, err)
+		}
+	}
+	err := checkToolStatus("web_search", "http_503", "upstream unavailable", "")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503") || !strings.Contains(err.Error(), "upstream unavailable") {
+		t.Fatalf("http_503: err = %v", err)
+	}
+	if err := checkToolStatus("t", "exploded", "", ""); err == nil {
+		t.Fatal("unrecognized status treated as success")
+	}
+}
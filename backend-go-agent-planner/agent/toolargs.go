@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// nestedArg returns the first (by key) arg whose value is an object or array.
+func nestedArg(args map[string]any) (string, bool) {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch args[k].(type) {
+		case map[string]any, []any:
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// checkFlatToolArgs enforces AGENT_TOOL_ARGS_FLAT. It returns a correction for
+// the model when a tool not listed in AGENT_TOOL_ARGS_NESTED_TOOLS receives a
+// nested object or array arg, or nil when the call may proceed.
+func (p *Planner) checkFlatToolArgs(call *ToolCall) error {
+	if !p.cfg.ToolArgsFlat {
+		return nil
+	}
+	for _, name := range p.cfg.ToolArgsNestedTools {
+		if name == call.Name {
+			return nil
+		}
+	}
+	key, ok := nestedArg(call.Args)
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("tool %q only accepts flat args: arg %q must be a string, number, boolean or null, not an object or array; retry the call with flat args", call.Name, key)
+}
+
+// canonicalArgsJSON encodes tool args for the sandbox's args_json and for
+// tool-call fingerprints. encoding/json sorts map keys at every level, so the
+// output is deterministic for identical args; nil args encode as {}.
+func canonicalArgsJSON(args map[string]any) ([]byte, error) {
+	if args == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(args)
+}
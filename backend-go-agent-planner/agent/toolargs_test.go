@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalArgsJSON_Nested(t *testing.T) {
+	// The same logical args, built with different insertion orders.
+	a := map[string]any{
+		"query": "search",
+		"filters": map[string]any{
+			"tags":  []any{"x", map[string]any{"z": 1, "a": true}},
+			"range": map[string]any{"to": 10, "from": 1},
+		},
+	}
+	b := map[string]any{
+		"filters": map[string]any{
+			"range": map[string]any{"from": 1, "to": 10},
+			"tags":  []any{"x", map[string]any{"a": true, "z": 1}},
+		},
+		"query": "search",
+	}
+
+	const want = `{"filters":{"range":{"from":1,"to":10},"tags":["x",{"a":true,"z":1}]},"query":"search"}`
+	for name, args := range map[string]map[string]any{"a": a, "b": b} {
+		got, err := canonicalArgsJSON(args)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s:\n got %s\nwant %s", name, got, want)
+		}
+	}
+	if toolCallFingerprint(ToolCall{Name: "search", Args: a}) != toolCallFingerprint(ToolCall{Name: "search", Args: b}) {
+		t.Fatal("fingerprints differ for identical logical args")
+	}
+	if got, _ := canonicalArgsJSON(nil); string(got) != "{}" {
+		t.Fatalf("nil args = %s, want {}", got)
+	}
+}
+
+func TestCanonicalArgsJSON_NumbersUnchanged(t *testing.T) {
+	got, err := canonicalArgsJSON(map[string]any{
+		"limit": json.Number("5.0"),
+		"id":    json.Number("12345678901234567890"),
+		"ratio": 0.25,
+		"count": 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"count":3,"id":12345678901234567890,"limit":5.0,"ratio":0.25}`
+	if string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
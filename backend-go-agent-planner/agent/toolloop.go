@@ -0,0 +1,42 @@
+package agent
+
+import "fmt"
+
+// Tool-call loop actions (Config.ToolLoopAction).
+const (
+	toolLoopWarn  = "warn"
+	toolLoopAbort = "abort"
+)
+
+// toolCallFingerprint identifies a tool call by name and canonical arguments
+// (canonicalArgsJSON), so argument order does not matter.
+func toolCallFingerprint(c ToolCall) string {
+	args, err := canonicalArgsJSON(c.Args)
+	if err != nil {
+		args = []byte("{}")
+	}
+	return c.Name + ":" + string(args)
+}
+
+// repeatedToolCall returns the fingerprint of the first call that has already
+// been executed threshold-1 times in this run, i.e. that would repeat for the
+// threshold-th time. A threshold below 2 disables detection.
+func repeatedToolCall(executed map[string]int, calls []ToolCall, threshold int) (string, int, bool) {
+	if threshold < 2 {
+		return "", 0, false
+	}
+	for _, c := range calls {
+		fp := toolCallFingerprint(c)
+		if n := executed[fp] + 1; n >= threshold {
+			return fp, n, true
+		}
+	}
+	return "", 0, false
+}
+
+// buildToolLoopMessage tells the model it is repeating a tool call.
+func buildToolLoopMessage(fingerprint string, count int) string {
+	return fmt.Sprintf("Tool error: you have requested the identical tool call %s %d times. "+
+		"Its result is already above; do not call it again. Use a different tool or arguments, or give the final answer.",
+		fingerprint, count)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultEmptyToolOutputMessage = "tool completed successfully with no output"
+
+// toolOutputForModel returns the tool output as shown to the model. A successful
+// execution with empty stdout and stderr is replaced by an explicit marker
+// (AGENT_EMPTY_TOOL_OUTPUT_MSG) so the model does not mistake it for a failure.
+// The audit trail keeps the original output.
+func (p *Planner) toolOutputForModel(toolOut string) string {
+	var out struct {
+		Status string `json:"status"`
+		Stdout string `json:"stdout"`
+		Stderr string `json:"stderr"`
+	}
+	if err := json.Unmarshal([]byte(toolOut), &out); err != nil {
+		return toolOut
+	}
+	switch strings.ToLower(out.Status) {
+	case "ok", "success":
+	default:
+		return toolOut
+	}
+	if strings.TrimSpace(out.Stdout) != "" || strings.TrimSpace(out.Stderr) != "" {
+		return toolOut
+	}
+	encoded, _ := json.Marshal(map[string]any{
+		"status": out.Status,
+		"output": p.cfg.EmptyToolOutputMessage,
+	})
+	return string(encoded)
+}
+
+// repeatedToolOutputMessage stands in for a tool result already shown to the model.
+const repeatedToolOutputMessage = "[identical to an earlier tool_result above]"
+
+// compressRepeatedToolResults returns results with every output that already
+// appears as a tool_result in prompt, or earlier in results, replaced by
+// repeatedToolOutputMessage, and the number replaced. The first copy stays in the
+// prompt, so the model still sees the full text once. results is not modified.
+func compressRepeatedToolResults(prompt string, results []toolStepResult) ([]toolStepResult, int) {
+	out := make([]toolStepResult, len(results))
+	copy(out, results)
+	seen := make(map[string]bool, len(results))
+	repeated := 0
+	for i, r := range out {
+		if len(r.output) <= len(repeatedToolOutputMessage) {
+			continue
+		}
+		if seen[r.output] || strings.Contains(prompt, ">\n"+r.output+"\n</tool_result>") {
+			out[i].output = repeatedToolOutputMessage
+			repeated++
+			continue
+		}
+		seen[r.output] = true
+	}
+	return out, repeated
+}
+
+// truncateMiddle caps s at about maxBytes by cutting out its middle and inserting
+// a "[truncated N bytes]" marker, keeping the head and tail. Cuts fall on rune
+// boundaries so valid UTF-8 stays valid. maxBytes <= 0 disables truncation.
+func truncateMiddle(s string, maxBytes int) (string, int) {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s, 0
+	}
+	head := maxBytes / 2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tail := len(s) - (maxBytes - maxBytes/2)
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	cut := tail - head
+	return s[:head] + fmt.Sprintf("\n[truncated %d bytes]\n", cut) + s[tail:], cut
+}
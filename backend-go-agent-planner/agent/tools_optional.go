@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+
+	"backend-go-model-gateway/jsonextract"
+)
+
+// toolsOptionalInstruction tells the model it may answer without a tool
+// (AGENT_TOOLS_OPTIONAL).
+const toolsOptionalInstruction = "\n<tool_policy>\nTools are optional. If you can answer the user directly from your own knowledge and the context above, " +
+	"do not call a tool: respond with STRICT JSON {\"final_answer\": \"<your answer>\"}. " +
+	"Only return a 'tool' object when the answer needs external data or an action.\n</tool_policy>\n"
+
+// withToolsOptionalInstruction appends the tool policy when Config.ToolsOptional is set.
+func (p *Planner) withToolsOptionalInstruction(plannerInput string) string {
+	if !p.cfg.ToolsOptional {
+		return plannerInput
+	}
+	return plannerInput + toolsOptionalInstruction
+}
+
+// parseFinalAnswer returns the answer of an explicit {"final_answer": "..."} action.
+// The gateway wraps objects it does not recognize as a single plan step, so that
+// step's text is inspected as well as the plan itself.
+func parseFinalAnswer(planJSON string) (string, bool) {
+	texts := []string{planJSON}
+	var plan struct {
+		Steps []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plan); err == nil && len(plan.Steps) == 1 {
+		texts = append(texts, plan.Steps[0])
+	}
+	for _, text := range texts {
+		candidates := append([]string{jsonextract.StripFences(text)}, jsonextract.Candidates(text)...)
+		for _, c := range candidates {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(c), &obj); err != nil {
+				continue
+			}
+			if answer, ok := obj["final_answer"].(string); ok && strings.TrimSpace(answer) != "" {
+				return answer, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,13 @@
+package agent
+
+import "errors"
+
+// ErrTurnTimeout is returned by AgentLoop when a turn exceeds Config.TurnTimeout
+// and Config.TurnTimeoutAction is "abort".
+var ErrTurnTimeout = errors.New("agent turn timed out")
+
+// Turn timeout actions (Config.TurnTimeoutAction).
+const (
+	turnTimeoutRetry = "retry"
+	turnTimeoutAbort = "abort"
+)
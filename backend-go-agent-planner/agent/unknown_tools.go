@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unknownTool returns the first call to a tool missing from the sandbox catalog,
+// together with the catalog. It returns "" when Config.RejectUnknownTools is off or
+// the catalog is incomplete.
+func (p *Planner) unknownTool(calls []ToolCall) (string, []string) {
+	if !p.cfg.RejectUnknownTools {
+		return "", nil
+	}
+	available := p.toolRouter.catalog()
+	if available == nil {
+		return "", nil
+	}
+	for _, c := range calls {
+		if !p.toolRouter.known(c.Name) {
+			return c.Name, available
+		}
+	}
+	return "", nil
+}
+
+// buildUnknownToolMessage tells the model which tool does not exist and which do.
+func buildUnknownToolMessage(name string, available []string) string {
+	return fmt.Sprintf("Tool error: tool %q does not exist; available tools are: [%s]. Call one of these or give the final answer.",
+		name, strings.Join(available, ", "))
+}
@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"github.com/sony/gobreaker"
+)
+
+// asyncWriteTimeout bounds a single background batch insert so a wedged
+// database cannot stall the writer forever.
+const asyncWriteTimeout = 5 * time.Second
+
+// Batching defaults for the async writer.
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 200 * time.Millisecond
+)
+
+// errSlowWrite marks an insert that succeeded but exceeded Options.SlowWriteThreshold.
+// It counts as a failure for the circuit so a degraded database trips it too.
+var errSlowWrite = errors.New("audit write exceeded slow threshold")
+
+// row is a fully prepared audit_log row waiting to be written.
+type row struct {
+	traceID   string
+	sessionID string
+	timestamp time.Time
+	eventType string
+	data      string
+}
+
+func newAuditBreaker(opts Options) *gobreaker.CircuitBreaker {
+	failures := opts.CircuitFailures
+	if failures <= 0 {
+		failures = 5
+	}
+	cooldown := opts.CircuitCooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "audit-db",
+		MaxRequests: 1,
+		Timeout:     cooldown,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= uint32(failures)
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logger.LogCircuitBreakerStateChange(nil, name, from.String(), to.String())
+		},
+	})
+}
+
+// write inserts rows through the circuit in one transaction. While the circuit
+// is open the rows are dropped (and counted) without touching the database.
+func (a *AuditDB) write(ctx context.Context, rows ...row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	_, err := a.breaker.Execute(func() (any, error) {
+		start := time.Now()
+		if err := a.insertBatch(ctx, rows); err != nil {
+			return nil, err
+		}
+		if a.opts.SlowWriteThreshold > 0 && time.Since(start) > a.opts.SlowWriteThreshold {
+			return nil, errSlowWrite
+		}
+		return nil, nil
+	})
+	switch {
+	case err == nil, errors.Is(err, errSlowWrite):
+		return nil
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		a.dropped.Add(int64(len(rows)))
+		return fmt.Errorf("audit circuit open: %w", err)
+	default:
+		return err
+	}
+}
+
+// enqueue hands r to the background writer without blocking. Rows are dropped
+// (and counted) when the queue is full or the circuit is open.
+func (a *AuditDB) enqueue(r row) {
+	if a.breaker.State() == gobreaker.StateOpen {
+		a.dropped.Add(1)
+		return
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+	select {
+	case a.queue <- r:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+func (a *AuditDB) batchSize() int {
+	if a.opts.BatchSize > 0 {
+		return a.opts.BatchSize
+	}
+	return defaultBatchSize
+}
+
+func (a *AuditDB) flushInterval() time.Duration {
+	if a.opts.FlushInterval > 0 {
+		return a.opts.FlushInterval
+	}
+	return defaultFlushInterval
+}
+
+// runWriter drains the queue until it is closed, writing rows in batches of up
+// to Options.BatchSize, or whatever is queued every Options.FlushInterval.
+func (a *AuditDB) runWriter() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.flushInterval())
+	defer ticker.Stop()
+
+	size := a.batchSize()
+	batch := make([]row, 0, size)
+	flush := func() {
+		for len(batch) > 0 {
+			n := min(len(batch), size)
+			ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+			_ = a.write(ctx, batch[:n]...)
+			cancel()
+			batch = batch[n:]
+		}
+		batch = make([]row, 0, size)
+	}
+	for {
+		select {
+		case r, ok := <-a.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) >= size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case reply := <-a.flushReq:
+			// Rows enqueued before Flush was called are already in the queue.
+			for drained := false; !drained; {
+				select {
+				case r, ok := <-a.queue:
+					if ok {
+						batch = append(batch, r)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
+				}
+			}
+			flush()
+			close(reply)
+		}
+	}
+}
+
+// Flush blocks until every event recorded before the call has been written (or
+// dropped). It is a no-op in synchronous mode and after Close.
+func (a *AuditDB) Flush(ctx context.Context) error {
+	if a == nil || a.queue == nil {
+		return nil
+	}
+	reply := make(chan struct{})
+	select {
+	case a.flushReq <- reply:
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of audit events discarded because the queue was
+// full or the audit circuit was open.
+func (a *AuditDB) Dropped() int64 {
+	if a == nil {
+		return 0
+	}
+	return a.dropped.Load()
+}
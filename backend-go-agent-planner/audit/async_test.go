@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAsyncBatchedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	a, err := NewAuditDB(path, Options{
+		QueueSize:     100,
+		BatchSize:     3,
+		FlushInterval: time.Hour, // only size, Flush and Close trigger writes
+	})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if err := a.RecordStep(ctx, "t1", "s1", "TOOL_CALL", map[string]int{"i": i}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	steps, err := a.QuerySteps(ctx, StepFilter{SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(steps) != 5 {
+		t.Fatalf("got %d steps after Flush, want 5", len(steps))
+	}
+	for i, s := range steps {
+		if want := fmt.Sprintf(`{"i":%d}`, i); string(s.Data) != want {
+			t.Fatalf("step %d data = %s, want %s (recorded order)", i, s.Data, want)
+		}
+	}
+
+	// Close writes whatever is still queued.
+	if err := a.RecordStep(ctx, "t1", "s1", "PLAN_END", nil); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	reopened, err := NewAuditDB(path, Options{})
+	if err != nil {
+		t.Fatalf("reopen audit db: %v", err)
+	}
+	defer reopened.Close()
+	steps, err = reopened.QuerySteps(ctx, StepFilter{EventTypes: []string{"PLAN_END"}})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(steps) != 1 {
+		t.Fatalf("got %d PLAN_END steps after Close, want 1", len(steps))
+	}
+}
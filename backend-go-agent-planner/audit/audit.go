@@ -5,70 +5,127 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"backend-go-model-gateway/redact"
+
+	"github.com/sony/gobreaker"
 )
 
 // AuditDB is a lightweight, embedded audit log store for the Agent Planner.
 //
-// It writes an append-only chronological record of key AgentLoop events to SQLite.
+// It writes an append-only chronological record of key AgentLoop events to SQLite,
+// or to Postgres when several planner replicas share one audit trail.
+// With Options.QueueSize > 0, writes happen on a background goroutine so a slow
+// or failing database never blocks the caller.
 type AuditDB struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
+	opts    Options
+
+	exempt  map[string]bool
+	breaker *gobreaker.CircuitBreaker
+	dropped atomic.Int64
+
+	// mu guards closed against concurrent sends on queue.
+	mu     sync.RWMutex
+	closed bool
+	queue  chan row
+	done   chan struct{}
+	// flushReq asks the writer to write everything queued, then close the reply.
+	flushReq chan chan struct{}
 }
 
-const createTableSQL = `
-CREATE TABLE IF NOT EXISTS audit_log (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	trace_id TEXT,
-	session_id TEXT,
-	timestamp DATETIME NOT NULL,
-	event_type TEXT NOT NULL,
-	data TEXT
-);
-
-CREATE INDEX IF NOT EXISTS idx_audit_log_trace_id ON audit_log(trace_id);
-CREATE INDEX IF NOT EXISTS idx_audit_log_session_id ON audit_log(session_id);
-CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
-`
+// Options tunes optional AuditDB behavior. The zero value keeps every event intact.
+type Options struct {
+	// Driver selects the backend: DriverSQLite (default) or DriverPostgres.
+	Driver string
+
+	// MaxDataBytes caps the serialized data payload stored per event (0 = unlimited).
+	// Oversized payloads are truncated with a marker while keeping their structure.
+	MaxDataBytes int
+	// MaxDataExemptEvents lists event types whose payloads are never truncated.
+	MaxDataExemptEvents []string
+	// Redact masks secrets and email addresses in payloads before they are stored.
+	Redact bool
+
+	// QueueSize enables asynchronous writes through a bounded queue of this size
+	// (0 = write synchronously). Events are dropped when the queue is full.
+	QueueSize int
+	// BatchSize is the most queued events written in one multi-row insert
+	// (default 100); a full batch is written at once.
+	BatchSize int
+	// FlushInterval is how often a partial batch is written (default 200ms).
+	FlushInterval time.Duration
+	// SlowWriteThreshold counts writes slower than this as failures for the circuit (0 = disabled).
+	SlowWriteThreshold time.Duration
+	// CircuitFailures is the number of consecutive failed or slow writes that opens
+	// the circuit (default 5). While open, events are dropped instead of written.
+	CircuitFailures int
+	// CircuitCooldown is how long the circuit stays open before probing again (default 30s).
+	CircuitCooldown time.Duration
+}
 
-// NewAuditDB opens/creates the SQLite database at dbPath and ensures the schema exists.
-func NewAuditDB(dbPath string) (*AuditDB, error) {
-	if dbPath == "" {
-		dbPath = "./pagi_audit.db"
+// NewAuditDB opens the audit database selected by opts.Driver and ensures the
+// schema exists. dsn is the SQLite file path (default ./pagi_audit.db) or the
+// Postgres connection string.
+func NewAuditDB(dsn string, opts Options) (*AuditDB, error) {
+	d, err := dialectFor(opts.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if dsn == "" {
+		if d.sqlDriver != "sqlite3" {
+			return nil, fmt.Errorf("audit db dsn is required for driver %q", opts.Driver)
+		}
+		dsn = "./pagi_audit.db"
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := openDB(d, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite: %w", err)
+		return nil, err
 	}
 
-	// SQLite works best with a single writer connection.
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-
-	if err := db.Ping(); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("ping sqlite: %w", err)
+	exempt := make(map[string]bool, len(opts.MaxDataExemptEvents))
+	for _, ev := range opts.MaxDataExemptEvents {
+		exempt[ev] = true
 	}
 
-	if _, err := db.Exec(createTableSQL); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("create schema: %w", err)
+	a := &AuditDB{db: db, dialect: d, opts: opts, exempt: exempt, breaker: newAuditBreaker(opts)}
+	if opts.QueueSize > 0 {
+		a.queue = make(chan row, opts.QueueSize)
+		a.done = make(chan struct{})
+		a.flushReq = make(chan chan struct{})
+		go a.runWriter()
 	}
-
-	return &AuditDB{db: db}, nil
+	return a, nil
 }
 
+// Close stops accepting events, writes any queued events and closes the database.
 func (a *AuditDB) Close() error {
 	if a == nil || a.db == nil {
 		return nil
 	}
+	if a.queue != nil {
+		a.mu.Lock()
+		if !a.closed {
+			a.closed = true
+			close(a.queue)
+		}
+		a.mu.Unlock()
+		<-a.done
+	}
 	return a.db.Close()
 }
 
 // RecordStep inserts a single audit log row.
 //
+// In async mode the row is queued and RecordStep returns immediately; write
+// errors are then only reflected in the circuit state and Dropped().
+//
 // - traceID: the request correlation ID (X-Trace-ID)
 // - sessionID: agent session identifier
 // - eventType: e.g. PLAN_START, TOOL_CALL, PLAN_END
@@ -87,16 +144,37 @@ func (a *AuditDB) RecordStep(ctx context.Context, traceID, sessionID, eventType
 			payload = string(b)
 		}
 	}
+	if a.opts.Redact {
+		payload = redact.Redact(payload)
+	}
+	if a.opts.MaxDataBytes > 0 && !a.exempt[eventType] {
+		payload = truncatePayload(payload, a.opts.MaxDataBytes)
+	}
+
+	r := row{
+		traceID:   traceID,
+		sessionID: sessionID,
+		timestamp: time.Now().UTC(),
+		eventType: eventType,
+		data:      payload,
+	}
+	if a.queue != nil {
+		a.enqueue(r)
+		return nil
+	}
+	return a.write(ctx, r)
+}
 
+func (a *AuditDB) insert(ctx context.Context, r row) error {
 	_, err := a.db.ExecContext(
 		ctx,
-		`INSERT INTO audit_log (trace_id, session_id, timestamp, event_type, data)
-		 VALUES (?, ?, ?, ?, ?)`,
-		traceID,
-		sessionID,
-		time.Now().UTC(),
-		eventType,
-		payload,
+		a.dialect.rebind(`INSERT INTO audit_log (trace_id, session_id, timestamp, event_type, data)
+		 VALUES (?, ?, ?, ?, ?)`),
+		r.traceID,
+		r.sessionID,
+		r.timestamp,
+		r.eventType,
+		r.data,
 	)
 	if err != nil {
 		return fmt.Errorf("insert audit_log: %w", err)
@@ -104,3 +182,29 @@ func (a *AuditDB) RecordStep(ctx context.Context, traceID, sessionID, eventType
 
 	return nil
 }
+
+// insertBatch writes rows with a single multi-row insert in one transaction.
+func (a *AuditDB) insertBatch(ctx context.Context, rows []row) error {
+	if len(rows) == 1 {
+		return a.insert(ctx, rows[0])
+	}
+	args := make([]any, 0, len(rows)*5)
+	for _, r := range rows {
+		args = append(args, r.traceID, r.sessionID, r.timestamp, r.eventType, r.data)
+	}
+	query := `INSERT INTO audit_log (trace_id, session_id, timestamp, event_type, data) VALUES ` +
+		strings.Repeat("(?, ?, ?, ?, ?), ", len(rows)-1) + "(?, ?, ?, ?, ?)"
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin audit batch: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, a.dialect.rebind(query), args...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("insert audit_log batch: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit audit batch: %w", err)
+	}
+	return nil
+}
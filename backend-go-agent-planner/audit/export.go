@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Supported ExportSession formats.
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+)
+
+// exportCSVHeader is the CSV export's column order; data is the step payload as JSON.
+var exportCSVHeader = []string{"trace_id", "session_id", "event_type", "timestamp", "data"}
+
+// ExportSession writes a session's full audit trail to w in recorded order, as a
+// JSON array of steps or as CSV. Rows are streamed from the database as they
+// are written, so large trails are never held in memory.
+func (a *AuditDB) ExportSession(ctx context.Context, sessionID, format string, w io.Writer) error {
+	if format != ExportJSON && format != ExportCSV {
+		return fmt.Errorf("unsupported export format %q (supported: %s, %s)", format, ExportJSON, ExportCSV)
+	}
+	if a == nil || a.db == nil {
+		return nil
+	}
+	rows, err := a.db.QueryContext(ctx, a.dialect.rebind(
+		"SELECT "+stepColumns+" FROM audit_log WHERE session_id = ? ORDER BY id"),
+		sessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("export session: %w", err)
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	var write func(Step) error
+	var finish func() error
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return err
+		}
+		write = func(s Step) error {
+			return cw.Write([]string{s.TraceID, s.SessionID, s.EventType, s.Timestamp.Format(time.RFC3339Nano), string(s.Data)})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		if _, err := bw.WriteString("["); err != nil {
+			return err
+		}
+		first := true
+		write = func(s Step) error {
+			b, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := bw.WriteString(","); err != nil {
+					return err
+				}
+			}
+			first = false
+			_, err = bw.Write(append([]byte("\n"), b...))
+			return err
+		}
+		finish = func() error {
+			_, err := bw.WriteString("\n]\n")
+			return err
+		}
+	}
+
+	for rows.Next() {
+		s, err := scanStep(rows)
+		if err != nil {
+			return err
+		}
+		if err := write(s); err != nil {
+			return fmt.Errorf("write export: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("export session: %w", err)
+	}
+	if err := finish(); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	return bw.Flush()
+}
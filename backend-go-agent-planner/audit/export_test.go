@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportSession(t *testing.T) {
+	a, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"), Options{})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, r := range []row{
+		{traceID: "t1", sessionID: "s1", timestamp: base, eventType: "PLAN_START", data: `{"prompt":"a, \"b\""}`},
+		{traceID: "t2", sessionID: "s2", timestamp: base, eventType: "PLAN_START", data: `{}`},
+		{traceID: "t1", sessionID: "s1", timestamp: base.Add(time.Second), eventType: "PLAN_END", data: ""},
+	} {
+		if err := a.insert(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := a.ExportSession(ctx, "s1", ExportCSV, &buf); err != nil {
+		t.Fatalf("export csv: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := [][]string{
+		exportCSVHeader,
+		{"t1", "s1", "PLAN_START", "2025-03-01T12:00:00Z", `{"prompt":"a, \"b\""}`},
+		{"t1", "s1", "PLAN_END", "2025-03-01T12:00:01Z", "null"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d csv records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Fatalf("record %d column %d = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := a.ExportSession(ctx, "s1", ExportJSON, &buf); err != nil {
+		t.Fatalf("export json: %v", err)
+	}
+	var steps []Step
+	if err := json.Unmarshal(buf.Bytes(), &steps); err != nil {
+		t.Fatalf("parse json export %q: %v", buf.String(), err)
+	}
+	if len(steps) != 2 || steps[0].EventType != "PLAN_START" || steps[1].EventType != "PLAN_END" {
+		t.Fatalf("json export = %+v, want s1's two steps in order", steps)
+	}
+
+	buf.Reset()
+	if err := a.ExportSession(ctx, "missing", ExportJSON, &buf); err != nil {
+		t.Fatalf("export empty: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &steps); err != nil || len(steps) != 0 {
+		t.Fatalf("empty export = %q, want an empty JSON array", buf.String())
+	}
+
+	if err := a.ExportSession(ctx, "s1", "xml", &buf); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Session summarizes the audit trail of one session.
+type Session struct {
+	SessionID string    `json:"session_id"`
+	FirstAt   time.Time `json:"first_at"`
+	LastAt    time.Time `json:"last_at"`
+	Steps     int       `json:"steps"`
+}
+
+// Step is a recorded audit_log row.
+type Step struct {
+	ID        int64           `json:"id"`
+	TraceID   string          `json:"trace_id"`
+	SessionID string          `json:"session_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	EventType string          `json:"event_type"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ListSessions returns the sessions with recorded steps, most recently active
+// first, with their first and last step timestamps.
+func (a *AuditDB) ListSessions(ctx context.Context, limit, offset int) ([]Session, error) {
+	if a == nil || a.db == nil {
+		return nil, nil
+	}
+	rows, err := a.db.QueryContext(ctx, a.dialect.rebind(
+		`SELECT session_id, MIN(timestamp), MAX(timestamp), COUNT(*)
+		 FROM audit_log
+		 WHERE session_id IS NOT NULL AND session_id != ''
+		 GROUP BY session_id
+		 ORDER BY MAX(timestamp) DESC, session_id
+		 LIMIT ? OFFSET ?`),
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		var first, last dbTime
+		if err := rows.Scan(&s.SessionID, &first, &last, &s.Steps); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		s.FirstAt, s.LastAt = time.Time(first), time.Time(last)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// StepFilter selects audit steps for QuerySteps. Zero-valued fields do not filter.
+type StepFilter struct {
+	SessionID string
+	TraceID   string
+	// EventTypes matches any of the listed event types.
+	EventTypes []string
+	// Start and End bound the step timestamp: Start inclusive, End exclusive.
+	Start time.Time
+	End   time.Time
+	// Limit caps the number of steps returned (0 = no limit); Offset skips steps.
+	Limit  int
+	Offset int
+}
+
+// stepColumns are the audit_log columns read by scanStep.
+const stepColumns = "id, trace_id, session_id, timestamp, event_type, data"
+
+// QuerySteps returns the audit steps matching f in recorded order. Filters on
+// session, event type and time use the audit_log indexes.
+func (a *AuditDB) QuerySteps(ctx context.Context, f StepFilter) ([]Step, error) {
+	if a == nil || a.db == nil {
+		return nil, nil
+	}
+	var where []string
+	var args []any
+	if f.SessionID != "" {
+		where = append(where, "session_id = ?")
+		args = append(args, f.SessionID)
+	}
+	if f.TraceID != "" {
+		where = append(where, "trace_id = ?")
+		args = append(args, f.TraceID)
+	}
+	if len(f.EventTypes) > 0 {
+		where = append(where, "event_type IN (?"+strings.Repeat(", ?", len(f.EventTypes)-1)+")")
+		for _, t := range f.EventTypes {
+			args = append(args, t)
+		}
+	}
+	// Timestamps are stored in UTC by RecordStep, so bounds compare in UTC too.
+	if !f.Start.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, f.Start.UTC())
+	}
+	if !f.End.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, f.End.UTC())
+	}
+
+	query := "SELECT " + stepColumns + " FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id"
+	switch {
+	case f.Limit > 0:
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, f.Limit, f.Offset)
+	case f.Offset > 0 && a.dialect.numbered:
+		query += " OFFSET ?"
+		args = append(args, f.Offset)
+	case f.Offset > 0:
+		// SQLite requires a LIMIT before OFFSET; -1 means no limit.
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, f.Offset)
+	}
+
+	rows, err := a.db.QueryContext(ctx, a.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit steps: %w", err)
+	}
+	return scanSteps(rows)
+}
+
+// scanSteps reads audit_log rows selected as stepColumns.
+func scanSteps(rows *sql.Rows) ([]Step, error) {
+	defer rows.Close()
+	steps := []Step{}
+	for rows.Next() {
+		s, err := scanStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
+
+// scanStep reads the current row of an audit_log query selecting stepColumns.
+func scanStep(rows *sql.Rows) (Step, error) {
+	var s Step
+	var traceID, sessionID, data sql.NullString
+	var ts dbTime
+	if err := rows.Scan(&s.ID, &traceID, &sessionID, &ts, &s.EventType, &data); err != nil {
+		return Step{}, fmt.Errorf("scan step: %w", err)
+	}
+	s.TraceID, s.SessionID, s.Timestamp = traceID.String, sessionID.String, time.Time(ts)
+	switch {
+	case data.String == "":
+		s.Data = json.RawMessage("null")
+	case json.Valid([]byte(data.String)):
+		s.Data = json.RawMessage(data.String)
+	default:
+		// Payloads are JSON unless they were truncated mid-value.
+		b, _ := json.Marshal(data.String)
+		s.Data = b
+	}
+	return s, nil
+}
+
+// parseTimestamp parses a timestamp as stored by the sqlite3 driver.
+func parseTimestamp(v string) (time.Time, error) {
+	v = strings.TrimSuffix(v, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parse audit timestamp %q", v)
+}
@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuerySteps_Filters(t *testing.T) {
+	a, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"), Options{})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := []row{
+		{traceID: "t1", sessionID: "s1", timestamp: base, eventType: "PLAN_START", data: `{"prompt":"a"}`},
+		{traceID: "t1", sessionID: "s1", timestamp: base.Add(10 * time.Minute), eventType: "TOOL_ERROR", data: `{"tool":"x"}`},
+		{traceID: "t2", sessionID: "s2", timestamp: base.Add(50 * time.Minute), eventType: "TOOL_ERROR", data: `{"tool":"y"}`},
+		{traceID: "t2", sessionID: "s2", timestamp: base.Add(70 * time.Minute), eventType: "TOOL_ERROR", data: `{"tool":"z"}`},
+		{traceID: "t2", sessionID: "s2", timestamp: base.Add(80 * time.Minute), eventType: "PLAN_END", data: ""},
+	}
+	for _, r := range rows {
+		if err := a.insert(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		filter StepFilter
+		want   []int64
+	}{
+		{"all", StepFilter{}, []int64{1, 2, 3, 4, 5}},
+		{"session", StepFilter{SessionID: "s1"}, []int64{1, 2}},
+		{"trace", StepFilter{TraceID: "t2"}, []int64{3, 4, 5}},
+		{"event types", StepFilter{EventTypes: []string{"PLAN_START", "PLAN_END"}}, []int64{1, 5}},
+		{"last hour of errors", StepFilter{EventTypes: []string{"TOOL_ERROR"}, Start: base.Add(30 * time.Minute), End: base.Add(90 * time.Minute)}, []int64{3, 4}},
+		{"end is exclusive", StepFilter{End: base.Add(10 * time.Minute)}, []int64{1}},
+		{"non-UTC bound", StepFilter{Start: base.Add(75 * time.Minute).In(time.FixedZone("UTC+2", 2*3600))}, []int64{5}},
+		{"session and type", StepFilter{SessionID: "s2", EventTypes: []string{"TOOL_ERROR"}}, []int64{3, 4}},
+		{"paged", StepFilter{Limit: 2, Offset: 1}, []int64{2, 3}},
+		{"offset only", StepFilter{Offset: 3}, []int64{4, 5}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps, err := a.QuerySteps(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			var got []int64
+			for _, s := range steps {
+				got = append(got, s.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got steps %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got steps %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+
+	steps, err := a.QuerySteps(ctx, StepFilter{TraceID: "t2", EventTypes: []string{"TOOL_ERROR"}, Limit: 1})
+	if err != nil || len(steps) != 1 {
+		t.Fatalf("expected one step, got %v (%v)", steps, err)
+	}
+	if s := steps[0]; s.SessionID != "s2" || !s.Timestamp.Equal(base.Add(50*time.Minute)) || string(s.Data) != `{"tool":"y"}` {
+		t.Fatalf("unexpected step: %+v", s)
+	}
+}
+
+func TestListSessions_MostRecentFirst(t *testing.T) {
+	a, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"), Options{})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, r := range []row{
+		{sessionID: "old", timestamp: base, eventType: "PLAN_START"},
+		{sessionID: "new", timestamp: base.Add(time.Hour), eventType: "PLAN_START"},
+		{sessionID: "old", timestamp: base.Add(30 * time.Minute), eventType: "PLAN_END"},
+	} {
+		if err := a.insert(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	sessions, err := a.ListSessions(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].SessionID != "new" || sessions[1].Steps != 2 ||
+		!sessions[1].FirstAt.Equal(base) || !sessions[1].LastAt.Equal(base.Add(30*time.Minute)) {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+}
+
+func TestPruneOlderThan(t *testing.T) {
+	a, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"), Options{})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for i := 0; i < pruneBatchSize+5; i++ {
+		if err := a.insert(ctx, row{sessionID: "old", timestamp: now.Add(-48 * time.Hour), eventType: "PLAN_START"}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if err := a.insert(ctx, row{sessionID: "new", timestamp: now, eventType: "PLAN_START"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	n, err := a.PruneOlderThan(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if n != pruneBatchSize+5 {
+		t.Fatalf("pruned %d rows, want %d", n, pruneBatchSize+5)
+	}
+	steps, err := a.QuerySteps(ctx, StepFilter{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(steps) != 1 || steps[0].SessionID != "new" {
+		t.Fatalf("remaining steps = %+v, want only the recent one", steps)
+	}
+	if err := a.Vacuum(ctx); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+}
+
+func TestRecordStep_Redact(t *testing.T) {
+	a, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"), Options{Redact: true})
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	if err := a.RecordStep(ctx, "t1", "s1", "PLAN_START", map[string]any{"prompt": "email ops@example.com with api_key=abc123"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	steps, err := a.QuerySteps(ctx, StepFilter{SessionID: "s1"})
+	if err != nil || len(steps) != 1 {
+		t.Fatalf("query: %v (%d steps)", err, len(steps))
+	}
+	if got, want := string(steps[0].Data), `{"prompt":"email [REDACTED_EMAIL] with api_key=[REDACTED]"}`; got != want {
+		t.Fatalf("data = %s, want %s", got, want)
+	}
+}
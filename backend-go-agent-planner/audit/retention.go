@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// pruneBatchSize bounds the rows deleted per statement. Each batch is its own
+// short transaction, so concurrent RecordStep writes are only held up briefly.
+const pruneBatchSize = 1000
+
+// PruneOlderThan deletes steps recorded before cutoff and returns how many rows
+// were removed. It deletes in small batches until no matching rows remain.
+func (a *AuditDB) PruneOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	if a == nil || a.db == nil {
+		return 0, nil
+	}
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		// Timestamps are stored in UTC by RecordStep, so the cutoff compares in UTC too.
+		res, err := a.db.ExecContext(ctx, a.dialect.rebind(
+			`DELETE FROM audit_log WHERE id IN (
+				SELECT id FROM audit_log WHERE timestamp < ? ORDER BY id LIMIT ?
+			)`),
+			cutoff.UTC(), pruneBatchSize,
+		)
+		if err != nil {
+			return total, fmt.Errorf("prune audit_log: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("prune audit_log: %w", err)
+		}
+		total += n
+		if n < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Vacuum reclaims the space freed by PruneOlderThan.
+func (a *AuditDB) Vacuum(ctx context.Context) error {
+	if a == nil || a.db == nil {
+		return nil
+	}
+	if _, err := a.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum audit db: %w", err)
+	}
+	return nil
+}
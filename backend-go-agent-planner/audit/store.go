@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Store is an audit trail backend. AuditDB implements it for SQLite and Postgres.
+type Store interface {
+	// RecordStep records one audit event.
+	RecordStep(ctx context.Context, traceID, sessionID, eventType string, data any) error
+	// QuerySteps returns the recorded steps matching the filter in recorded order.
+	QuerySteps(ctx context.Context, f StepFilter) ([]Step, error)
+	// ListSessions returns the sessions with recorded steps, most recently active first.
+	ListSessions(ctx context.Context, limit, offset int) ([]Session, error)
+	// ExportSession streams a session's trail to w as ExportJSON or ExportCSV.
+	ExportSession(ctx context.Context, sessionID, format string, w io.Writer) error
+	// Close flushes pending events and releases the backend.
+	Close() error
+}
+
+var _ Store = (*AuditDB)(nil)
+
+// Supported audit database drivers (Options.Driver).
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// dialect holds what differs between the SQL backends.
+type dialect struct {
+	// sqlDriver is the database/sql driver name.
+	sqlDriver string
+	schema    string
+	// numbered rewrites ? placeholders to $1, $2, ... (Postgres).
+	numbered bool
+	// singleWriter limits the pool to one connection (SQLite).
+	singleWriter bool
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	trace_id TEXT,
+	session_id TEXT,
+	timestamp DATETIME NOT NULL,
+	event_type TEXT NOT NULL,
+	data TEXT
+);
+` + indexesSchema
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	trace_id TEXT,
+	session_id TEXT,
+	timestamp TIMESTAMPTZ NOT NULL,
+	event_type TEXT NOT NULL,
+	data TEXT
+);
+` + indexesSchema
+
+const indexesSchema = `
+CREATE INDEX IF NOT EXISTS idx_audit_log_trace_id ON audit_log(trace_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_session_id ON audit_log(session_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_log_session_event ON audit_log(session_id, event_type);
+CREATE INDEX IF NOT EXISTS idx_audit_log_event_timestamp ON audit_log(event_type, timestamp);
+`
+
+func dialectFor(driver string) (dialect, error) {
+	switch driver {
+	case "", DriverSQLite:
+		return dialect{sqlDriver: "sqlite3", schema: sqliteSchema, singleWriter: true}, nil
+	case DriverPostgres:
+		return dialect{sqlDriver: "postgres", schema: postgresSchema, numbered: true}, nil
+	default:
+		return dialect{}, fmt.Errorf("unsupported audit db driver %q (supported: %s, %s)", driver, DriverSQLite, DriverPostgres)
+	}
+}
+
+// rebind rewrites a query written with ? placeholders for the dialect.
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// dbTime scans a timestamp column: Postgres returns time.Time, while SQLite
+// aggregates (MIN/MAX) return the stored text.
+type dbTime time.Time
+
+func (t *dbTime) Scan(v any) error {
+	switch v := v.(type) {
+	case time.Time:
+		*t = dbTime(v.UTC())
+		return nil
+	case string:
+		parsed, err := parseTimestamp(v)
+		*t = dbTime(parsed)
+		return err
+	case []byte:
+		parsed, err := parseTimestamp(string(v))
+		*t = dbTime(parsed)
+		return err
+	default:
+		return fmt.Errorf("scan audit timestamp: unsupported type %T", v)
+	}
+}
+
+// openDB opens the database for d and applies the schema migration.
+func openDB(d dialect, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(d.sqlDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", d.sqlDriver, err)
+	}
+
+	if d.singleWriter {
+		// SQLite works best with a single writer connection.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping %s: %w", d.sqlDriver, err)
+	}
+
+	if _, err := db.Exec(d.schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,26 @@
+package audit
+
+import "testing"
+
+func TestDialectRebind(t *testing.T) {
+	pg, err := dialectFor(DriverPostgres)
+	if err != nil {
+		t.Fatalf("postgres dialect: %v", err)
+	}
+	if got, want := pg.rebind("SELECT id FROM audit_log WHERE session_id = ? AND event_type IN (?, ?)"),
+		"SELECT id FROM audit_log WHERE session_id = $1 AND event_type IN ($2, $3)"; got != want {
+		t.Fatalf("rebind = %q, want %q", got, want)
+	}
+
+	sqlite, err := dialectFor("")
+	if err != nil {
+		t.Fatalf("default dialect: %v", err)
+	}
+	if got := sqlite.rebind("LIMIT ? OFFSET ?"); got != "LIMIT ? OFFSET ?" {
+		t.Fatalf("sqlite rebind = %q, want placeholders unchanged", got)
+	}
+
+	if _, err := dialectFor("mysql"); err == nil {
+		t.Fatal("expected an error for an unsupported driver")
+	}
+}
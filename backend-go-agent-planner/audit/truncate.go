@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// minTruncatedStringBytes is the smallest per-string budget tried before giving up
+// on preserving the payload structure.
+const minTruncatedStringBytes = 32
+
+// truncatePayload caps a serialized JSON payload at maxBytes.
+//
+// It keeps the payload's structure (object keys, array shape) and shortens long
+// string values instead, appending a "...[truncated N bytes]" marker to each.
+// Objects additionally get "_truncated" and "_original_bytes" keys. If the payload
+// cannot fit even with short strings, a marker-only object is returned.
+func truncatePayload(payload string, maxBytes int) string {
+	if maxBytes <= 0 || len(payload) <= maxBytes {
+		return payload
+	}
+	original := len(payload)
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(payload)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err == nil {
+		for limit := maxBytes / 2; limit >= minTruncatedStringBytes; limit /= 2 {
+			t := truncateStrings(v, limit)
+			if obj, ok := t.(map[string]any); ok {
+				obj["_truncated"] = true
+				obj["_original_bytes"] = original
+			}
+			b, err := json.Marshal(t)
+			if err == nil && len(b) <= maxBytes {
+				return string(b)
+			}
+		}
+	}
+
+	b, _ := json.Marshal(map[string]any{"_truncated": true, "_original_bytes": original})
+	return string(b)
+}
+
+// truncateStrings returns a copy of v with every string longer than limit bytes shortened.
+func truncateStrings(v any, limit int) any {
+	switch t := v.(type) {
+	case string:
+		return truncateString(t, limit)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = truncateStrings(val, limit)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = truncateStrings(val, limit)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// truncateString cuts s to at most limit bytes on a rune boundary and appends a marker.
+func truncateString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("...[truncated %d bytes]", len(s)-cut)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
+
+	"backend-go-model-gateway/redact"
 )
 
 // contextKey is an unexported type for context keys.
@@ -12,7 +15,32 @@ type contextKey string
 // TraceIDKey is the context key (and canonical header name) for the Trace ID.
 const TraceIDKey contextKey = "X-Trace-ID"
 
-var defaultLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+var defaultLogger = newDefaultLogger()
+
+// newDefaultLogger writes JSON lines to stdout at LOG_LEVEL (debug, info, warn
+// or error; default info). LOG_FORMAT=text switches to key=value lines. Secrets
+// and email addresses in logged fields are masked.
+func newDefaultLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(os.Getenv("LOG_LEVEL")), ReplaceAttr: redact.ReplaceAttr}
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("LOG_FORMAT")), "text") {
+		return slog.New(slog.NewTextHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+}
+
+// ParseLevel maps a LOG_LEVEL value to a slog level. Unknown values mean info.
+func ParseLevel(v string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
 
 // NewContextLogger creates a logger that always includes the trace_id from the context, if present.
 func NewContextLogger(ctx context.Context) *slog.Logger {
@@ -45,3 +73,38 @@ func LogCircuitBreakerStateChange(logger *slog.Logger, breakerName string, fromS
 		"to", toState,
 	)
 }
+
+// RunSummary is the canonical, one-per-run analytics record for an AgentLoop execution.
+type RunSummary struct {
+	SessionID string
+	TurnsUsed int
+	ToolsUsed []string
+	Outcome   string
+	LatencyMs int64
+
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	EstimatedCostUSD float64
+}
+
+// LogRunSummary emits a single structured "agent_run_summary" line for a completed run
+// (regardless of outcome). trace_id is included via the context logger.
+func LogRunSummary(logger *slog.Logger, s RunSummary) {
+	if logger == nil {
+		logger = defaultLogger
+	}
+	logger.Info(
+		"agent_run_summary",
+		"session_id", s.SessionID,
+		"turns_used", s.TurnsUsed,
+		"tools_used", s.ToolsUsed,
+		"tool_count", len(s.ToolsUsed),
+		"outcome", s.Outcome,
+		"latency_ms", s.LatencyMs,
+		"prompt_tokens", s.PromptTokens,
+		"completion_tokens", s.CompletionTokens,
+		"total_tokens", s.TotalTokens,
+		"estimated_cost_usd", s.EstimatedCostUSD,
+	)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// JSON key casing for API responses (AGENT_JSON_CASE).
+const (
+	jsonCaseSnake = "snake"
+	jsonCaseCamel = "camel"
+)
+
+// responseJSONCase is resolved once at startup; snake_case is the wire default.
+var responseJSONCase = parseJSONCase(os.Getenv("AGENT_JSON_CASE"))
+
+func parseJSONCase(v string) string {
+	if strings.EqualFold(strings.TrimSpace(v), jsonCaseCamel) {
+		return jsonCaseCamel
+	}
+	return jsonCaseSnake
+}
+
+// writeJSON writes v as the JSON response body with the given status, applying
+// the configured key casing. Every endpoint (including errors) goes through here.
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	body, err := marshalWithCase(v, responseJSONCase)
+	if err != nil {
+		return err
+	}
+	return writeJSONBody(w, status, body)
+}
+
+// writeJSONBody writes an already encoded JSON response body.
+func writeJSONBody(w http.ResponseWriter, status int, body []byte) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// opaqueJSONKeys name fields whose values are user or tool data rather than API
+// schema: tool-call args (pending_tools) and audit step data. Their keys are
+// passed through unchanged in camelCase responses.
+var opaqueJSONKeys = map[string]bool{
+	"args": true,
+	"data": true,
+}
+
+// marshalWithCase encodes v as a JSON line, rewriting object keys to camelCase
+// when requested. Struct tags stay snake_case; the transform is post-marshal and
+// leaves the values of opaqueJSONKeys as they are.
+func marshalWithCase(v any, jsonCase string) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if jsonCase == jsonCaseCamel {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var generic any
+		if err := dec.Decode(&generic); err != nil {
+			return nil, err
+		}
+		if b, err = json.Marshal(camelizeKeys(generic)); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '\n'), nil
+}
+
+func camelizeKeys(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			if opaqueJSONKeys[k] {
+				out[k] = val
+				continue
+			}
+			out[snakeToCamel(k)] = camelizeKeys(val)
+		}
+		return out
+	case []any:
+		for i, val := range t {
+			t[i] = camelizeKeys(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// snakeToCamel converts "tool_calls_used" to "toolCallsUsed".
+func snakeToCamel(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	parts := strings.Split(s, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, p := range parts[1:] {
+		if p == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestMarshalWithCase_CamelKeepsOpaqueData(t *testing.T) {
+	body := map[string]any{
+		"session_id": "s1",
+		"pending_tools": []agent.ToolCall{
+			{Name: "web_search", Args: map[string]any{"max_results": 5, "filter_by": map[string]any{"site_name": "x"}}},
+		},
+		"steps": []map[string]any{
+			{"event_type": "TOOL_RESULT", "data": json.RawMessage(`{"tool_name":"web_search","exit_code":0}`)},
+		},
+	}
+	got, err := marshalWithCase(body, jsonCaseCamel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"pendingTools":[{"args":{"filter_by":{"site_name":"x"},"max_results":5},"name":"web_search"}],"sessionId":"s1","steps":[{"data":{"exit_code":0,"tool_name":"web_search"},"eventType":"TOOL_RESULT"}]}` + "\n"
+	if string(got) != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
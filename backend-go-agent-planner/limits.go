@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Defaults for the /plan request size limits.
+const (
+	defaultMaxBodyBytes   = 1 << 20
+	defaultMaxPromptChars = 100000
+)
+
+// Request size limits, resolved once at startup. Zero disables a limit.
+var (
+	// maxBodyBytes caps a /plan request body (AGENT_MAX_BODY_BYTES).
+	maxBodyBytes = envLimit("AGENT_MAX_BODY_BYTES", defaultMaxBodyBytes)
+	// maxPromptChars caps the prompt length in characters (AGENT_MAX_PROMPT_CHARS).
+	maxPromptChars = envLimit("AGENT_MAX_PROMPT_CHARS", defaultMaxPromptChars)
+)
+
+// envLimit parses a non-negative integer env var, returning fallback when unset or invalid.
+func envLimit(key string, fallback int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
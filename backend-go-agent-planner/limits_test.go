@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeWithLimits(t *testing.T, body string, bodyLimit, promptLimit int) (int, string) {
+	t.Helper()
+	oldBody, oldPrompt := maxBodyBytes, maxPromptChars
+	maxBodyBytes, maxPromptChars = bodyLimit, promptLimit
+	t.Cleanup(func() { maxBodyBytes, maxPromptChars = oldBody, oldPrompt })
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(body))
+	if _, _, ok := decodePlanRequest(w, r, nil); ok {
+		return http.StatusOK, ""
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode error body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, resp["error"]
+}
+
+func TestDecodePlanRequest_BodyTooLarge(t *testing.T) {
+	body := `{"prompt":"` + strings.Repeat("a", 200) + `","session_id":"s1"}`
+	code, msg := decodeWithLimits(t, body, 100, 0)
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d (%s), want 413", code, msg)
+	}
+	if !strings.Contains(msg, "100 bytes") {
+		t.Fatalf("error = %q, want the byte limit", msg)
+	}
+}
+
+func TestDecodePlanRequest_PromptTooLong(t *testing.T) {
+	body := `{"prompt":"` + strings.Repeat("é", 11) + `","session_id":"s1"}`
+	code, msg := decodeWithLimits(t, body, 1<<20, 10)
+	if code != http.StatusBadRequest {
+		t.Fatalf("status = %d (%s), want 400", code, msg)
+	}
+	if !strings.Contains(msg, "11 characters") || !strings.Contains(msg, "limit is 10") {
+		t.Fatalf("error = %q, want the prompt length and limit", msg)
+	}
+
+	// Exactly at the limit passes the length check (multi-byte characters count once).
+	body = `{"prompt":"` + strings.Repeat("é", 10) + `","session_id":"s1"}`
+	if code, msg := decodeWithLimits(t, body, 1<<20, 10); strings.Contains(msg, "characters") {
+		t.Fatalf("prompt at the limit rejected: %d %s", code, msg)
+	}
+}
+
+func TestDecodePlanRequest_RequiredFieldsStillChecked(t *testing.T) {
+	code, msg := decodeWithLimits(t, `{"prompt":"hi"}`, 1<<20, 10)
+	if code != http.StatusBadRequest || msg != "Prompt and session_id are required" {
+		t.Fatalf("got %d %q, want 400 required-field error", code, msg)
+	}
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"backend-go-agent-planner/agent"
 	"backend-go-agent-planner/internal/logger"
@@ -34,6 +41,16 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// newTextMapPropagator returns the W3C trace-context propagator, plus W3C baggage
+// unless AGENT_PROPAGATE_BAGGAGE=false. Baggage (e.g. tenant/user identifiers) from
+// incoming requests is then forwarded on every outgoing gRPC and HTTP call.
+func newTextMapPropagator() propagation.TextMapPropagator {
+	if v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("AGENT_PROPAGATE_BAGGAGE"))); err == nil && !v {
+		return propagation.TraceContext{}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
 func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) error, promHandler http.Handler, err error) {
 	serviceName := os.Getenv("OTEL_SERVICE_NAME")
 	if strings.TrimSpace(serviceName) == "" {
@@ -71,7 +88,7 @@ func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) erro
 		trace.WithResource(res),
 	)
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetTextMapPropagator(newTextMapPropagator())
 
 	// --- Metrics (Prometheus exporter) ---
 	reg := promclient.NewRegistry()
@@ -98,12 +115,40 @@ func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) erro
 	return shutdown, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}), nil
 }
 
-// apiKeyMiddleware validates the X-API-Key header against the configured API key.
+// loadAPIKeys returns the accepted API keys: AGENT_API_KEYS (comma-separated)
+// plus the older single-key PAGI_API_KEY.
+func loadAPIKeys() []string {
+	var keys []string
+	for _, k := range strings.Split(os.Getenv("AGENT_API_KEYS")+","+os.Getenv("PAGI_API_KEY"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// validAPIKey reports whether provided matches one of keys. Every key is
+// compared in constant time, so the response time does not reveal which key
+// (if any) was a near match.
+func validAPIKey(provided string, keys []string) bool {
+	if provided == "" {
+		return false
+	}
+	match := 0
+	for _, k := range keys {
+		match |= subtle.ConstantTimeCompare([]byte(provided), []byte(k))
+	}
+	return match == 1
+}
+
+// apiKeyMiddleware validates the X-API-Key header (or Authorization: Bearer)
+// against the configured API keys.
 // This is a critical security control for production deployments.
-// If PAGI_API_KEY is not set, authentication is DISABLED (dev mode only).
+// If neither AGENT_API_KEYS nor PAGI_API_KEY is set, authentication is DISABLED
+// (dev mode only).
 func apiKeyMiddleware(next http.Handler) http.Handler {
-	apiKey := os.Getenv("PAGI_API_KEY")
-	authEnabled := strings.TrimSpace(apiKey) != ""
+	keys := loadAPIKeys()
+	authEnabled := len(keys) > 0
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip auth for health checks (required for K8s probes)
@@ -117,32 +162,30 @@ func apiKeyMiddleware(next http.Handler) http.Handler {
 			logger.NewContextLogger(r.Context()).Warn(
 				"auth_disabled",
 				"path", r.URL.Path,
-				"warning", "PAGI_API_KEY not set - authentication disabled (INSECURE)",
+				"warning", "AGENT_API_KEYS not set - authentication disabled (INSECURE)",
 			)
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		// Extract API key from header
-		providedKey := r.Header.Get("X-API-Key")
+		providedKey := strings.TrimSpace(r.Header.Get("X-API-Key"))
 		if providedKey == "" {
 			// Also check Authorization: Bearer <token>
 			authHeader := r.Header.Get("Authorization")
 			if strings.HasPrefix(authHeader, "Bearer ") {
-				providedKey = strings.TrimPrefix(authHeader, "Bearer ")
+				providedKey = strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 			}
 		}
 
-		// Constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) != 1 {
+		if !validAPIKey(providedKey, keys) {
 			logger.NewContextLogger(r.Context()).Warn(
 				"auth_failed",
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
+				"key_provided", providedKey != "",
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			_ = json.NewEncoder(w).Encode(map[string]string{
+			_ = writeJSON(w, http.StatusUnauthorized, map[string]string{
 				"error":   "unauthorized",
 				"message": "Invalid or missing API key",
 			})
@@ -232,8 +275,7 @@ func main() {
 
 	// Health Check Endpoint
 	r.Get("/health", func(w http.ResponseWriter, _r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		_ = writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 
 	// Prometheus metrics endpoint (OpenTelemetry Prometheus exporter).
@@ -245,6 +287,21 @@ func main() {
 	r.Post("/plan", handlePlan(planner))
 	// Backwards/alternate naming: allow either endpoint.
 	r.Post("/run", handlePlan(planner))
+	// Resume a run paused for tool confirmation (AGENT_APPROVAL_REQUIRED_TOOLS).
+	r.Post("/plan/confirm", handleConfirm(planner))
+	// Cancel the in-flight runs of a session.
+	r.Post("/plan/cancel", handleCancel(planner))
+	r.Post("/plan/approve", handleApprove(planner))
+	// Same as /plan, streaming audit steps as Server-Sent Events.
+	r.Post("/plan/stream", handlePlanStream(planner))
+	// Same as /plan/stream over a WebSocket that also accepts a cancel frame.
+	r.Get("/plan/ws", handlePlanWS(planner))
+	r.Get("/sessions", handleListSessions(planner))
+	r.Get("/sessions/{id}/trail", handleSessionTrail(planner))
+	r.Get("/sessions/{id}/export", handleSessionExport(planner))
+	r.Get("/audit/steps", handleAuditSteps(planner))
+	// One-line session summary (AGENT_SUMMARIZE_ON_COMPLETE).
+	r.Get("/sessions/{id}/summary", handleSessionSummary(planner))
 
 	// 3) Start Server
 	server := &http.Server{
@@ -280,53 +337,452 @@ type PlanRequest struct {
 	Prompt    string           `json:"prompt"`
 	SessionID string           `json:"session_id"`
 	Resources []agent.Resource `json:"resources"`
+	// IncludeToolOutcomes asks for per-tool outcomes when the run fails because of tools.
+	IncludeToolOutcomes bool `json:"include_tool_outcomes"`
+	// KnowledgeBases and TopK override the configured RAG retrieval for this run.
+	// The X-Agent-KBs and X-Agent-Top-K headers take precedence over these fields.
+	KnowledgeBases []string `json:"knowledge_bases"`
+	TopK           *int     `json:"top_k"`
+	// Locale requests the response language (e.g. "es", "pt-BR"); see AGENT_RESPONSE_LOCALE_MODE.
+	Locale string `json:"locale"`
+	// Model overrides the gateway's model for this run, subject to AGENT_TENANT_MODELS.
+	Model string `json:"model"`
+	// Seed requests reproducible generations from providers that support it.
+	Seed *int32 `json:"seed,omitempty"`
+	// RawResult returns the model's final plan JSON as result instead of the
+	// extracted answer.
+	RawResult bool `json:"raw_result"`
+}
+
+// Request headers that override RAG retrieval parameters (see PlanRequest).
+const (
+	headerAgentKBs  = "X-Agent-KBs"
+	headerAgentTopK = "X-Agent-Top-K"
+)
+
+// headerTenantID identifies the caller's tenant for AGENT_TENANT_MODELS.
+const headerTenantID = "X-Tenant-ID"
+
+// headerIdempotencyKey makes /plan retries with the same key (per session)
+// replay the first response instead of running the agent again.
+const headerIdempotencyKey = "Idempotency-Key"
+
+// applyRAGOverrideHeaders copies X-Agent-KBs / X-Agent-Top-K into req, overriding body fields.
+func applyRAGOverrideHeaders(r *http.Request, req *PlanRequest) error {
+	if v := strings.TrimSpace(r.Header.Get(headerAgentKBs)); v != "" {
+		var kbs []string
+		for _, kb := range strings.Split(v, ",") {
+			if kb = strings.TrimSpace(kb); kb != "" {
+				kbs = append(kbs, kb)
+			}
+		}
+		req.KnowledgeBases = kbs
+	}
+	if v := strings.TrimSpace(r.Header.Get(headerAgentTopK)); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", headerAgentTopK)
+		}
+		req.TopK = &n
+	}
+	return nil
 }
 
 type PlanResponse struct {
-	Result string `json:"result"`
+	// Result is the final answer; answers larger than AGENT_RESULT_INLINE_MAX_BYTES
+	// are offloaded to AGENT_RESULT_STORE and returned as ResultURL instead.
+	Result    string `json:"result,omitempty"`
+	ResultURL string `json:"result_url,omitempty"`
+	// SystemFingerprint identifies the provider backend, to verify seeded runs.
+	SystemFingerprint string              `json:"system_fingerprint,omitempty"`
+	ToolOutcomes      []agent.ToolOutcome `json:"tool_outcomes,omitempty"`
+	// Sources are the RAG matches the answer cites; InvalidCitations are cited IDs
+	// that were not in the injected context (AGENT_CITATIONS_ENABLED).
+	Sources          []agent.Source `json:"sources,omitempty"`
+	InvalidCitations []string       `json:"invalid_citations,omitempty"`
+	// ToolCallsUsed / ToolCallsRemaining report the session's tool-call budget.
+	// They are omitted when Redis is unavailable (remaining also when no cap is set).
+	ToolCallsUsed      *int `json:"tool_calls_used,omitempty"`
+	ToolCallsRemaining *int `json:"tool_calls_remaining,omitempty"`
+	// Outcome, ConfirmationToken and PendingTools are set when the run paused for
+	// confirmation of a tool in AGENT_APPROVAL_REQUIRED_TOOLS (see /plan/confirm).
+	// Outcome is also "canceled" for runs stopped with /plan/cancel.
+	Outcome           string           `json:"outcome,omitempty"`
+	ConfirmationToken string           `json:"confirmation_token,omitempty"`
+	PendingTools      []agent.ToolCall `json:"pending_tools,omitempty"`
+}
+
+// toolOutcomesFor returns the run's tool outcomes when the client opted in and the
+// run failed (error or no final answer) with at least one failed tool.
+func toolOutcomesFor(req PlanRequest, res *agent.RunResult, runErr error) []agent.ToolOutcome {
+	if !req.IncludeToolOutcomes || res == nil || !res.ToolFailed() {
+		return nil
+	}
+	if runErr == nil && res.Completed {
+		return nil
+	}
+	return res.ToolOutcomes
 }
 
 func writeJSONError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	_ = writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func handleSessionSummary(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+		summary, err := p.SessionSummary(r.Context(), sessionID)
+		switch {
+		case errors.Is(err, agent.ErrSessionSummaryNotFound):
+			writeJSONError(w, http.StatusNotFound, "summary not found")
+		case err != nil:
+			logger.NewContextLogger(r.Context()).Warn("session_summary_lookup_failed", "session_id", sessionID, "error", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "summary store unavailable")
+		default:
+			_ = writeJSON(w, http.StatusOK, map[string]string{"session_id": sessionID, "summary": summary})
+		}
+	}
 }
 
 func handlePlan(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Responses depend on the RAG override headers; let caches partition on them.
+		w.Header().Add("Vary", headerAgentKBs+", "+headerAgentTopK)
+		log := logger.NewContextLogger(r.Context())
+
+		req, runReq, ok := decodePlanRequest(w, r, p)
+		if !ok {
+			return
+		}
+		idemKey := strings.TrimSpace(r.Header.Get(headerIdempotencyKey))
+		if idemKey != "" {
+			stored, err := p.BeginIdempotent(r.Context(), req.SessionID, idemKey)
+			switch {
+			case errors.Is(err, agent.ErrIdempotencyInProgress):
+				writeJSONError(w, http.StatusConflict, err.Error())
+				return
+			case err != nil:
+				// Without Redis the request still runs, just without deduplication.
+				log.Warn("idempotency_unavailable", "session_id", req.SessionID, "error", err)
+				idemKey = ""
+			case stored != nil:
+				log.Info("idempotent_replay", "session_id", req.SessionID, "status", stored.Status)
+				w.Header().Set("Idempotent-Replayed", "true")
+				_ = writeJSONBody(w, stored.Status, append([]byte(stored.Body), '\n'))
+				return
+			}
+		}
+
+		log.Info("agent_loop_start", "session_id", req.SessionID)
+		res, err := p.AgentLoop(r.Context(), runReq)
+		code, body := writeRunResult(w, log.With("session_id", req.SessionID), req, res, err)
+
+		if idemKey != "" {
+			// Only successful responses are replayed; anything else frees the key so
+			// the client's retry runs again.
+			var stored *agent.IdempotentResponse
+			if code >= 200 && code < 300 && body != nil {
+				stored = &agent.IdempotentResponse{Status: code, Body: bytes.TrimSpace(body)}
+			}
+			if err := p.FinishIdempotent(context.WithoutCancel(r.Context()), req.SessionID, idemKey, stored); err != nil {
+				log.Warn("idempotency_store_failed", "session_id", req.SessionID, "error", err)
+			}
+		}
+	}
+}
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// planRequestError is a rejected /plan request: the status code and message to
+// return, and for rate-limited requests when the client may retry.
+type planRequestError struct {
+	status     int
+	msg        string
+	retryAfter time.Duration
+}
+
+// decodePlanRequest decodes and validates a /plan request body. On failure it
+// writes the error response and returns false.
+func decodePlanRequest(w http.ResponseWriter, r *http.Request, p *agent.Planner) (PlanRequest, agent.RunRequest, bool) {
+	var req PlanRequest
+	body := r.Body
+	if maxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, int64(maxBodyBytes))
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return req, agent.RunRequest{}, false
+		}
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return req, agent.RunRequest{}, false
+	}
+	req, runReq, reqErr := checkPlanRequest(r, p, req)
+	if reqErr != nil {
+		if reqErr.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reqErr.retryAfter.Seconds()))))
+		}
+		writeJSONError(w, reqErr.status, reqErr.msg)
+		return req, agent.RunRequest{}, false
+	}
+	return req, runReq, true
+}
+
+// checkPlanRequest rate limits and validates a decoded /plan request, applying
+// the RAG override headers of r.
+func checkPlanRequest(r *http.Request, p *agent.Planner, req PlanRequest) (PlanRequest, agent.RunRequest, *planRequestError) {
+	log := logger.NewContextLogger(r.Context())
+	reject := func(status int, msg string) (PlanRequest, agent.RunRequest, *planRequestError) {
+		return req, agent.RunRequest{}, &planRequestError{status: status, msg: msg}
+	}
+
+	if retryAfter, ok := p.AllowRequest(r.Context(), req.SessionID, clientIP(r)); !ok {
+		log.Warn("rate_limited", "session_id", req.SessionID, "retry_after_ms", retryAfter.Milliseconds())
+		return req, agent.RunRequest{}, &planRequestError{status: http.StatusTooManyRequests, msg: "Rate limit exceeded", retryAfter: retryAfter}
+	}
+	if err := applyRAGOverrideHeaders(r, &req); err != nil {
+		return reject(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Prompt == "" || req.SessionID == "" {
+		return reject(http.StatusBadRequest, "Prompt and session_id are required")
+	}
+	if n := utf8.RuneCountInString(req.Prompt); maxPromptChars > 0 && n > maxPromptChars {
+		return reject(http.StatusBadRequest, fmt.Sprintf("Prompt is %d characters; the limit is %d", n, maxPromptChars))
+	}
+
+	for i, res := range req.Resources {
+		if strings.TrimSpace(res.Type) == "" || strings.TrimSpace(res.URI) == "" {
+			return reject(http.StatusBadRequest, fmt.Sprintf("resources[%d] must include non-empty type and uri", i))
+		}
+	}
+
+	topK := 0
+	if req.TopK != nil {
+		if *req.TopK <= 0 {
+			return reject(http.StatusBadRequest, "top_k must be a positive integer")
+		}
+		topK = *req.TopK
+	}
+	if err := p.ValidateRAGOverrides(req.KnowledgeBases, topK); err != nil {
+		return reject(http.StatusBadRequest, err.Error())
+	}
+	if err := agent.ValidateLocale(req.Locale); err != nil {
+		return reject(http.StatusBadRequest, err.Error())
+	}
+	req.Model = strings.TrimSpace(req.Model)
+	if err := p.AuthorizeModel(strings.TrimSpace(r.Header.Get(headerTenantID)), req.Model); err != nil {
+		log.Warn("model_not_allowed", "session_id", req.SessionID, "model", req.Model, "error", err)
+		return reject(http.StatusForbidden, err.Error())
+	}
+
+	return req, agent.RunRequest{
+		Prompt:         req.Prompt,
+		SessionID:      req.SessionID,
+		Resources:      req.Resources,
+		KnowledgeBases: req.KnowledgeBases,
+		TopK:           topK,
+		Locale:         req.Locale,
+		Model:          req.Model,
+		Seed:           req.Seed,
+		RawResult:      req.RawResult,
+	}, nil
+}
+
+// writeRunResult writes the HTTP response for an AgentLoop or ConfirmRun result
+// and returns the status code and encoded body it wrote.
+func writeRunResult(w http.ResponseWriter, log *slog.Logger, req PlanRequest, res *agent.RunResult, err error) (int, []byte) {
+	if after, ok := agent.RetryAfter(err); ok {
+		// The LLM provider is throttling; pass its Retry-After on to the client.
+		// Round up so a sub-second wait is not sent as "retry now".
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(after.Seconds()))))
+	}
+	code, body := runResultBody(log, req, res, err)
+	b, err := marshalWithCase(body, responseJSONCase)
+	if err != nil {
+		log.Error("encode_response_failed", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to encode response")
+		return http.StatusInternalServerError, nil
+	}
+	if err := writeJSONBody(w, code, b); err != nil {
+		log.Error("write_response_failed", "error", err)
+	}
+	return code, b
+}
+
+// runResultBody maps an AgentLoop or ConfirmRun result to a status code and body.
+func runResultBody(log *slog.Logger, req PlanRequest, res *agent.RunResult, err error) (int, any) {
+	if errors.Is(err, agent.ErrSessionCollision) {
+		return http.StatusConflict, map[string]string{"error": err.Error()}
+	}
+	if errors.Is(err, agent.ErrInjectionDetected) {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	if errors.Is(err, agent.ErrEmptyGeneration) {
+		log.Warn("agent_empty_generation", "error", err)
+		return http.StatusBadGateway, map[string]string{"error": err.Error()}
+	}
+	if errors.Is(err, agent.ErrTurnTimeout) {
+		log.Warn("agent_turn_timeout", "error", err)
+		return http.StatusGatewayTimeout, map[string]string{"error": err.Error()}
+	}
+	if after, ok := agent.RetryAfter(err); ok {
+		log.Warn("agent_loop_throttled", "retry_after_seconds", int(math.Ceil(after.Seconds())), "error", err)
+		return http.StatusTooManyRequests, map[string]string{"error": fmt.Sprintf("Agent execution throttled by the LLM provider: %s", err.Error())}
+	}
+	if err != nil {
+		log.Error("agent_loop_failed", "error", err)
+		msg := fmt.Sprintf("Agent execution failed: %s", err.Error())
+		if outcomes := toolOutcomesFor(req, res, err); len(outcomes) > 0 {
+			return http.StatusInternalServerError, map[string]any{"error": msg, "tool_outcomes": outcomes}
+		}
+		return http.StatusInternalServerError, map[string]string{"error": msg}
+	}
+	log.Info("agent_loop_complete", "outcome", res.Outcome)
+	if res.Outcome == agent.OutcomeContentBlocked {
+		return http.StatusUnprocessableEntity, map[string]string{"error": "content_blocked", "message": res.Result}
+	}
+
+	resp := PlanResponse{
+		Result:             res.Result,
+		ResultURL:          res.ResultURL,
+		SystemFingerprint:  res.SystemFingerprint,
+		ToolOutcomes:       toolOutcomesFor(req, res, nil),
+		Sources:            res.Sources,
+		InvalidCitations:   res.HallucinatedCitations,
+		ToolCallsUsed:      res.ToolCallsUsed,
+		ToolCallsRemaining: res.ToolCallsRemaining,
+	}
+	code := http.StatusOK
+	if res.Outcome == agent.OutcomePendingConfirmation {
+		code = http.StatusAccepted
+		resp.Outcome = res.Outcome
+		resp.ConfirmationToken = res.ConfirmationToken
+		resp.PendingTools = res.PendingTools
+	}
+	if res.Outcome == agent.OutcomeCanceled {
+		resp.Outcome = res.Outcome
+	}
+	return code, resp
+}
+
+// ConfirmRequest resumes a run paused with outcome pending_confirmation.
+type ConfirmRequest struct {
+	ConfirmationToken string `json:"confirmation_token"`
+	// Decision is "approve" or "deny".
+	Decision string `json:"decision"`
+	// IncludeToolOutcomes has the same meaning as in PlanRequest.
+	IncludeToolOutcomes bool `json:"include_tool_outcomes"`
+}
+
+func handleConfirm(p *agent.Planner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		log := logger.NewContextLogger(r.Context())
 
-		var req PlanRequest
+		var req ConfirmRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		if strings.TrimSpace(req.ConfirmationToken) == "" {
+			writeJSONError(w, http.StatusBadRequest, "confirmation_token is required")
+			return
+		}
+		var approved bool
+		switch strings.ToLower(strings.TrimSpace(req.Decision)) {
+		case "approve":
+			approved = true
+		case "deny":
+		default:
+			writeJSONError(w, http.StatusBadRequest, `decision must be "approve" or "deny"`)
+			return
+		}
 
-		if req.Prompt == "" || req.SessionID == "" {
-			writeJSONError(w, http.StatusBadRequest, "Prompt and session_id are required")
+		log.Info("agent_loop_resume", "decision", req.Decision)
+		res, err := p.ConfirmRun(r.Context(), strings.TrimSpace(req.ConfirmationToken), approved)
+		if errors.Is(err, agent.ErrConfirmationNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		writeRunResult(w, log, PlanRequest{IncludeToolOutcomes: req.IncludeToolOutcomes}, res, err)
+	}
+}
 
-		for i, res := range req.Resources {
-			if strings.TrimSpace(res.Type) == "" || strings.TrimSpace(res.URI) == "" {
-				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("resources[%d] must include non-empty type and uri", i))
-				return
-			}
+// CancelRequest stops the in-flight runs of a session.
+type CancelRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+func handleCancel(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CancelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
 		}
+		sessionID := strings.TrimSpace(req.SessionID)
+		if sessionID == "" {
+			writeJSONError(w, http.StatusBadRequest, "session_id is required")
+			return
+		}
+		n := p.CancelRun(sessionID)
+		logger.NewContextLogger(r.Context()).Info("plan_cancel_requested", "session_id", sessionID, "runs", n)
+		if n == 0 {
+			writeJSONError(w, http.StatusNotFound, "no running plan for session")
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, map[string]any{"session_id": sessionID, "canceled": n})
+	}
+}
 
-		log.Info("agent_loop_start", "session_id", req.SessionID)
-		result, err := p.AgentLoop(r.Context(), req.Prompt, req.SessionID, req.Resources)
-		if err != nil {
-			log.Error("agent_loop_failed", "session_id", req.SessionID, "error", err)
-			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Agent execution failed: %s", err.Error()))
+// ApproveRequest decides an approval request published by a run waiting with
+// AGENT_APPROVAL_MODE=wait.
+type ApproveRequest struct {
+	ApprovalID string `json:"approval_id"`
+	// Decision is "approve" or "deny".
+	Decision string `json:"decision"`
+}
+
+func handleApprove(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ApproveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		id := strings.TrimSpace(req.ApprovalID)
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "approval_id is required")
+			return
+		}
+		var approved bool
+		switch strings.ToLower(strings.TrimSpace(req.Decision)) {
+		case "approve":
+			approved = true
+		case "deny":
+		default:
+			writeJSONError(w, http.StatusBadRequest, `decision must be "approve" or "deny"`)
 			return
 		}
-		log.Info("agent_loop_complete", "session_id", req.SessionID)
 
-		resp := PlanResponse{Result: result}
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Error("encode_response_failed", "error", err)
+		logger.NewContextLogger(r.Context()).Info("tool_approval_decision", "approval_id", id, "decision", req.Decision)
+		err := p.ResolveApproval(r.Context(), id, approved)
+		switch {
+		case errors.Is(err, agent.ErrApprovalNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			_ = writeJSON(w, http.StatusOK, map[string]any{"approval_id": id, "approved": approved})
 		}
 	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/internal/logger"
+)
+
+// handlePlanStream runs the agent loop like /plan but streams its progress as
+// Server-Sent Events: one event per audit step (named after the step, e.g.
+// PLAN_START or TOOL_RESULT), then a final "result" event carrying the /plan
+// response body, or an "error" event. A client disconnect cancels the run.
+func handlePlanStream(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.NewContextLogger(r.Context())
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeJSONError(w, http.StatusInternalServerError, "streaming is not supported")
+			return
+		}
+		req, runReq, ok := decodePlanRequest(w, r, p)
+		if !ok {
+			return
+		}
+		log = log.With("session_id", req.SessionID)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		events := make(chan agent.StepEvent, 64)
+		type runOutcome struct {
+			res *agent.RunResult
+			err error
+		}
+		done := make(chan runOutcome, 1)
+		log.Info("agent_loop_start", "stream", true)
+		go func() {
+			res, err := p.AgentLoop(agent.WithStepEvents(ctx, events), runReq)
+			done <- runOutcome{res, err}
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		send := func(event string, v any) bool {
+			// marshalWithCase ends data with the newline of the "data:" line.
+			data, err := marshalWithCase(v, responseJSONCase)
+			if err == nil {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n", event, data)
+			}
+			if err != nil {
+				log.Warn("plan_stream_write_failed", "event", event, "error", err)
+				cancel()
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		for {
+			select {
+			case ev := <-events:
+				if !send(ev.Type, ev) {
+					return
+				}
+			case out := <-done:
+				// Steps recorded just before the run returned are still buffered.
+				for len(events) > 0 {
+					ev := <-events
+					if !send(ev.Type, ev) {
+						return
+					}
+				}
+				code, body := runResultBody(log, req, out.res, out.err)
+				event := "result"
+				if code >= http.StatusBadRequest {
+					event = "error"
+				}
+				send(event, body)
+				return
+			case <-ctx.Done():
+				log.Info("plan_stream_client_gone", "error", ctx.Err())
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/internal/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+// planWSWriteTimeout bounds each frame write, so a stalled client cannot hold
+// the run open.
+const planWSWriteTimeout = 10 * time.Second
+
+var planWSUpgrader = websocket.Upgrader{}
+
+// planWSControl is a control frame sent by the client during a run.
+type planWSControl struct {
+	// Action is "cancel", which stops the run like POST /plan/cancel.
+	Action string `json:"action"`
+}
+
+// planWSFrame is a frame sent to the client: event is an audit step type (e.g.
+// PLAN_START or TOOL_RESULT), "result" with the /plan response body, or "error".
+type planWSFrame struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// handlePlanWS runs the agent loop over a WebSocket. The first client frame is a
+// /plan request body; the server then sends the same events as /plan/stream, one
+// JSON frame each, ending with a "result" or "error" frame. While the run is in
+// progress the client may send {"action":"cancel"}. Closing the connection
+// cancels the run.
+func handlePlanWS(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.NewContextLogger(r.Context())
+		conn, err := planWSUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already written the HTTP error response.
+			log.Warn("plan_ws_upgrade_failed", "error", err)
+			return
+		}
+		defer conn.Close()
+		if maxBodyBytes > 0 {
+			conn.SetReadLimit(int64(maxBodyBytes))
+		}
+
+		send := func(event string, v any) bool {
+			// Case the payload on its own: the frame's "data" key is opaque to marshalWithCase.
+			payload, err := marshalWithCase(v, responseJSONCase)
+			var data []byte
+			if err == nil {
+				data, err = json.Marshal(planWSFrame{Event: event, Data: json.RawMessage(payload)})
+			}
+			if err == nil {
+				_ = conn.SetWriteDeadline(time.Now().Add(planWSWriteTimeout))
+				err = conn.WriteMessage(websocket.TextMessage, data)
+			}
+			if err != nil {
+				log.Warn("plan_ws_write_failed", "event", event, "error", err)
+				return false
+			}
+			return true
+		}
+		closeWith := func(code int, reason string) {
+			msg := websocket.FormatCloseMessage(code, reason)
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(planWSWriteTimeout))
+		}
+
+		var req PlanRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			send("error", map[string]any{"status": http.StatusBadRequest, "error": "Invalid request frame"})
+			closeWith(websocket.ClosePolicyViolation, "invalid request frame")
+			return
+		}
+		req, runReq, reqErr := checkPlanRequest(r, p, req)
+		if reqErr != nil {
+			body := map[string]any{"status": reqErr.status, "error": reqErr.msg}
+			if reqErr.retryAfter > 0 {
+				body["retry_after_ms"] = reqErr.retryAfter.Milliseconds()
+			}
+			send("error", body)
+			closeWith(websocket.ClosePolicyViolation, reqErr.msg)
+			return
+		}
+		log = log.With("session_id", req.SessionID)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// The reader handles control frames; a read error means the client is gone.
+		go func() {
+			defer cancel()
+			for {
+				_, b, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				var ctl planWSControl
+				if err := json.Unmarshal(b, &ctl); err != nil || ctl.Action != "cancel" {
+					log.Warn("plan_ws_unknown_frame", "frame", string(b))
+					continue
+				}
+				n := p.CancelRun(req.SessionID)
+				log.Info("plan_cancel_requested", "runs", n, "ws", true)
+			}
+		}()
+
+		events := make(chan agent.StepEvent, 64)
+		type runOutcome struct {
+			res *agent.RunResult
+			err error
+		}
+		done := make(chan runOutcome, 1)
+		log.Info("agent_loop_start", "ws", true)
+		go func() {
+			res, err := p.AgentLoop(agent.WithStepEvents(ctx, events), runReq)
+			done <- runOutcome{res, err}
+		}()
+
+		for {
+			select {
+			case ev := <-events:
+				if !send(ev.Type, ev) {
+					return
+				}
+			case out := <-done:
+				// Steps recorded just before the run returned are still buffered.
+				for len(events) > 0 {
+					ev := <-events
+					if !send(ev.Type, ev) {
+						return
+					}
+				}
+				code, body := runResultBody(log, req, out.res, out.err)
+				event := "result"
+				if code >= http.StatusBadRequest {
+					event = "error"
+				}
+				if send(event, body) {
+					closeWith(websocket.CloseNormalClosure, "")
+				}
+				return
+			case <-ctx.Done():
+				log.Info("plan_ws_client_gone", "error", ctx.Err())
+				return
+			}
+		}
+	}
+}
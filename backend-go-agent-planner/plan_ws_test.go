@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHandlePlanWS_InvalidRequest(t *testing.T) {
+	srv := httptest.NewServer(handlePlanWS(nil))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]string{"prompt": "hi"}); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	var frame struct {
+		Event string         `json:"event"`
+		Data  map[string]any `json:"data"`
+	}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if frame.Event != "error" || frame.Data["error"] != "Prompt and session_id are required" || frame.Data["status"] != float64(400) {
+		t.Fatalf("frame = %+v, want a 400 required-field error", frame)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("read after error = %v, want policy violation close", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/audit"
+	"backend-go-agent-planner/internal/logger"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// pagination reads the limit and offset query parameters.
+func pagination(r *http.Request) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+func handleListSessions(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := pagination(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sessions, err := p.ListSessions(r.Context(), limit, offset)
+		if err != nil {
+			logger.NewContextLogger(r.Context()).Warn("list_sessions_failed", "error", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "audit store unavailable")
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions, "limit": limit, "offset": offset})
+	}
+}
+
+// stepFilter builds an audit step filter from the trace_id, event_type
+// (comma-separated), since and until (RFC 3339) query parameters and pagination.
+func stepFilter(r *http.Request) (audit.StepFilter, error) {
+	q := r.URL.Query()
+	limit, offset, err := pagination(r)
+	if err != nil {
+		return audit.StepFilter{}, err
+	}
+	f := audit.StepFilter{TraceID: strings.TrimSpace(q.Get("trace_id")), Limit: limit, Offset: offset}
+	for _, t := range strings.Split(q.Get("event_type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			f.EventTypes = append(f.EventTypes, t)
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Start}, {"until", &f.End}} {
+		if v := q.Get(bound.name); v != "" {
+			if *bound.dst, err = time.Parse(time.RFC3339, v); err != nil {
+				return audit.StepFilter{}, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.name)
+			}
+		}
+	}
+	return f, nil
+}
+
+func handleSessionTrail(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+		filter, err := stepFilter(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.SessionID = sessionID
+		steps, err := p.QueryAuditSteps(r.Context(), filter)
+		if err != nil {
+			logger.NewContextLogger(r.Context()).Warn("session_trail_failed", "session_id", sessionID, "error", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "audit store unavailable")
+			return
+		}
+		if len(steps) == 0 && filter.Offset == 0 && len(filter.EventTypes) == 0 && filter.TraceID == "" && filter.Start.IsZero() && filter.End.IsZero() {
+			writeJSONError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, map[string]any{"session_id": sessionID, "steps": steps, "limit": filter.Limit, "offset": filter.Offset})
+	}
+}
+
+func handleAuditSteps(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := stepFilter(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.SessionID = strings.TrimSpace(r.URL.Query().Get("session_id"))
+		steps, err := p.QueryAuditSteps(r.Context(), filter)
+		if err != nil {
+			logger.NewContextLogger(r.Context()).Warn("audit_query_failed", "error", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "audit store unavailable")
+			return
+		}
+		_ = writeJSON(w, http.StatusOK, map[string]any{"steps": steps, "limit": filter.Limit, "offset": filter.Offset})
+	}
+}
+
+func handleSessionExport(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+		format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+		contentType := "application/json"
+		switch format {
+		case "", audit.ExportJSON:
+			format = audit.ExportJSON
+		case audit.ExportCSV:
+			contentType = "text/csv; charset=utf-8"
+		default:
+			writeJSONError(w, http.StatusBadRequest, "format must be json or csv")
+			return
+		}
+		lg := logger.NewContextLogger(r.Context())
+
+		// The export is streamed, so check the session exists before sending headers.
+		steps, err := p.QueryAuditSteps(r.Context(), audit.StepFilter{SessionID: sessionID, Limit: 1})
+		if err != nil {
+			lg.Warn("session_export_failed", "session_id", sessionID, "error", err)
+			writeJSONError(w, http.StatusServiceUnavailable, "audit store unavailable")
+			return
+		}
+		if len(steps) == 0 {
+			writeJSONError(w, http.StatusNotFound, "session not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "session-" + sessionID + "." + format}))
+		w.WriteHeader(http.StatusOK)
+		if err := p.ExportSession(r.Context(), sessionID, format, w); err != nil {
+			// Headers are already sent; the client sees a truncated file.
+			lg.Warn("session_export_failed", "session_id", sessionID, "format", format, "error", err)
+		}
+	}
+}
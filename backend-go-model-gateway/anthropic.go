@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicModel     = "claude-3-haiku-20240307"
+	anthropicAPIVersion       = "2023-06-01"
+	defaultAnthropicMaxTokens = 1024
+)
+
+// newAnthropicClient returns a go-openai client whose chat completion calls are
+// served by the Anthropic Messages API, so GetPlan/GetPlanStream work unchanged.
+func newAnthropicClient(apiKey, baseURL string) *openai.Client {
+	cfg := openai.DefaultConfig(apiKey)
+	// Only used to build request URLs; anthropicTransport rewrites them.
+	cfg.BaseURL = baseURL
+	cfg.HTTPClient = &http.Client{
+		Transport: &anthropicTransport{
+			apiKey:  apiKey,
+			baseURL: strings.TrimRight(baseURL, "/"),
+			next:    sharedHTTPClient.Transport,
+		},
+	}
+	return openai.NewClientWithConfig(cfg)
+}
+
+// anthropicTransport adapts OpenAI chat completion requests to the Anthropic
+// Messages API and translates responses (and errors) back into the OpenAI shape.
+//
+// Streaming requests are served from a single non-streaming call and replayed as
+// one OpenAI SSE delta followed by [DONE].
+type anthropicTransport struct {
+	apiKey  string
+	baseURL string
+	next    http.RoundTripper
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	StopSeqs    []string           `json:"stop_sequences,omitempty"`
+}
+
+type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (t *anthropicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return openAIErrorResponse(req, http.StatusNotFound, "not_found_error", "the anthropic provider only supports chat completions"), nil
+	}
+
+	var in openai.ChatCompletionRequest
+	if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+		return nil, fmt.Errorf("decode chat completion request: %w", err)
+	}
+	_ = req.Body.Close()
+	if in.N > 1 {
+		return openAIErrorResponse(req, http.StatusBadRequest, "invalid_request_error", "the anthropic provider does not support n > 1"), nil
+	}
+
+	body, err := json.Marshal(toAnthropicRequest(in))
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodPost, t.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	upstream.Header.Set("Content-Type", "application/json")
+	upstream.Header.Set("x-api-key", t.apiKey)
+	upstream.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := t.next.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read anthropic response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr anthropicErrorResponse
+		msg := strings.TrimSpace(string(raw))
+		errType := "api_error"
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg, errType = apiErr.Error.Message, apiErr.Error.Type
+		}
+		code := resp.StatusCode
+		// 529 "overloaded" is Anthropic-specific; surface it as 503 so it is retried.
+		if code == 529 {
+			code = http.StatusServiceUnavailable
+		}
+		return openAIErrorResponse(req, code, errType, msg), nil
+	}
+
+	var out anthropicResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("decode anthropic response: %w", err)
+	}
+	completion := fromAnthropicResponse(out)
+
+	if in.Stream {
+		includeUsage := in.StreamOptions != nil && in.StreamOptions.IncludeUsage
+		return streamResponse(req, completion, includeUsage), nil
+	}
+	b, _ := json.Marshal(completion)
+	return newHTTPResponse(req, http.StatusOK, "application/json", b), nil
+}
+
+// toAnthropicRequest moves system messages into the top-level system prompt and
+// merges consecutive same-role messages, since Anthropic requires alternating
+// user/assistant turns starting with the user.
+func toAnthropicRequest(in openai.ChatCompletionRequest) anthropicRequest {
+	out := anthropicRequest{Model: in.Model, MaxTokens: in.MaxTokens, StopSeqs: in.Stop}
+	if out.MaxTokens <= 0 {
+		out.MaxTokens = defaultAnthropicMaxTokens
+	}
+	if in.Temperature != 0 {
+		temp := in.Temperature
+		out.Temperature = &temp
+	}
+
+	var system []string
+	for _, m := range in.Messages {
+		role := m.Role
+		switch role {
+		case openai.ChatMessageRoleSystem:
+			system = append(system, m.Content)
+			continue
+		case openai.ChatMessageRoleAssistant:
+		default:
+			role = openai.ChatMessageRoleUser
+		}
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		if len(out.Messages) == 0 && role != openai.ChatMessageRoleUser {
+			out.Messages = append(out.Messages, anthropicMessage{Role: openai.ChatMessageRoleUser, Content: "(continue)"})
+		}
+		out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: m.Content})
+	}
+	out.System = strings.Join(system, "\n\n")
+	return out
+}
+
+func fromAnthropicResponse(in anthropicResponse) openai.ChatCompletionResponse {
+	var text strings.Builder
+	for _, c := range in.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	finish := openai.FinishReasonStop
+	if in.StopReason == "max_tokens" {
+		finish = openai.FinishReasonLength
+	}
+	return openai.ChatCompletionResponse{
+		ID:      in.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   in.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text.String()},
+			FinishReason: finish,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     in.Usage.InputTokens,
+			CompletionTokens: in.Usage.OutputTokens,
+			TotalTokens:      in.Usage.InputTokens + in.Usage.OutputTokens,
+		},
+	}
+}
+
+// streamResponse replays a completed response as OpenAI server-sent events.
+func streamResponse(req *http.Request, c openai.ChatCompletionResponse, includeUsage bool) *http.Response {
+	var buf bytes.Buffer
+	writeEvent := func(v any) {
+		b, _ := json.Marshal(v)
+		buf.WriteString("data: ")
+		buf.Write(b)
+		buf.WriteString("\n\n")
+	}
+
+	content := ""
+	finish := openai.FinishReasonStop
+	if len(c.Choices) > 0 {
+		content = c.Choices[0].Message.Content
+		finish = c.Choices[0].FinishReason
+	}
+	writeEvent(openai.ChatCompletionStreamResponse{
+		ID:      c.ID,
+		Object:  "chat.completion.chunk",
+		Created: c.Created,
+		Model:   c.Model,
+		Choices: []openai.ChatCompletionStreamChoice{{
+			Index:        0,
+			Delta:        openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant, Content: content},
+			FinishReason: finish,
+		}},
+	})
+	if includeUsage {
+		usage := c.Usage
+		writeEvent(openai.ChatCompletionStreamResponse{
+			ID:      c.ID,
+			Object:  "chat.completion.chunk",
+			Created: c.Created,
+			Model:   c.Model,
+			Choices: []openai.ChatCompletionStreamChoice{},
+			Usage:   &usage,
+		})
+	}
+	buf.WriteString("data: [DONE]\n\n")
+	return newHTTPResponse(req, http.StatusOK, "text/event-stream", buf.Bytes())
+}
+
+// openAIErrorResponse builds an OpenAI-style error body so go-openai surfaces an
+// *openai.APIError with the given HTTP status.
+func openAIErrorResponse(req *http.Request, code int, errType, message string) *http.Response {
+	b, _ := json.Marshal(openai.ErrorResponse{Error: &openai.APIError{Type: errType, Message: message}})
+	return newHTTPResponse(req, code, "application/json", b)
+}
+
+func newHTTPResponse(req *http.Request, code int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
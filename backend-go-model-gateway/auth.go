@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"backend-go-model-gateway/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// healthMethodPrefix is exempt from API-key auth so orchestrator probes keep working.
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// loadAPIKeys parses GATEWAY_API_KEY, a comma-separated set of accepted keys.
+// An empty result disables authentication.
+func loadAPIKeys() []string {
+	var keys []string
+	for _, k := range strings.Split(getEnv("GATEWAY_API_KEY", ""), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// apiKeyAuth checks the "x-api-key" or "authorization" (optionally "Bearer "-prefixed)
+// metadata of incoming calls against a set of accepted keys.
+type apiKeyAuth struct {
+	keys []string
+}
+
+func (a apiKeyAuth) authorize(ctx context.Context, method string) error {
+	if len(a.keys) == 0 || strings.HasPrefix(method, healthMethodPrefix) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	provided := append(md.Get("x-api-key"), md.Get("authorization")...)
+	for _, p := range provided {
+		p = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(p), "Bearer "))
+		for _, k := range a.keys {
+			if subtle.ConstantTimeCompare([]byte(p), []byte(k)) == 1 {
+				return nil
+			}
+		}
+	}
+	logger.NewContextLogger(ctx).Warn("auth_failed", "method", method, "key_provided", len(provided) > 0)
+	return status.Error(codes.Unauthenticated, "missing or invalid API key")
+}
+
+func (a apiKeyAuth) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a apiKeyAuth) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultCacheTTLSec = 300
+
+// planCache is a concurrency-safe LRU cache of GetPlan responses with a TTL.
+// A nil *planCache is a disabled cache.
+type planCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently used
+	entries map[string]*list.Element
+}
+
+type planCacheEntry struct {
+	key     string
+	resp    *pb.PlanResponse
+	expires time.Time
+}
+
+// loadPlanCache reads LLM_CACHE_SIZE (entries; unset disables the cache) and
+// LLM_CACHE_TTL_SECONDS (default 300).
+func loadPlanCache() *planCache {
+	size := getEnvInt("LLM_CACHE_SIZE", 0)
+	if size <= 0 {
+		return nil
+	}
+	ttl := time.Duration(getEnvInt("LLM_CACHE_TTL_SECONDS", defaultCacheTTLSec)) * time.Second
+	return newPlanCache(size, ttl)
+}
+
+func newPlanCache(size int, ttl time.Duration) *planCache {
+	return &planCache{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// planCacheKey hashes everything that determines the LLM output for a request:
+// prompt, resources, model and sampling parameters.
+func planCacheKey(in *pb.PlanRequest, model string, params planParams, n int) string {
+	b, _ := json.Marshal(struct {
+		Prompt      string         `json:"prompt"`
+		Resources   []*pb.Resource `json:"resources"`
+		Model       string         `json:"model"`
+		Temperature float32        `json:"temperature"`
+		MaxTokens   int            `json:"max_tokens"`
+		N           int            `json:"n"`
+		Seed        *int           `json:"seed,omitempty"`
+	}{in.GetPrompt(), in.GetResources(), model, params.temperature, params.maxTokens, n, params.seed})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a copy of the cached response for key, if present and not expired.
+func (c *planCache) get(key string) (*pb.PlanResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*planCacheEntry)
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return proto.Clone(e.resp).(*pb.PlanResponse), true
+}
+
+// put stores a copy of resp, evicting the least recently used entry when full.
+func (c *planCache) put(key string, resp *pb.PlanResponse) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &planCacheEntry{key: key, resp: proto.Clone(resp).(*pb.PlanResponse), expires: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*planCacheEntry).key)
+	}
+}
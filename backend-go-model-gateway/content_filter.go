@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// contentBlockedReason is the ErrorInfo reason clients match to tell a provider's
+// content-policy refusal apart from a service failure.
+const contentBlockedReason = "CONTENT_BLOCKED"
+
+// contentBlockedError returns a PermissionDenied status carrying an ErrorInfo with
+// reason CONTENT_BLOCKED.
+func contentBlockedError(rt *llmRuntime, model string) error {
+	st := status.New(codes.PermissionDenied, "the request was blocked by the model provider's content policy")
+	info := &errdetails.ErrorInfo{Reason: contentBlockedReason, Domain: SERVICE_NAME, Metadata: map[string]string{}}
+	if rt != nil {
+		info.Metadata["provider"] = string(rt.Provider)
+		info.Metadata["model"] = model
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// isProviderContentFilter reports whether an upstream error is a content-policy
+// rejection (OpenAI-style error codes "content_filter"/"content_policy_violation").
+func isProviderContentFilter(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	code, _ := apiErr.Code.(string)
+	switch code {
+	case "content_filter", "content_policy_violation":
+		return true
+	}
+	return apiErr.Type == "content_filter"
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+// embeddingsResult is one upstream embeddings call's vectors (in input order) and usage.
+type embeddingsResult struct {
+	vectors [][]float32
+	usage   openai.Usage
+}
+
+// GetEmbeddings implements modelgateway.ModelGatewayServer. All inputs are embedded
+// in a single upstream call with the primary provider; fallbacks are not used since
+// vectors from different models are not comparable.
+func (s *server) GetEmbeddings(ctx context.Context, in *pb.EmbeddingsRequest) (out *pb.EmbeddingsResponse, err error) {
+	requestStart := time.Now()
+	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+
+	rt := s.llm
+	var usage openai.Usage
+	defer func() { s.metrics.record(ctx, "GetEmbeddings", s.llm, rt, requestStart, usage, err) }()
+
+	if rt == nil || rt.Client == nil {
+		return nil, status.Error(codes.Unavailable, "LLM client is not initialized")
+	}
+	inputs := in.GetInputs()
+	if len(inputs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "inputs must not be empty")
+	}
+	for i, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "inputs[%d] is empty", i)
+		}
+	}
+	model := rt.EmbeddingModel
+	if m := strings.TrimSpace(in.GetModel()); m != "" {
+		model = m
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+	release, err := s.limiter.acquire(callCtx, "GetEmbeddings")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var res embeddingsResult
+	switch rt.Provider {
+	case providerAnthropic:
+		return nil, status.Error(codes.Unimplemented, "embeddings are not supported by the anthropic provider")
+	case providerOllama:
+		res, err = withRetry(callCtx, s.retry, "Embed", func(ctx context.Context) (embeddingsResult, error) {
+			return ollamaEmbeddings(ctx, rt.ollamaBaseURL, model, inputs)
+		})
+	default:
+		res, err = withRetry(callCtx, s.retry, "CreateEmbeddings", func(ctx context.Context) (embeddingsResult, error) {
+			return openAIEmbeddings(ctx, rt.Client, model, inputs)
+		})
+	}
+	vectors := res.vectors
+	usage = res.usage
+	if err != nil {
+		logger.NewContextLogger(ctx).Error("embeddings_failed", "provider", rt.Provider, "model", model, "inputs", len(inputs), "error", err)
+		return nil, err
+	}
+	if len(vectors) != len(inputs) {
+		return nil, status.Errorf(codes.Internal, "provider returned %d embeddings for %d inputs", len(vectors), len(inputs))
+	}
+
+	out = &pb.EmbeddingsResponse{
+		Embeddings:   make([]*pb.Embedding, len(vectors)),
+		ModelName:    model,
+		PromptTokens: int32(usage.PromptTokens),
+		TotalTokens:  int32(usage.TotalTokens),
+	}
+	for i, v := range vectors {
+		out.Embeddings[i] = &pb.Embedding{Values: v}
+	}
+	logger.NewContextLogger(ctx).Info(
+		"embeddings_complete",
+		"provider", rt.Provider,
+		"model", model,
+		"inputs", len(inputs),
+		"latency_ms", time.Since(requestStart).Milliseconds(),
+	)
+	return out, nil
+}
+
+// openAIEmbeddings calls the OpenAI-compatible /embeddings endpoint with every input
+// in one request, returning vectors in input order.
+func openAIEmbeddings(ctx context.Context, client *openai.Client, model string, inputs []string) (embeddingsResult, error) {
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{Input: inputs, Model: openai.EmbeddingModel(model)})
+	if err != nil {
+		return embeddingsResult{}, err
+	}
+	vectors := make([][]float32, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return embeddingsResult{}, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return embeddingsResult{vectors: vectors, usage: resp.Usage}, nil
+}
+
+// ollamaEmbeddings calls Ollama's native batch endpoint (POST /api/embed), whose
+// response shape differs from OpenAI's: {"embeddings": [[...], ...]}.
+func ollamaEmbeddings(ctx context.Context, baseURL, model string, inputs []string) (embeddingsResult, error) {
+	body, _ := json.Marshal(map[string]any{"model": model, "input": inputs})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return embeddingsResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return embeddingsResult{}, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return embeddingsResult{}, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Surface the status as an APIError so retryable codes (429/5xx) are retried.
+		return embeddingsResult{}, &openai.APIError{HTTPStatusCode: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+	}
+	var parsed struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return embeddingsResult{}, fmt.Errorf("decode ollama embeddings: %w", err)
+	}
+	usage := openai.Usage{PromptTokens: parsed.PromptEvalCount, TotalTokens: parsed.PromptEvalCount}
+	return embeddingsResult{vectors: parsed.Embeddings, usage: usage}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// emptyGenerationReason is the ErrorInfo reason clients match to tell a provider
+// response with no usable content apart from other failures.
+const emptyGenerationReason = "EMPTY_GENERATION"
+
+// emptyGenerationError returns an Unavailable status carrying an ErrorInfo with
+// reason EMPTY_GENERATION. The condition is usually transient, so clients may retry.
+func emptyGenerationError(rt *llmRuntime, model string) error {
+	st := status.New(codes.Unavailable, "the model provider returned an empty generation")
+	info := &errdetails.ErrorInfo{Reason: emptyGenerationReason, Domain: SERVICE_NAME, Metadata: map[string]string{}}
+	if rt != nil {
+		info.Metadata["provider"] = string(rt.Provider)
+		info.Metadata["model"] = model
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// isEmptyGeneration reports whether a completion has no choices or only
+// whitespace content.
+func isEmptyGeneration(choices []openai.ChatCompletionChoice) bool {
+	for _, c := range choices {
+		if strings.TrimSpace(c.Message.Content) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasContentFilter reports whether any choice stopped on the provider's content
+// filter; those are reported as CONTENT_BLOCKED instead.
+func hasContentFilter(choices []openai.ChatCompletionChoice) bool {
+	for _, c := range choices {
+		if c.FinishReason == openai.FinishReasonContentFilter {
+			return true
+		}
+	}
+	return false
+}
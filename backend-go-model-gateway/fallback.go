@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"backend-go-model-gateway/internal/logger"
+)
+
+// chain returns the runtime followed by its fallbacks, in the order they are tried.
+func (rt *llmRuntime) chain() []*llmRuntime {
+	return append([]*llmRuntime{rt}, rt.Fallbacks...)
+}
+
+// withFallback runs fn (with retries) against the primary runtime, then against
+// each fallback in order until one succeeds. It returns the runtime that answered.
+//
+// Fallbacks are not attempted once ctx is done.
+func withFallback[T any](ctx context.Context, s *server, op string, fn func(ctx context.Context, rt *llmRuntime) (T, error)) (T, *llmRuntime, error) {
+	lg := logger.NewContextLogger(ctx)
+	runtimes := s.llm.chain()
+
+	var zero T
+	var lastErr error
+	for i, rt := range runtimes {
+		if i > 0 {
+			if ctx.Err() != nil {
+				break
+			}
+			lg.Warn(
+				"llm_fallback",
+				"op", op,
+				"provider", rt.Provider,
+				"model", rt.Model,
+				"fallback_index", i,
+				"previous_provider", runtimes[i-1].Provider,
+				"previous_error", lastErr,
+			)
+		}
+		res, err := withRetry(ctx, s.retry, op, func(ctx context.Context) (T, error) {
+			return fn(ctx, rt)
+		})
+		if err == nil {
+			return res, rt, nil
+		}
+		lastErr = err
+	}
+	if len(runtimes) == 1 {
+		return zero, nil, lastErr
+	}
+	return zero, nil, fmt.Errorf("all %d LLM providers failed; last error: %w", len(runtimes), lastErr)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+const (
+	defaultHealthCacheSec = 5
+	llmProbeTimeout       = 2 * time.Second
+)
+
+// Health statuses reported by the Health RPC.
+const (
+	healthServing    = "SERVING"
+	healthNotServing = "NOT_SERVING"
+)
+
+// llmProbe checks that an LLM provider is reachable with a lightweight GET
+// (OpenRouter/Anthropic: list models; Ollama: /api/tags). Results are cached for
+// LLM_HEALTH_CACHE_SECONDS so frequent health checks do not hammer the provider.
+type llmProbe struct {
+	url     string
+	headers map[string]string
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+func newLLMProbe(url string, headers map[string]string) *llmProbe {
+	return &llmProbe{url: url, headers: headers}
+}
+
+// check returns the cached probe result, re-probing once it is older than the TTL.
+func (p *llmProbe) check(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	ttl := time.Duration(getEnvInt("LLM_HEALTH_CACHE_SECONDS", defaultHealthCacheSec)) * time.Second
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.checked.IsZero() && time.Since(p.checked) < ttl {
+		return p.err
+	}
+	p.err = p.probe(ctx)
+	p.checked = time.Now()
+	return p.err
+}
+
+func (p *llmProbe) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, llmProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: unexpected status %d", p.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// Health implements modelgateway.ModelGatewayServer by probing the primary LLM provider.
+func (s *server) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	if s.llm == nil || s.llm.Client == nil {
+		return &pb.HealthResponse{Status: healthNotServing, Error: "LLM client is not initialized"}, nil
+	}
+	resp := &pb.HealthResponse{Status: healthServing, Provider: string(s.llm.Provider), Model: s.llm.Model}
+	if err := s.llm.probe.check(ctx); err != nil {
+		resp.Status = healthNotServing
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
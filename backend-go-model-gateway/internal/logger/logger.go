@@ -4,6 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
+
+	"backend-go-model-gateway/redact"
 )
 
 // contextKey is an unexported type for context keys.
@@ -12,7 +15,32 @@ type contextKey string
 // TraceIDKey is the context key (and canonical header name) for the Trace ID.
 const TraceIDKey contextKey = "X-Trace-ID"
 
-var defaultLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+var defaultLogger = newDefaultLogger()
+
+// newDefaultLogger writes JSON lines to stdout at LOG_LEVEL (debug, info, warn
+// or error; default info). LOG_FORMAT=text switches to key=value lines. Secrets
+// and email addresses in logged fields are masked.
+func newDefaultLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(os.Getenv("LOG_LEVEL")), ReplaceAttr: redact.ReplaceAttr}
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("LOG_FORMAT")), "text") {
+		return slog.New(slog.NewTextHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+}
+
+// ParseLevel maps a LOG_LEVEL value to a slog level. Unknown values mean info.
+func ParseLevel(v string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
 
 // NewContextLogger creates a logger that always includes the trace_id from the context, if present.
 func NewContextLogger(ctx context.Context) *slog.Logger {
@@ -22,3 +50,10 @@ func NewContextLogger(ctx context.Context) *slog.Logger {
 	}
 	return defaultLogger.With("trace_id", traceID)
 }
+
+// Fatalf logs an error message and exits the program with status code 1.
+// This provides Fatalf-like functionality for slog.Logger.
+func Fatalf(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
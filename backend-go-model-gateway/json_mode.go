@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// jsonModeProviders lists providers whose chat API honors
+// response_format {"type":"json_object"}.
+var jsonModeProviders = map[llmProvider]bool{
+	providerOpenRouter: true,
+}
+
+// jsonModeEnabled decides whether plans from provider are constrained to JSON
+// server-side. PLAN_JSON_MODE is "auto" (default: per-provider capability), "on" or "off".
+func jsonModeEnabled(provider llmProvider) bool {
+	switch strings.ToLower(strings.TrimSpace(getEnv("PLAN_JSON_MODE", "auto"))) {
+	case "on", "true", "1":
+		return true
+	case "off", "false", "0":
+		return false
+	default:
+		return jsonModeProviders[provider]
+	}
+}
+
+// applyJSONMode constrains req to a JSON object response when rt supports it.
+func (rt *llmRuntime) applyJSONMode(req *openai.ChatCompletionRequest) {
+	if rt.JSONMode {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+}
@@ -0,0 +1,78 @@
+// Package jsonextract locates JSON embedded in LLM completions: fenced code
+// blocks and balanced objects/arrays surrounded by prose. It is shared by the
+// gateway's plan normalization and the agent planner's tool-call parsing.
+package jsonextract
+
+import "strings"
+
+// StripFences removes a surrounding markdown code fence (```json ... ```), if any.
+func StripFences(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	// Drop the first fence line
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		s = s[idx+1:]
+	}
+	// Drop the trailing fence
+	if end := strings.LastIndex(s, "```"); end >= 0 {
+		s = s[:end]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Candidates returns every top-level balanced JSON object or array embedded in s,
+// in order of appearance. Brackets inside JSON strings (including escaped quotes)
+// are ignored. Candidates are not validated; callers should try to parse each.
+func Candidates(s string) []string {
+	var out []string
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' && s[i] != '[' {
+			continue
+		}
+		if end := matchBalanced(s, i); end > 0 {
+			out = append(out, s[i:end])
+			i = end - 1
+		}
+	}
+	return out
+}
+
+// matchBalanced returns the index just past the bracket that closes s[start],
+// or -1 if it is never closed or the brackets are mismatched.
+func matchBalanced(s string, start int) int {
+	var stack []byte
+	inString, escaped := false, false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return -1
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,16 @@
+package jsonextract
+
+import "testing"
+
+func TestCandidates_IgnoresBracketsInStrings(t *testing.T) {
+	got := Candidates(`note: {"a":"}{","b":[1,2]} then [3] and {unclosed`)
+	want := []string{`{"a":"}{","b":[1,2]}`, `[3]`}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d candidates, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("candidate %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
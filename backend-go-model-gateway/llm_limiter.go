@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	"backend-go-model-gateway/internal/logger"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// llmLimiter caps concurrent upstream LLM requests (LLM_MAX_CONCURRENCY) so many
+// parallel sessions do not trip provider rate limits. A nil limiter is unlimited.
+type llmLimiter struct {
+	sem      chan struct{}
+	inFlight atomic.Int64
+}
+
+func loadLLMLimiter() *llmLimiter {
+	n := getEnvInt("LLM_MAX_CONCURRENCY", 0)
+	if n <= 0 {
+		return nil
+	}
+	return &llmLimiter{sem: make(chan struct{}, n)}
+}
+
+// acquire waits for a slot until ctx is done, in which case it returns a
+// ResourceExhausted status. The returned release func must be called once the
+// LLM call finishes.
+func (l *llmLimiter) acquire(ctx context.Context, op string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	lg := logger.NewContextLogger(ctx)
+	select {
+	case l.sem <- struct{}{}:
+	default:
+		lg.Info("llm_concurrency_wait", "op", op, "in_flight", l.inFlight.Load(), "max_concurrency", cap(l.sem))
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			lg.Warn("llm_concurrency_exhausted", "op", op, "in_flight", l.inFlight.Load(), "max_concurrency", cap(l.sem), "error", ctx.Err())
+			return nil, status.Errorf(codes.ResourceExhausted, "LLM concurrency limit (%d) reached: %v", cap(l.sem), ctx.Err())
+		}
+	}
+	inFlight := l.inFlight.Add(1)
+	lg.Debug("llm_concurrency_acquired", "op", op, "in_flight", inFlight, "max_concurrency", cap(l.sem))
+	return func() {
+		l.inFlight.Add(-1)
+		<-l.sem
+	}, nil
+}
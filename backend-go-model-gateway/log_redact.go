@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"backend-go-model-gateway/redact"
+)
+
+// redactPrompts logs a hash and length instead of prompt and query text
+// (LOG_REDACT_PROMPTS). Secrets are masked in every log field regardless.
+var redactPrompts = envBool("LOG_REDACT_PROMPTS")
+
+func envBool(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// textAttrs returns the log fields for user text logged under key.
+func textAttrs(key, text string) []any {
+	if redactPrompts {
+		return []any{key + "_hash", redact.Digest(text), key + "_length", len(text)}
+	}
+	return []any{key, text}
+}
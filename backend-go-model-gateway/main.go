@@ -7,13 +7,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"backend-go-model-gateway/internal/logger"
@@ -41,6 +42,9 @@ const (
 	defaultProvider          = "openrouter"
 	defaultOllamaBaseURL     = "http://localhost:11434"
 	defaultRequestTimeoutSec = 5
+	defaultMaxCandidates     = 5
+	// defaultShutdownTimeoutSec bounds draining of in-flight RPCs on SIGINT/SIGTERM.
+	defaultShutdownTimeoutSec = 10
 )
 
 // sharedHTTPClient is a single, long-lived HTTP client that provides connection
@@ -65,7 +69,7 @@ func newSharedHTTPClient() *http.Client {
 	}
 
 	return &http.Client{
-		Transport: ClientTraceTransport(base),
+		Transport: retryAfterTransport{next: ClientTraceTransport(base)},
 	}
 }
 
@@ -74,12 +78,23 @@ type llmProvider string
 const (
 	providerOpenRouter llmProvider = "openrouter"
 	providerOllama     llmProvider = "ollama"
+	providerAnthropic  llmProvider = "anthropic"
 )
 
 type llmRuntime struct {
 	Provider llmProvider
 	Model    string
 	Client   *openai.Client
+	// JSONMode requests response_format json_object (see jsonModeEnabled).
+	JSONMode bool
+	// Fallbacks are tried in order when this runtime fails (LLM_FALLBACK_PROVIDERS).
+	Fallbacks []*llmRuntime
+	// probe checks provider reachability for the Health RPC.
+	probe *llmProbe
+	// EmbeddingModel is used by GetEmbeddings (EMBEDDING_MODEL_NAME).
+	EmbeddingModel string
+	// ollamaBaseURL is Ollama's native API root, used for its embeddings endpoint.
+	ollamaBaseURL string
 }
 
 // --- Tool Definitions (for LLM tool-use prompting) ---
@@ -132,6 +147,24 @@ func normalizeOllamaBaseURL(base string) string {
 	return base + "/v1"
 }
 
+// loadTLSServerCreds loads a server certificate for one-way TLS from
+// GRPC_TLS_CERT/GRPC_TLS_KEY. It is used when mTLS (TLS_SERVER_*) is not configured.
+func loadTLSServerCreds() (credentials.TransportCredentials, bool, error) {
+	certPath := os.Getenv("GRPC_TLS_CERT")
+	keyPath := os.Getenv("GRPC_TLS_KEY")
+	if certPath == "" && keyPath == "" {
+		return nil, false, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, false, fmt.Errorf("TLS misconfigured: GRPC_TLS_CERT and GRPC_TLS_KEY must both be set")
+	}
+	creds, err := credentials.NewServerTLSFromFile(certPath, keyPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("load server keypair (%s, %s): %w", filepath.Clean(certPath), filepath.Clean(keyPath), err)
+	}
+	return creds, true, nil
+}
+
 func loadMTLSServerCreds() (credentials.TransportCredentials, bool, error) {
 	serverCertPath := os.Getenv("TLS_SERVER_CERT_PATH")
 	serverKeyPath := os.Getenv("TLS_SERVER_KEY_PATH")
@@ -170,9 +203,38 @@ func loadMTLSServerCreds() (credentials.TransportCredentials, bool, error) {
 	return credentials.NewTLS(conf), true, nil
 }
 
+// initializeLLMClient builds the primary runtime (LLM_PROVIDER) and its ordered
+// fallbacks (LLM_FALLBACK_PROVIDERS, comma-separated provider names).
 func initializeLLMClient() (*llmRuntime, error) {
-	provider := llmProvider(strings.ToLower(getEnv("LLM_PROVIDER", defaultProvider)))
+	primary, err := newLLMRuntime(llmProvider(strings.ToLower(getEnv("LLM_PROVIDER", defaultProvider))))
+	if err != nil {
+		return nil, err
+	}
+	primary.JSONMode = jsonModeEnabled(primary.Provider)
+	primary.EmbeddingModel = getEnv("EMBEDDING_MODEL_NAME", defaultEmbeddingModel)
 
+	seen := map[llmProvider]bool{primary.Provider: true}
+	for _, name := range strings.Split(getEnv("LLM_FALLBACK_PROVIDERS", ""), ",") {
+		provider := llmProvider(strings.ToLower(strings.TrimSpace(name)))
+		if provider == "" {
+			continue
+		}
+		if seen[provider] {
+			return nil, fmt.Errorf("LLM_FALLBACK_PROVIDERS: provider %q is configured more than once", provider)
+		}
+		seen[provider] = true
+		rt, err := newLLMRuntime(provider)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_FALLBACK_PROVIDERS: %w", err)
+		}
+		rt.JSONMode = jsonModeEnabled(rt.Provider)
+		primary.Fallbacks = append(primary.Fallbacks, rt)
+	}
+	return primary, nil
+}
+
+// newLLMRuntime builds a client for a single provider from its environment variables.
+func newLLMRuntime(provider llmProvider) (*llmRuntime, error) {
 	// Shared OpenAI-compatible client setup (go-openai)
 	switch provider {
 	case providerOllama:
@@ -182,7 +244,9 @@ func initializeLLMClient() (*llmRuntime, error) {
 		cfg.BaseURL = ollamaBase
 		cfg.HTTPClient = sharedHTTPClient
 		client := openai.NewClientWithConfig(cfg)
-		return &llmRuntime{Provider: providerOllama, Model: model, Client: client}, nil
+		nativeBase := strings.TrimSuffix(ollamaBase, "/v1")
+		probe := newLLMProbe(nativeBase+"/api/tags", nil)
+		return &llmRuntime{Provider: providerOllama, Model: model, Client: client, probe: probe, ollamaBaseURL: nativeBase}, nil
 
 	case providerOpenRouter, "":
 		apiKey := os.Getenv("OPENROUTER_API_KEY")
@@ -194,10 +258,22 @@ func initializeLLMClient() (*llmRuntime, error) {
 		cfg.BaseURL = "https://openrouter.ai/api/v1"
 		cfg.HTTPClient = sharedHTTPClient
 		client := openai.NewClientWithConfig(cfg)
-		return &llmRuntime{Provider: providerOpenRouter, Model: model, Client: client}, nil
+		probe := newLLMProbe(cfg.BaseURL+"/models", map[string]string{"Authorization": "Bearer " + apiKey})
+		return &llmRuntime{Provider: providerOpenRouter, Model: model, Client: client, probe: probe}, nil
+
+	case providerAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY is required when LLM_PROVIDER=anthropic")
+		}
+		model := getEnv("ANTHROPIC_MODEL_NAME", defaultAnthropicModel)
+		baseURL := strings.TrimRight(getEnv("ANTHROPIC_BASE_URL", defaultAnthropicBaseURL), "/")
+		client := newAnthropicClient(apiKey, baseURL)
+		probe := newLLMProbe(baseURL+"/models", map[string]string{"x-api-key": apiKey, "anthropic-version": anthropicAPIVersion})
+		return &llmRuntime{Provider: providerAnthropic, Model: model, Client: client, probe: probe}, nil
 
 	default:
-		return nil, fmt.Errorf("unsupported LLM_PROVIDER=%q (supported: openrouter, ollama)", provider)
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER=%q (supported: openrouter, ollama, anthropic)", provider)
 	}
 }
 
@@ -209,6 +285,22 @@ type server struct {
 	vectorDB RAGContextClient
 	// Per-request timeout for the LLM call.
 	requestTimeout time.Duration
+	// contextLimits rejects prompts that would overflow the model's context window.
+	contextLimits contextLimits
+	// modelTypes decides the model_type reported in normalized plans.
+	modelTypes modelTypePolicy
+	// retry retries transient (429/5xx) LLM failures within requestTimeout.
+	retry retryPolicy
+	// limiter caps concurrent upstream LLM requests (LLM_MAX_CONCURRENCY); nil is unlimited.
+	limiter *llmLimiter
+	// cache serves repeated GetPlan requests (LLM_CACHE_SIZE); nil disables it.
+	cache *planCache
+	// metrics records per-RPC Prometheus metrics; nil disables them.
+	metrics *planMetrics
+	// maxCandidates bounds PlanRequest.n to cap best-of-N cost.
+	maxCandidates int
+	// prices estimates per-request cost from token usage.
+	prices modelPrices
 }
 
 // healthServer implements the standard gRPC Health Checking Protocol.
@@ -248,20 +340,143 @@ func (h *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, _ grpc_health
 }
 
 // GetPlan implements modelgateway.ModelGatewayServer.
-func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanResponse, error) {
+func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (out *pb.PlanResponse, err error) {
 	requestStart := time.Now()
 
 	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
 
+	var rt *llmRuntime
+	var usage openai.Usage
+	defer func() { s.metrics.record(ctx, "GetPlan", s.llm, rt, requestStart, usage, err) }()
+
 	// Bound the LLM call.
 	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
 	defer cancel()
 
+	n := int(in.GetN())
+	if n == 0 {
+		n = 1
+	}
+	if n < 0 || n > s.maxCandidates {
+		return nil, status.Errorf(codes.InvalidArgument, "n must be between 1 and %d, got %d", s.maxCandidates, in.GetN())
+	}
+
+	params, err := planParamsFrom(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheKey string
+	if s.cache != nil {
+		cacheKey = planCacheKey(in, params.modelFor(s.llm, s.llm), params, n)
+		lookupStart := time.Now()
+		cached, hit := s.cache.get(cacheKey)
+		s.metrics.recordCache(ctx, hit)
+		if hit {
+			cached.LatencyMs = time.Since(lookupStart).Milliseconds()
+			logger.NewContextLogger(ctx).Info("llm_cache_hit", "model", cached.GetModelName())
+			return cached, nil
+		}
+		logger.NewContextLogger(ctx).Info("llm_cache_miss")
+	}
+
+	provider, messages, err := s.preparePlan(callCtx, "GetPlan", in, params)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.limiter.acquire(callCtx, "GetPlan")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, rt, err := withFallback(callCtx, s, "CreateChatCompletion", func(ctx context.Context, rt *llmRuntime) (openai.ChatCompletionResponse, error) {
+		req := openai.ChatCompletionRequest{
+			Model:    params.modelFor(rt, s.llm),
+			Messages: messages,
+		}
+		params.apply(&req)
+		rt.applyJSONMode(&req)
+		if n > 1 {
+			req.N = n
+		}
+		ctx, span := startLLMSpan(ctx, "CreateChatCompletion", rt, req.Model)
+		resp, err := rt.Client.CreateChatCompletion(ctx, req)
+		endLLMSpan(span, resp.Usage, err)
+		return resp, err
+	})
+	if err != nil {
+		if isProviderContentFilter(err) {
+			return nil, contentBlockedError(s.llm, params.modelFor(s.llm, s.llm))
+		}
+		return nil, err
+	}
+	// model_type and pricing follow the runtime that actually answered.
+	provider = string(rt.Provider)
+	usage = resp.Usage
+
+	if isEmptyGeneration(resp.Choices) && !hasContentFilter(resp.Choices) {
+		logger.NewContextLogger(ctx).Warn("llm_empty_generation", "provider", provider, "model", params.modelFor(rt, s.llm), "choices", len(resp.Choices))
+		return nil, emptyGenerationError(rt, params.modelFor(rt, s.llm))
+	}
+
+	// Best-of-N: keep every normalized candidate and select the first valid one.
+	var candidates []string
+	selected := -1
+	filtered := 0
+	for i, choice := range resp.Choices {
+		if choice.FinishReason == openai.FinishReasonContentFilter {
+			filtered++
+		}
+		plan, ok := normalizePlan(choice.Message.Content, in.GetPrompt(), provider, s.modelTypes, rt.JSONMode)
+		candidates = append(candidates, plan)
+		if ok && selected < 0 {
+			selected = i
+		}
+	}
+	// A content-filtered generation has no usable plan; report it as blocked
+	// rather than wrapping the (empty or partial) output as a fallback plan.
+	if selected < 0 && filtered > 0 {
+		logger.NewContextLogger(ctx).Warn("llm_content_blocked", "provider", provider, "model", params.modelFor(rt, s.llm), "filtered_choices", filtered)
+		return nil, contentBlockedError(rt, params.modelFor(rt, s.llm))
+	}
+	// Only responses with a valid plan are cached.
+	cacheable := selected >= 0
+	if selected < 0 {
+		selected = 0
+	}
+
+	latencyMs := time.Since(requestStart).Milliseconds()
+	out = &pb.PlanResponse{
+		Plan:              candidates[selected],
+		ModelName:         params.modelFor(rt, s.llm),
+		LatencyMs:         latencyMs,
+		PromptTokens:      int32(resp.Usage.PromptTokens),
+		CompletionTokens:  int32(resp.Usage.CompletionTokens),
+		TotalTokens:       int32(resp.Usage.TotalTokens),
+		EstimatedCostUsd:  s.prices.cost(params.modelFor(rt, s.llm), resp.Usage),
+		SystemFingerprint: resp.SystemFingerprint,
+	}
+	out.ModelType, out.Steps = planFields(out.Plan)
+	if n > 1 {
+		out.Candidates = candidates
+	}
+	if cacheable {
+		s.cache.put(cacheKey, out)
+	}
+	return out, nil
+}
+
+// preparePlan logs the request, retrieves RAG context and builds the chat messages
+// shared by GetPlan and GetPlanStream. It returns the provider name used for
+// model_type normalization.
+func (s *server) preparePlan(callCtx context.Context, method string, in *pb.PlanRequest, params planParams) (string, []openai.ChatCompletionMessage, error) {
 	provider := "uninitialized"
 	model := "uninitialized"
 	if s.llm != nil {
 		provider = string(s.llm.Provider)
-		model = s.llm.Model
+		model = params.modelFor(s.llm, s.llm)
 	}
 
 	lg := logger.NewContextLogger(callCtx)
@@ -273,16 +488,20 @@ func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanRespo
 		resourceTypes = append(resourceTypes, r.GetType())
 	}
 	lg.Info(
-		"GetPlan",
+		method,
 		"provider", provider,
 		"model", model,
-		"prompt", in.GetPrompt(),
+		"prompt_length", len(in.GetPrompt()),
 		"resource_count", len(in.GetResources()),
 		"resource_types", resourceTypes,
+		"temperature", params.temperature,
+		"max_tokens", params.maxTokens,
 	)
+	// The prompt itself is only logged at LOG_LEVEL=debug (hashed with LOG_REDACT_PROMPTS).
+	lg.Debug(method+"_prompt", textAttrs("prompt", in.GetPrompt())...)
 
 	if s.llm == nil || s.llm.Client == nil {
-		return nil, fmt.Errorf("LLM client not initialized")
+		return "", nil, fmt.Errorf("LLM client not initialized")
 	}
 
 	// --- RAG: Retrieve vector context (best-effort; do not fail the request) ---
@@ -332,140 +551,58 @@ func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanRespo
 
 	user := retrievalPreamble + fmt.Sprintf("User prompt: %s", in.GetPrompt())
 
-	resp, err := s.llm.Client.CreateChatCompletion(
-		callCtx,
-		openai.ChatCompletionRequest{
-			Model: s.llm.Model,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: system},
-				{Role: openai.ChatMessageRoleUser, Content: user},
-			},
-			Temperature: 0.2,
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	content := ""
-	if len(resp.Choices) > 0 {
-		content = resp.Choices[0].Message.Content
-	}
-
-	trimmed := strings.TrimSpace(content)
-
-	// Normalize common LLM output formats into strict JSON:
-	// - raw JSON object
-	// - fenced code block containing JSON
-	// - non-JSON text (fallback wrapper)
-	stripFences := func(s string) string {
-		s = strings.TrimSpace(s)
-		if !strings.HasPrefix(s, "```") {
-			return s
-		}
-		// Drop the first fence line
-		if idx := strings.Index(s, "\n"); idx >= 0 {
-			s = s[idx+1:]
-		}
-		// Drop the trailing fence
-		if end := strings.LastIndex(s, "```"); end >= 0 {
-			s = s[:end]
-		}
-		return strings.TrimSpace(s)
-	}
-
-	normalizeJSON := func(raw string) (string, bool) {
-		candidate := strings.TrimSpace(raw)
-		if !strings.HasPrefix(candidate, "{") {
-			return "", false
-		}
-
-		var obj map[string]any
-		if err := json.Unmarshal([]byte(candidate), &obj); err != nil {
-			return "", false
-		}
-
-		// Tool-call path: pass through (but ensure tracing fields exist).
-		if toolObj, ok := obj["tool"].(map[string]any); ok {
-			name, _ := toolObj["name"].(string)
-			if strings.TrimSpace(name) == "" {
-				return "", false
-			}
-			if _, ok := toolObj["args"]; !ok {
-				toolObj["args"] = map[string]any{}
-			}
-			if _, ok := obj["model_type"]; !ok {
-				obj["model_type"] = provider
-			}
-			if _, ok := obj["prompt"]; !ok {
-				obj["prompt"] = in.GetPrompt()
-			}
-			b, _ := json.Marshal(obj)
-			return string(b), true
-		}
-
-		// Planning path: require a non-empty steps array.
-		stepsAny, ok := obj["steps"].([]any)
-		if !ok || len(stepsAny) == 0 {
-			return "", false
-		}
-		steps := make([]string, 0, len(stepsAny))
-		for _, v := range stepsAny {
-			if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
-				steps = append(steps, s)
-			}
-		}
-		if len(steps) == 0 {
-			return "", false
-		}
-		payload := map[string]any{
-			"model_type": provider,
-			"steps":      steps,
-			"prompt":     in.GetPrompt(),
-		}
-		b, _ := json.Marshal(payload)
-		return string(b), true
-	}
-
-	// 1) Try raw JSON
-	if normalized, ok := normalizeJSON(trimmed); ok {
-		trimmed = normalized
-	} else {
-		// 2) Try fenced JSON
-		fenced := stripFences(trimmed)
-		if normalized, ok := normalizeJSON(fenced); ok {
-			trimmed = normalized
-		} else {
-			// 3) Fallback wrapper
-			fallback := map[string]any{
-				"model_type": provider,
-				"steps":      []string{trimmed},
-				"prompt":     in.GetPrompt(),
-			}
-			b, _ := json.Marshal(fallback)
-			trimmed = string(b)
+	// Fail fast instead of letting the provider reject an oversized prompt.
+	if maxTokens := s.contextLimits.maxFor(model); maxTokens > 0 {
+		if estimated := estimateTokens(system, user); estimated > maxTokens {
+			lg.Warn("prompt_too_long", "estimated_tokens", estimated, "max_context_tokens", maxTokens)
+			return "", nil, status.Errorf(
+				codes.InvalidArgument,
+				"prompt too long for model %q: estimated %d tokens exceeds the %d-token context window by approximately %d tokens",
+				model, estimated, maxTokens, estimated-maxTokens,
+			)
 		}
 	}
 
-	latencyMs := time.Since(requestStart).Milliseconds()
-	return &pb.PlanResponse{
-		Plan:      trimmed,
-		ModelName: s.llm.Model,
-		LatencyMs: latencyMs,
+	return provider, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: system},
+		{Role: openai.ChatMessageRoleUser, Content: user},
 	}, nil
 }
 
 func main() {
+	lg := logger.NewContextLogger(context.Background())
+
 	// --- OpenTelemetry tracing (best-effort) ---
 	if tp, err := InitTracer(context.Background()); err != nil {
-		log.Printf(
-			`{"timestamp":"%s","level":"warn","service":"%s","component":"tracing","error":%q,"message":"failed to initialize OpenTelemetry; continuing without tracing"}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
-		)
+		lg.Warn("tracing_init_failed", "error", err)
+	} else if tp == nil {
+		lg.Info("tracing_disabled", "reason", "OTEL_EXPORTER_OTLP_ENDPOINT not set")
 	} else {
 		defer func() { _ = tp.Shutdown(context.Background()) }()
 	}
 
+	// --- Prometheus metrics (best-effort) ---
+	var metricsSrv *http.Server
+	var planMetricsRec *planMetrics
+	if promHandler, shutdownMetrics, err := InitMetrics(); err != nil {
+		lg.Warn("metrics_init_failed", "error", err)
+	} else {
+		defer func() { _ = shutdownMetrics(context.Background()) }()
+		if planMetricsRec, err = newPlanMetrics(); err != nil {
+			lg.Warn("plan_metrics_init_failed", "error", err)
+		}
+		metricsPort := getEnvInt("METRICS_PORT", defaultMetricsPort)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promHandler)
+		metricsSrv = &http.Server{Addr: fmt.Sprintf(":%d", metricsPort), Handler: mux}
+		go func() {
+			lg.Info("metrics_server_listening", "port", metricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				lg.Error("metrics_server_failed", "error", err)
+			}
+		}()
+	}
+
 	// Parse port from environment or flag
 	grpcPortEnv := os.Getenv("MODEL_GATEWAY_GRPC_PORT")
 	port, err := strconv.Atoi(grpcPortEnv)
@@ -478,79 +615,110 @@ func main() {
 	defer cancelRAGDial()
 	vectorClient, err := NewRAGGRPCClient(rigCtx)
 	if err != nil {
-		log.Fatalf(
-			`{"timestamp":"%s","level":"fatal","service":"%s","component":"RAGGRPCClient","error":%q}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
-		)
+		logger.Fatalf(lg, "rag_client_init_failed", "error", err)
 	}
 	defer func() { _ = vectorClient.Close() }()
 
 	// Temporary HTTP endpoint for independent testing of vector retrieval.
 	httpPort := getEnvInt("MODEL_GATEWAY_HTTP_PORT", DEFAULT_HTTP_PORT)
+	httpSrv := &http.Server{Addr: fmt.Sprintf(":%d", httpPort), Handler: NewHTTPMux(vectorClient)}
 	go func() {
-		srv := &http.Server{Addr: fmt.Sprintf(":%d", httpPort), Handler: NewHTTPMux(vectorClient)}
-		log.Printf(
-			`{"timestamp":"%s","level":"info","service":"%s","version":"%s","port":%d,"message":"HTTP server listening (temporary vector-test endpoint)."}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, VERSION, httpPort,
-		)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Printf(
-				`{"timestamp":"%s","level":"error","service":"%s","error":"http server failed: %v"}`,
-				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err,
-			)
+		lg.Info("http_server_listening", "version", VERSION, "port", httpPort, "note", "temporary vector-test endpoint")
+		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lg.Error("http_server_failed", "error", err)
 		}
 	}()
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
-		log.Fatalf(
-			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": "failed to listen: %v"}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err,
-		)
+		logger.Fatalf(lg, "grpc_listen_failed", "port", port, "error", err)
 	}
 
 	llm, err := initializeLLMClient()
 	if err != nil {
-		log.Fatalf(
-			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
-		)
+		logger.Fatalf(lg, "llm_client_init_failed", "error", err)
 	}
 
 	timeoutSec := getEnvInt("REQUEST_TIMEOUT_SECONDS", defaultRequestTimeoutSec)
 
-	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	auth := apiKeyAuth{keys: loadAPIKeys()}
+	serverOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(auth.unary, retryAfterUnaryInterceptor),
+		grpc.ChainStreamInterceptor(auth.stream, retryAfterStreamInterceptor),
+	}
+	if len(auth.keys) == 0 {
+		lg.Warn("grpc_auth_disabled", "reason", "GATEWAY_API_KEY not set")
+	}
 	if creds, enabled, err := loadMTLSServerCreds(); err != nil {
-		log.Fatalf(
-			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
-		)
+		logger.Fatalf(lg, "grpc_mtls_config_failed", "error", err)
 	} else if enabled {
 		serverOpts = append(serverOpts, grpc.Creds(creds))
-		log.Printf(
-			`{"timestamp": "%s", "level": "info", "service": "%s", "message": "mTLS enabled for gRPC server."}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME,
-		)
+		lg.Info("grpc_mtls_enabled")
+	} else if creds, enabled, err := loadTLSServerCreds(); err != nil {
+		logger.Fatalf(lg, "grpc_tls_config_failed", "error", err)
+	} else if enabled {
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+		lg.Info("grpc_tls_enabled", "source", "GRPC_TLS_CERT")
 	} else {
-		log.Printf(
-			`{"timestamp": "%s", "level": "warn", "service": "%s", "message": "mTLS NOT enabled for gRPC server (TLS_* env vars not set); running insecure."}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME,
-		)
+		lg.Warn("grpc_tls_disabled", "reason", "TLS_* / GRPC_TLS_* env vars not set; running insecure")
 	}
 
 	s := grpc.NewServer(serverOpts...)
 	grpc_health_v1.RegisterHealthServer(s, &healthServer{llm: llm, ragClient: vectorClient})
-	pb.RegisterModelGatewayServer(s, &server{llm: llm, vectorDB: vectorClient, requestTimeout: time.Duration(timeoutSec) * time.Second})
-
-	log.Printf(
-		`{"timestamp": "%s", "level": "info", "service": "%s", "version": "%s", "port": %d, "provider": %q, "model": %q, "message": "gRPC server listening."}`,
-		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, VERSION, port, llm.Provider, llm.Model,
-	)
+	pb.RegisterModelGatewayServer(s, &server{
+		llm:            llm,
+		vectorDB:       vectorClient,
+		requestTimeout: time.Duration(timeoutSec) * time.Second,
+		contextLimits:  loadContextLimits(),
+		modelTypes:     loadModelTypePolicy(),
+		retry:          loadRetryPolicy(),
+		maxCandidates:  getEnvInt("PLAN_MAX_CANDIDATES", defaultMaxCandidates),
+		prices:         loadModelPrices(),
+		metrics:        planMetricsRec,
+		cache:          loadPlanCache(),
+		limiter:        loadLLMLimiter(),
+	})
+
+	lg.Info("grpc_server_listening", "version", VERSION, "port", port, "provider", llm.Provider, "model", llm.Model)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.Serve(lis) }()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		logger.Fatalf(lg, "grpc_serve_failed", "error", err)
+	case sig := <-quit:
+		timeout := time.Duration(getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", defaultShutdownTimeoutSec)) * time.Second
+		lg.Info("server_shutdown_start", "signal", sig.String(), "timeout_ms", timeout.Milliseconds())
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+		if metricsSrv != nil {
+			_ = metricsSrv.Shutdown(shutdownCtx)
+		}
+		graceful := gracefulStop(shutdownCtx, s)
+		lg.Info("server_shutdown_complete", "graceful", graceful)
+	}
+}
 
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf(
-			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": "failed to serve: %v"}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err,
-		)
+// gracefulStop drains in-flight RPCs with GracefulStop, falling back to Stop
+// (which cancels them) once ctx is done. It reports whether draining finished in time.
+func gracefulStop(ctx context.Context, s *grpc.Server) bool {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		logger.NewContextLogger(ctx).Warn("graceful_shutdown_timeout", "action", "forcing stop")
+		s.Stop()
+		<-done
+		return false
 	}
 }
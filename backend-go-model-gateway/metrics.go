@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultMetricsPort = 9464
+
+// InitMetrics installs an OpenTelemetry meter provider backed by a Prometheus
+// exporter and returns the /metrics handler.
+func InitMetrics() (http.Handler, func(context.Context) error, error) {
+	reg := promclient.NewRegistry()
+	exp, err := otelprom.New(otelprom.WithRegisterer(reg))
+	if err != nil {
+		return nil, nil, err
+	}
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(exp))
+	otel.SetMeterProvider(mp)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{}), mp.Shutdown, nil
+}
+
+// planMetrics records GetPlan/GetPlanStream calls, failures, LLM latency and
+// token usage, labelled by method, provider and model.
+type planMetrics struct {
+	calls    metric.Int64Counter
+	failures metric.Int64Counter
+	latency  metric.Float64Histogram
+	tokens   metric.Int64Counter
+	cache    metric.Int64Counter
+}
+
+func newPlanMetrics() (*planMetrics, error) {
+	meter := otel.Meter(SERVICE_NAME)
+	m := &planMetrics{}
+	var err error
+	if m.calls, err = meter.Int64Counter("gateway_plan_calls_total",
+		metric.WithDescription("Plan RPCs handled by the model gateway.")); err != nil {
+		return nil, err
+	}
+	if m.failures, err = meter.Int64Counter("gateway_plan_failures_total",
+		metric.WithDescription("Failed plan RPCs, by error class.")); err != nil {
+		return nil, err
+	}
+	if m.latency, err = meter.Float64Histogram("gateway_llm_latency_ms",
+		metric.WithDescription("Plan RPC latency including the LLM call, in milliseconds."),
+		metric.WithUnit("ms")); err != nil {
+		return nil, err
+	}
+	if m.tokens, err = meter.Int64Counter("gateway_llm_tokens_total",
+		metric.WithDescription("LLM tokens reported by the provider, by type (prompt, completion).")); err != nil {
+		return nil, err
+	}
+	if m.cache, err = meter.Int64Counter("gateway_llm_cache_total",
+		metric.WithDescription("GetPlan response cache lookups, by result (hit, miss).")); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// record is called once per plan RPC, whether or not it succeeded. rt is the
+// runtime that answered, or nil when none did (the primary is reported then).
+func (m *planMetrics) record(ctx context.Context, method string, primary, rt *llmRuntime, start time.Time, usage openai.Usage, err error) {
+	if m == nil {
+		return
+	}
+	if rt == nil {
+		rt = primary
+	}
+	attrs := []attribute.KeyValue{attribute.String("method", method)}
+	if rt != nil {
+		attrs = append(attrs, attribute.String("provider", string(rt.Provider)), attribute.String("model", rt.Model))
+	}
+	set := metric.WithAttributes(attrs...)
+
+	m.calls.Add(ctx, 1, set)
+	m.latency.Record(ctx, float64(time.Since(start).Microseconds())/1000, set)
+	if err != nil {
+		m.failures.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("error_class", errorClass(err)))...))
+		return
+	}
+	m.tokens.Add(ctx, int64(usage.PromptTokens), metric.WithAttributes(append(attrs, attribute.String("type", "prompt"))...))
+	m.tokens.Add(ctx, int64(usage.CompletionTokens), metric.WithAttributes(append(attrs, attribute.String("type", "completion"))...))
+}
+
+// recordCache counts a response cache lookup.
+func (m *planMetrics) recordCache(ctx context.Context, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
+
+// errorClass buckets an error for the failures counter: "timeout", "canceled",
+// "upstream_<status>" for provider HTTP errors, the gRPC code name for errors
+// raised by the gateway itself, or "other".
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	if code, _ := retryableStatus(err); code != 0 {
+		return fmt.Sprintf("upstream_%d", code)
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		return st.Code().String()
+	}
+	return "other"
+}
@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// modelTypePolicy controls the "model_type" field of normalized plans.
+type modelTypePolicy struct {
+	// fallback is used when the model omits model_type or emits a disallowed one.
+	// Empty means "use the provider name" (backward compatible).
+	fallback string
+	// allowed restricts accepted model_type values (empty = accept any).
+	allowed map[string]bool
+}
+
+// loadModelTypePolicy reads PLAN_DEFAULT_MODEL_TYPE and PLAN_ALLOWED_MODEL_TYPES (comma-separated).
+func loadModelTypePolicy() modelTypePolicy {
+	p := modelTypePolicy{
+		fallback: strings.TrimSpace(getEnv("PLAN_DEFAULT_MODEL_TYPE", "")),
+		allowed:  map[string]bool{},
+	}
+	for _, t := range strings.Split(getEnv("PLAN_ALLOWED_MODEL_TYPES", ""), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			p.allowed[t] = true
+		}
+	}
+	return p
+}
+
+// resolve returns the model_type to emit given the value extracted from the model output.
+func (p modelTypePolicy) resolve(extracted any, provider string) string {
+	fallback := p.fallback
+	if fallback == "" {
+		fallback = provider
+	}
+	value, _ := extracted.(string)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+	if len(p.allowed) > 0 && !p.allowed[value] {
+		return fallback
+	}
+	return value
+}
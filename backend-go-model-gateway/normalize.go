@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"backend-go-model-gateway/jsonextract"
+)
+
+// normalizeJSON validates a raw JSON object emitted by the model and rewrites it
+// into the strict plan/tool-call shape expected downstream.
+//
+// It returns false when raw is not a usable tool call or plan.
+func normalizeJSON(raw, prompt, provider string, modelTypes modelTypePolicy) (string, bool) {
+	candidate := strings.TrimSpace(raw)
+	if strings.HasPrefix(candidate, "[") {
+		// A bare JSON array is treated as the steps of a plan.
+		var stepsAny []any
+		if err := json.Unmarshal([]byte(candidate), &stepsAny); err != nil {
+			return "", false
+		}
+		return normalizeSteps(stepsAny, nil, prompt, provider, modelTypes)
+	}
+	if !strings.HasPrefix(candidate, "{") {
+		return "", false
+	}
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(candidate), &obj); err != nil {
+		return "", false
+	}
+
+	// Tool-call path: pass through (but ensure tracing fields exist).
+	if toolObj, ok := obj["tool"].(map[string]any); ok {
+		name, _ := toolObj["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return "", false
+		}
+		if _, ok := toolObj["args"]; !ok {
+			toolObj["args"] = map[string]any{}
+		}
+		obj["model_type"] = modelTypes.resolve(obj["model_type"], provider)
+		if _, ok := obj["prompt"]; !ok {
+			obj["prompt"] = prompt
+		}
+		b, _ := json.Marshal(obj)
+		return string(b), true
+	}
+
+	// Multi-tool path: a non-empty "tools" array of {"name", "args"} (or {"tool": {...}})
+	// entries passes through like a single tool call.
+	if toolsAny, ok := obj["tools"].([]any); ok && len(toolsAny) > 0 {
+		for _, t := range toolsAny {
+			entry, ok := t.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if nested, ok := entry["tool"].(map[string]any); ok {
+				entry = nested
+			}
+			name, _ := entry["name"].(string)
+			if strings.TrimSpace(name) == "" {
+				return "", false
+			}
+			if _, ok := entry["args"]; !ok {
+				entry["args"] = map[string]any{}
+			}
+		}
+		obj["model_type"] = modelTypes.resolve(obj["model_type"], provider)
+		if _, ok := obj["prompt"]; !ok {
+			obj["prompt"] = prompt
+		}
+		b, _ := json.Marshal(obj)
+		return string(b), true
+	}
+
+	// Planning path: require a non-empty steps array.
+	stepsAny, ok := obj["steps"].([]any)
+	if !ok {
+		return "", false
+	}
+	return normalizeSteps(stepsAny, obj["model_type"], prompt, provider, modelTypes)
+}
+
+// normalizeSteps builds a plan payload from a steps array, keeping non-empty strings.
+func normalizeSteps(stepsAny []any, modelType any, prompt, provider string, modelTypes modelTypePolicy) (string, bool) {
+	if len(stepsAny) == 0 {
+		return "", false
+	}
+	steps := make([]string, 0, len(stepsAny))
+	for _, v := range stepsAny {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return "", false
+	}
+	payload := map[string]any{
+		"model_type": modelTypes.resolve(modelType, provider),
+		"steps":      steps,
+		"prompt":     prompt,
+	}
+	b, _ := json.Marshal(payload)
+	return string(b), true
+}
+
+// planFields extracts model_type and steps from a normalized plan payload.
+// steps is nil for tool-call payloads.
+func planFields(plan string) (string, []string) {
+	var v struct {
+		ModelType string   `json:"model_type"`
+		Steps     []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(plan), &v); err != nil {
+		return "", nil
+	}
+	return v.ModelType, v.Steps
+}
+
+// normalizePlan turns the model's completion into strict JSON, trying in order:
+//   - raw JSON object
+//   - fenced code block containing JSON (skipped when jsonMode constrained the
+//     provider to emit a bare JSON object)
+//   - balanced JSON objects/arrays found anywhere in the text, in order
+//   - non-JSON text (fallback wrapper with the text as the single step)
+//
+// The boolean is false when the fallback wrapper was used.
+func normalizePlan(content, prompt, provider string, modelTypes modelTypePolicy, jsonMode bool) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+
+	// 1) Try raw JSON
+	if normalized, ok := normalizeJSON(trimmed, prompt, provider, modelTypes); ok {
+		return normalized, true
+	}
+	// 2) Try fenced JSON
+	if !jsonMode {
+		if normalized, ok := normalizeJSON(jsonextract.StripFences(trimmed), prompt, provider, modelTypes); ok {
+			return normalized, true
+		}
+	}
+	// 3) Scan for JSON embedded in prose, multiple fences or trailing commentary.
+	for _, candidate := range jsonextract.Candidates(trimmed) {
+		if normalized, ok := normalizeJSON(candidate, prompt, provider, modelTypes); ok {
+			return normalized, true
+		}
+	}
+	// 4) Fallback wrapper
+	fallback := map[string]any{
+		"model_type": modelTypes.resolve(nil, provider),
+		"steps":      []string{trimmed},
+		"prompt":     prompt,
+	}
+	b, _ := json.Marshal(fallback)
+	return string(b), false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNormalizeJSON_PreservesModelTypeFromOutput(t *testing.T) {
+	out, ok := normalizeJSON(`{"model_type":"code","steps":["x"]}`, "write a script", "openrouter", modelTypePolicy{})
+	if !ok {
+		t.Fatalf("expected plan to normalize")
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal normalized plan: %v", err)
+	}
+	if got["model_type"] != "code" {
+		t.Fatalf("expected model_type %q, got %v", "code", got["model_type"])
+	}
+}
+
+func TestNormalizeJSON_FallsBackToProviderWhenModelTypeMissing(t *testing.T) {
+	out, ok := normalizeJSON(`{"steps":["x"]}`, "write a script", "openrouter", modelTypePolicy{})
+	if !ok {
+		t.Fatalf("expected plan to normalize")
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal normalized plan: %v", err)
+	}
+	if got["model_type"] != "openrouter" {
+		t.Fatalf("expected model_type %q, got %v", "openrouter", got["model_type"])
+	}
+}
+
+func TestNormalizePlan_ExtractsProsePrefixedJSON(t *testing.T) {
+	content := "Here is the plan:\n{\"steps\":[\"search flights\",\"book hotel\"]}\nLet me know if you need more."
+	out, ok := normalizePlan(content, "plan a trip", "openrouter", modelTypePolicy{}, false)
+	if !ok {
+		t.Fatalf("expected embedded JSON to be extracted, got fallback %s", out)
+	}
+
+	var got struct {
+		Steps []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal normalized plan: %v", err)
+	}
+	if len(got.Steps) != 2 || got.Steps[0] != "search flights" {
+		t.Fatalf("unexpected steps: %v", got.Steps)
+	}
+}
+
+func TestNormalizePlan_AcceptsJSONArrayOfSteps(t *testing.T) {
+	content := "Steps:\n```json\n[\"a\", \"b\", \"\"]\n```"
+	out, ok := normalizePlan(content, "p", "openrouter", modelTypePolicy{}, false)
+	if !ok {
+		t.Fatalf("expected JSON array to be accepted, got fallback %s", out)
+	}
+
+	var got struct {
+		Steps []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal normalized plan: %v", err)
+	}
+	if len(got.Steps) != 2 || got.Steps[0] != "a" || got.Steps[1] != "b" {
+		t.Fatalf("unexpected steps: %v", got.Steps)
+	}
+}
+
+func TestNormalizePlan_UsesFirstValidOfTwoJSONBlocks(t *testing.T) {
+	content := "First:\n```json\n{\"steps\":[\"use {braces} and \\\"quotes\\\"\"]}\n```\n" +
+		"Second:\n```json\n{\"steps\": [\"broken\",]}\n```"
+	out, ok := normalizePlan(content, "p", "openrouter", modelTypePolicy{}, false)
+	if !ok {
+		t.Fatalf("expected first block to be used, got fallback %s", out)
+	}
+
+	var got struct {
+		Steps []string `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal normalized plan: %v", err)
+	}
+	if len(got.Steps) != 1 || got.Steps[0] != `use {braces} and "quotes"` {
+		t.Fatalf("unexpected steps: %v", got.Steps)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// modelPrice is the USD price per one million tokens for a model.
+type modelPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// modelPrices maps model names to token prices used for estimated_cost_usd.
+type modelPrices map[string]modelPrice
+
+// loadModelPrices reads PLAN_MODEL_PRICES ("model=prompt:completion,..."), with
+// prices in USD per million tokens, e.g. "gpt-4o-mini=0.15:0.60".
+func loadModelPrices() modelPrices {
+	prices := modelPrices{}
+	for _, entry := range strings.Split(getEnv("PLAN_MODEL_PRICES", ""), ",") {
+		model, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		in, out, ok := strings.Cut(spec, ":")
+		if !ok {
+			continue
+		}
+		promptPrice, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		completionPrice, err2 := strconv.ParseFloat(strings.TrimSpace(out), 64)
+		if err1 != nil || err2 != nil || promptPrice < 0 || completionPrice < 0 {
+			continue
+		}
+		prices[strings.TrimSpace(model)] = modelPrice{prompt: promptPrice, completion: completionPrice}
+	}
+	return prices
+}
+
+// cost estimates the USD cost of usage for model (0 when the model has no price).
+func (p modelPrices) cost(model string, usage openai.Usage) float64 {
+	price, ok := p[model]
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.prompt + float64(usage.CompletionTokens)*price.completion) / 1e6
+}
@@ -21,6 +21,275 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_proto_model_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{0}
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // "SERVING" or "NOT_SERVING"
+	Provider      string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"` // Probe failure, when NOT_SERVING.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_proto_model_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type EmbeddingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Inputs        []string               `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"` // Overrides EMBEDDING_MODEL_NAME; empty uses the configured model.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbeddingsRequest) Reset() {
+	*x = EmbeddingsRequest{}
+	mi := &file_proto_model_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingsRequest) ProtoMessage() {}
+
+func (x *EmbeddingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingsRequest.ProtoReflect.Descriptor instead.
+func (*EmbeddingsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EmbeddingsRequest) GetInputs() []string {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *EmbeddingsRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_proto_model_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Embedding) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type EmbeddingsResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Embeddings []*Embedding           `protobuf:"bytes,1,rep,name=embeddings,proto3" json:"embeddings,omitempty"` // One per input, in input order.
+	ModelName  string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	// Token usage reported by the provider (0 when the provider omits it).
+	PromptTokens  int32 `protobuf:"varint,3,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	TotalTokens   int32 `protobuf:"varint,4,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbeddingsResponse) Reset() {
+	*x = EmbeddingsResponse{}
+	mi := &file_proto_model_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingsResponse) ProtoMessage() {}
+
+func (x *EmbeddingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingsResponse.ProtoReflect.Descriptor instead.
+func (*EmbeddingsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EmbeddingsResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+func (x *EmbeddingsResponse) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *EmbeddingsResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *EmbeddingsResponse) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
 // Resource represents a structured, optional multi-modal input to the model.
 //
 // This is intentionally minimal and "agnostic": planners can attach references
@@ -35,7 +304,7 @@ type Resource struct {
 
 func (x *Resource) Reset() {
 	*x = Resource{}
-	mi := &file_proto_model_proto_msgTypes[0]
+	mi := &file_proto_model_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -47,7 +316,7 @@ func (x *Resource) String() string {
 func (*Resource) ProtoMessage() {}
 
 func (x *Resource) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[0]
+	mi := &file_proto_model_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -60,7 +329,7 @@ func (x *Resource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Resource.ProtoReflect.Descriptor instead.
 func (*Resource) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{0}
+	return file_proto_model_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Resource) GetType() string {
@@ -78,16 +347,28 @@ func (x *Resource) GetUri() string {
 }
 
 type PlanRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Prompt        string                 `protobuf:"bytes,1,opt,name=prompt,proto3" json:"prompt,omitempty"`
-	Resources     []*Resource            `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"` // Optional multi-modal inputs.
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Prompt    string                 `protobuf:"bytes,1,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Resources []*Resource            `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"` // Optional multi-modal inputs.
+	// Number of candidate plans to generate (best-of-N). 0 means 1; bounded by the
+	// gateway's PLAN_MAX_CANDIDATES. GetPlanStream only supports a single candidate.
+	N int32 `protobuf:"varint,3,opt,name=n,proto3" json:"n,omitempty"`
+	// Sampling overrides. Unset keeps the defaults (temperature 0.2, provider max tokens).
+	Temperature *float32 `protobuf:"fixed32,4,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`             // Must be within [0, 2].
+	MaxTokens   *int32   `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"` // Must be positive.
+	// Overrides the primary provider's configured model; fallback providers keep
+	// their own models. Empty uses the configured model.
+	Model string `protobuf:"bytes,6,opt,name=model,proto3" json:"model,omitempty"`
+	// Sampling seed for reproducible generations on providers that support it
+	// (compare system_fingerprint across responses). Unset uses random sampling.
+	Seed          *int32 `protobuf:"varint,7,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PlanRequest) Reset() {
 	*x = PlanRequest{}
-	mi := &file_proto_model_proto_msgTypes[1]
+	mi := &file_proto_model_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -99,7 +380,7 @@ func (x *PlanRequest) String() string {
 func (*PlanRequest) ProtoMessage() {}
 
 func (x *PlanRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[1]
+	mi := &file_proto_model_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -112,7 +393,7 @@ func (x *PlanRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PlanRequest.ProtoReflect.Descriptor instead.
 func (*PlanRequest) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{1}
+	return file_proto_model_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *PlanRequest) GetPrompt() string {
@@ -129,18 +410,67 @@ func (x *PlanRequest) GetResources() []*Resource {
 	return nil
 }
 
+func (x *PlanRequest) GetN() int32 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetTemperature() float32 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetSeed() int32 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
 type PlanResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Plan          string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
-	ModelName     string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
-	LatencyMs     int64                  `protobuf:"varint,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Plan       string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"` // Selected candidate: the first one that was valid JSON.
+	ModelName  string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	LatencyMs  int64                  `protobuf:"varint,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	Candidates []string               `protobuf:"bytes,4,rep,name=candidates,proto3" json:"candidates,omitempty"` // All normalized candidates, in completion order.
+	// Token usage reported by the provider (0 when the provider omits it).
+	PromptTokens     int32 `protobuf:"varint,5,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32 `protobuf:"varint,6,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int32 `protobuf:"varint,7,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	// Estimated from PLAN_MODEL_PRICES; 0 when the model has no configured price.
+	EstimatedCostUsd float64 `protobuf:"fixed64,8,opt,name=estimated_cost_usd,json=estimatedCostUsd,proto3" json:"estimated_cost_usd,omitempty"`
+	// Structured view of the selected plan, for consumers that do not want to
+	// parse plan. steps is empty when the plan is a tool call.
+	ModelType string   `protobuf:"bytes,9,opt,name=model_type,json=modelType,proto3" json:"model_type,omitempty"`
+	Steps     []string `protobuf:"bytes,10,rep,name=steps,proto3" json:"steps,omitempty"`
+	// Backend configuration fingerprint reported by the provider; outputs for the
+	// same seed are only expected to match while it is unchanged. Empty when omitted.
+	SystemFingerprint string `protobuf:"bytes,11,opt,name=system_fingerprint,json=systemFingerprint,proto3" json:"system_fingerprint,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *PlanResponse) Reset() {
 	*x = PlanResponse{}
-	mi := &file_proto_model_proto_msgTypes[2]
+	mi := &file_proto_model_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -152,7 +482,7 @@ func (x *PlanResponse) String() string {
 func (*PlanResponse) ProtoMessage() {}
 
 func (x *PlanResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[2]
+	mi := &file_proto_model_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -165,7 +495,7 @@ func (x *PlanResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PlanResponse.ProtoReflect.Descriptor instead.
 func (*PlanResponse) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{2}
+	return file_proto_model_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *PlanResponse) GetPlan() string {
@@ -189,6 +519,179 @@ func (x *PlanResponse) GetLatencyMs() int64 {
 	return 0
 }
 
+func (x *PlanResponse) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *PlanResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetEstimatedCostUsd() float64 {
+	if x != nil {
+		return x.EstimatedCostUsd
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetModelType() string {
+	if x != nil {
+		return x.ModelType
+	}
+	return ""
+}
+
+func (x *PlanResponse) GetSteps() []string {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *PlanResponse) GetSystemFingerprint() string {
+	if x != nil {
+		return x.SystemFingerprint
+	}
+	return ""
+}
+
+type PlanStreamChunk struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Delta     string                 `protobuf:"bytes,1,opt,name=delta,proto3" json:"delta,omitempty"`                           // Raw content delta (not normalized).
+	Done      bool                   `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`                            // True on the final chunk.
+	Plan      string                 `protobuf:"bytes,3,opt,name=plan,proto3" json:"plan,omitempty"`                             // Normalized plan JSON (final chunk only).
+	ModelName string                 `protobuf:"bytes,4,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`  // Final chunk only.
+	LatencyMs int64                  `protobuf:"varint,5,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"` // Final chunk only.
+	// Token usage and estimated cost (final chunk only; see PlanResponse).
+	PromptTokens      int32   `protobuf:"varint,6,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens  int32   `protobuf:"varint,7,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens       int32   `protobuf:"varint,8,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	EstimatedCostUsd  float64 `protobuf:"fixed64,9,opt,name=estimated_cost_usd,json=estimatedCostUsd,proto3" json:"estimated_cost_usd,omitempty"`
+	SystemFingerprint string  `protobuf:"bytes,10,opt,name=system_fingerprint,json=systemFingerprint,proto3" json:"system_fingerprint,omitempty"` // Final chunk only; see PlanResponse.
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PlanStreamChunk) Reset() {
+	*x = PlanStreamChunk{}
+	mi := &file_proto_model_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanStreamChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanStreamChunk) ProtoMessage() {}
+
+func (x *PlanStreamChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanStreamChunk.ProtoReflect.Descriptor instead.
+func (*PlanStreamChunk) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PlanStreamChunk) GetDelta() string {
+	if x != nil {
+		return x.Delta
+	}
+	return ""
+}
+
+func (x *PlanStreamChunk) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *PlanStreamChunk) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+func (x *PlanStreamChunk) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *PlanStreamChunk) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *PlanStreamChunk) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *PlanStreamChunk) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *PlanStreamChunk) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *PlanStreamChunk) GetEstimatedCostUsd() float64 {
+	if x != nil {
+		return x.EstimatedCostUsd
+	}
+	return 0
+}
+
+func (x *PlanStreamChunk) GetSystemFingerprint() string {
+	if x != nil {
+		return x.SystemFingerprint
+	}
+	return ""
+}
+
 type RAGContextRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Query          string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
@@ -200,7 +703,7 @@ type RAGContextRequest struct {
 
 func (x *RAGContextRequest) Reset() {
 	*x = RAGContextRequest{}
-	mi := &file_proto_model_proto_msgTypes[3]
+	mi := &file_proto_model_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -212,7 +715,7 @@ func (x *RAGContextRequest) String() string {
 func (*RAGContextRequest) ProtoMessage() {}
 
 func (x *RAGContextRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[3]
+	mi := &file_proto_model_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -225,7 +728,7 @@ func (x *RAGContextRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGContextRequest.ProtoReflect.Descriptor instead.
 func (*RAGContextRequest) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{3}
+	return file_proto_model_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *RAGContextRequest) GetQuery() string {
@@ -262,7 +765,7 @@ type RAGMatch struct {
 
 func (x *RAGMatch) Reset() {
 	*x = RAGMatch{}
-	mi := &file_proto_model_proto_msgTypes[4]
+	mi := &file_proto_model_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -274,7 +777,7 @@ func (x *RAGMatch) String() string {
 func (*RAGMatch) ProtoMessage() {}
 
 func (x *RAGMatch) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[4]
+	mi := &file_proto_model_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -287,7 +790,7 @@ func (x *RAGMatch) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGMatch.ProtoReflect.Descriptor instead.
 func (*RAGMatch) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{4}
+	return file_proto_model_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *RAGMatch) GetId() string {
@@ -334,7 +837,7 @@ type RAGContextResponse struct {
 
 func (x *RAGContextResponse) Reset() {
 	*x = RAGContextResponse{}
-	mi := &file_proto_model_proto_msgTypes[5]
+	mi := &file_proto_model_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -346,7 +849,7 @@ func (x *RAGContextResponse) String() string {
 func (*RAGContextResponse) ProtoMessage() {}
 
 func (x *RAGContextResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[5]
+	mi := &file_proto_model_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -359,7 +862,7 @@ func (x *RAGContextResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGContextResponse.ProtoReflect.Descriptor instead.
 func (*RAGContextResponse) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{5}
+	return file_proto_model_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *RAGContextResponse) GetMatches() []*RAGMatch {
@@ -391,7 +894,7 @@ type ToolRequest struct {
 
 func (x *ToolRequest) Reset() {
 	*x = ToolRequest{}
-	mi := &file_proto_model_proto_msgTypes[6]
+	mi := &file_proto_model_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -403,7 +906,7 @@ func (x *ToolRequest) String() string {
 func (*ToolRequest) ProtoMessage() {}
 
 func (x *ToolRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[6]
+	mi := &file_proto_model_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -416,7 +919,7 @@ func (x *ToolRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolRequest.ProtoReflect.Descriptor instead.
 func (*ToolRequest) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{6}
+	return file_proto_model_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ToolRequest) GetToolName() string {
@@ -472,7 +975,7 @@ type ToolResponse struct {
 
 func (x *ToolResponse) Reset() {
 	*x = ToolResponse{}
-	mi := &file_proto_model_proto_msgTypes[7]
+	mi := &file_proto_model_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -484,7 +987,7 @@ func (x *ToolResponse) String() string {
 func (*ToolResponse) ProtoMessage() {}
 
 func (x *ToolResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[7]
+	mi := &file_proto_model_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -497,7 +1000,7 @@ func (x *ToolResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolResponse.ProtoReflect.Descriptor instead.
 func (*ToolResponse) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{7}
+	return file_proto_model_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ToolResponse) GetStatus() string {
@@ -525,19 +1028,73 @@ var File_proto_model_proto protoreflect.FileDescriptor
 
 const file_proto_model_proto_rawDesc = "" +
 	"\n" +
-	"\x11proto/model.proto\x12\fmodelgateway\"0\n" +
+	"\x11proto/model.proto\x12\fmodelgateway\"\x0f\n" +
+	"\rHealthRequest\"p\n" +
+	"\x0eHealthResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"A\n" +
+	"\x11EmbeddingsRequest\x12\x16\n" +
+	"\x06inputs\x18\x01 \x03(\tR\x06inputs\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"\xb4\x01\n" +
+	"\x12EmbeddingsResponse\x127\n" +
+	"\n" +
+	"embeddings\x18\x01 \x03(\v2\x17.modelgateway.EmbeddingR\n" +
+	"embeddings\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x02 \x01(\tR\tmodelName\x12#\n" +
+	"\rprompt_tokens\x18\x03 \x01(\x05R\fpromptTokens\x12!\n" +
+	"\ftotal_tokens\x18\x04 \x01(\x05R\vtotalTokens\"0\n" +
 	"\bResource\x12\x12\n" +
 	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
-	"\x03uri\x18\x02 \x01(\tR\x03uri\"[\n" +
+	"\x03uri\x18\x02 \x01(\tR\x03uri\"\x8b\x02\n" +
 	"\vPlanRequest\x12\x16\n" +
 	"\x06prompt\x18\x01 \x01(\tR\x06prompt\x124\n" +
-	"\tresources\x18\x02 \x03(\v2\x16.modelgateway.ResourceR\tresources\"`\n" +
+	"\tresources\x18\x02 \x03(\v2\x16.modelgateway.ResourceR\tresources\x12\f\n" +
+	"\x01n\x18\x03 \x01(\x05R\x01n\x12%\n" +
+	"\vtemperature\x18\x04 \x01(\x02H\x00R\vtemperature\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x05H\x01R\tmaxTokens\x88\x01\x01\x12\x14\n" +
+	"\x05model\x18\x06 \x01(\tR\x05model\x12\x17\n" +
+	"\x04seed\x18\a \x01(\x05H\x02R\x04seed\x88\x01\x01B\x0e\n" +
+	"\f_temperatureB\r\n" +
+	"\v_max_tokensB\a\n" +
+	"\x05_seed\"\x87\x03\n" +
 	"\fPlanResponse\x12\x12\n" +
 	"\x04plan\x18\x01 \x01(\tR\x04plan\x12\x1d\n" +
 	"\n" +
 	"model_name\x18\x02 \x01(\tR\tmodelName\x12\x1d\n" +
 	"\n" +
-	"latency_ms\x18\x03 \x01(\x03R\tlatencyMs\"g\n" +
+	"latency_ms\x18\x03 \x01(\x03R\tlatencyMs\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x04 \x03(\tR\n" +
+	"candidates\x12#\n" +
+	"\rprompt_tokens\x18\x05 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x06 \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\a \x01(\x05R\vtotalTokens\x12,\n" +
+	"\x12estimated_cost_usd\x18\b \x01(\x01R\x10estimatedCostUsd\x12\x1d\n" +
+	"\n" +
+	"model_type\x18\t \x01(\tR\tmodelType\x12\x14\n" +
+	"\x05steps\x18\n" +
+	" \x03(\tR\x05steps\x12-\n" +
+	"\x12system_fingerprint\x18\v \x01(\tR\x11systemFingerprint\"\xdf\x02\n" +
+	"\x0fPlanStreamChunk\x12\x14\n" +
+	"\x05delta\x18\x01 \x01(\tR\x05delta\x12\x12\n" +
+	"\x04done\x18\x02 \x01(\bR\x04done\x12\x12\n" +
+	"\x04plan\x18\x03 \x01(\tR\x04plan\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x04 \x01(\tR\tmodelName\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x05 \x01(\x03R\tlatencyMs\x12#\n" +
+	"\rprompt_tokens\x18\x06 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\a \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\b \x01(\x05R\vtotalTokens\x12,\n" +
+	"\x12estimated_cost_usd\x18\t \x01(\x01R\x10estimatedCostUsd\x12-\n" +
+	"\x12system_fingerprint\x18\n" +
+	" \x01(\tR\x11systemFingerprint\"g\n" +
 	"\x11RAGContextRequest\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\x12\x13\n" +
 	"\x05top_k\x18\x02 \x01(\x05R\x04topK\x12'\n" +
@@ -560,10 +1117,13 @@ const file_proto_model_proto_rawDesc = "" +
 	"\fToolResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x16\n" +
 	"\x06stdout\x18\x02 \x01(\tR\x06stdout\x12\x16\n" +
-	"\x06stderr\x18\x03 \x01(\tR\x06stderr2\xa4\x01\n" +
+	"\x06stderr\x18\x03 \x01(\tR\x06stderr2\x8a\x03\n" +
 	"\fModelGateway\x12@\n" +
-	"\aGetPlan\x12\x19.modelgateway.PlanRequest\x1a\x1a.modelgateway.PlanResponse\x12R\n" +
-	"\rGetRAGContext\x12\x1f.modelgateway.RAGContextRequest\x1a .modelgateway.RAGContextResponse2S\n" +
+	"\aGetPlan\x12\x19.modelgateway.PlanRequest\x1a\x1a.modelgateway.PlanResponse\x12K\n" +
+	"\rGetPlanStream\x12\x19.modelgateway.PlanRequest\x1a\x1d.modelgateway.PlanStreamChunk0\x01\x12R\n" +
+	"\rGetRAGContext\x12\x1f.modelgateway.RAGContextRequest\x1a .modelgateway.RAGContextResponse\x12C\n" +
+	"\x06Health\x12\x1b.modelgateway.HealthRequest\x1a\x1c.modelgateway.HealthResponse\x12R\n" +
+	"\rGetEmbeddings\x12\x1f.modelgateway.EmbeddingsRequest\x1a .modelgateway.EmbeddingsResponse2S\n" +
 	"\vToolService\x12D\n" +
 	"\vExecuteTool\x12\x19.modelgateway.ToolRequest\x1a\x1a.modelgateway.ToolResponseB&Z$backend-go-model-gateway/proto;protob\x06proto3"
 
@@ -579,31 +1139,44 @@ func file_proto_model_proto_rawDescGZIP() []byte {
 	return file_proto_model_proto_rawDescData
 }
 
-var file_proto_model_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_model_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_proto_model_proto_goTypes = []any{
-	(*Resource)(nil),           // 0: modelgateway.Resource
-	(*PlanRequest)(nil),        // 1: modelgateway.PlanRequest
-	(*PlanResponse)(nil),       // 2: modelgateway.PlanResponse
-	(*RAGContextRequest)(nil),  // 3: modelgateway.RAGContextRequest
-	(*RAGMatch)(nil),           // 4: modelgateway.RAGMatch
-	(*RAGContextResponse)(nil), // 5: modelgateway.RAGContextResponse
-	(*ToolRequest)(nil),        // 6: modelgateway.ToolRequest
-	(*ToolResponse)(nil),       // 7: modelgateway.ToolResponse
+	(*HealthRequest)(nil),      // 0: modelgateway.HealthRequest
+	(*HealthResponse)(nil),     // 1: modelgateway.HealthResponse
+	(*EmbeddingsRequest)(nil),  // 2: modelgateway.EmbeddingsRequest
+	(*Embedding)(nil),          // 3: modelgateway.Embedding
+	(*EmbeddingsResponse)(nil), // 4: modelgateway.EmbeddingsResponse
+	(*Resource)(nil),           // 5: modelgateway.Resource
+	(*PlanRequest)(nil),        // 6: modelgateway.PlanRequest
+	(*PlanResponse)(nil),       // 7: modelgateway.PlanResponse
+	(*PlanStreamChunk)(nil),    // 8: modelgateway.PlanStreamChunk
+	(*RAGContextRequest)(nil),  // 9: modelgateway.RAGContextRequest
+	(*RAGMatch)(nil),           // 10: modelgateway.RAGMatch
+	(*RAGContextResponse)(nil), // 11: modelgateway.RAGContextResponse
+	(*ToolRequest)(nil),        // 12: modelgateway.ToolRequest
+	(*ToolResponse)(nil),       // 13: modelgateway.ToolResponse
 }
 var file_proto_model_proto_depIdxs = []int32{
-	0, // 0: modelgateway.PlanRequest.resources:type_name -> modelgateway.Resource
-	4, // 1: modelgateway.RAGContextResponse.matches:type_name -> modelgateway.RAGMatch
-	1, // 2: modelgateway.ModelGateway.GetPlan:input_type -> modelgateway.PlanRequest
-	3, // 3: modelgateway.ModelGateway.GetRAGContext:input_type -> modelgateway.RAGContextRequest
-	6, // 4: modelgateway.ToolService.ExecuteTool:input_type -> modelgateway.ToolRequest
-	2, // 5: modelgateway.ModelGateway.GetPlan:output_type -> modelgateway.PlanResponse
-	5, // 6: modelgateway.ModelGateway.GetRAGContext:output_type -> modelgateway.RAGContextResponse
-	7, // 7: modelgateway.ToolService.ExecuteTool:output_type -> modelgateway.ToolResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	3,  // 0: modelgateway.EmbeddingsResponse.embeddings:type_name -> modelgateway.Embedding
+	5,  // 1: modelgateway.PlanRequest.resources:type_name -> modelgateway.Resource
+	10, // 2: modelgateway.RAGContextResponse.matches:type_name -> modelgateway.RAGMatch
+	6,  // 3: modelgateway.ModelGateway.GetPlan:input_type -> modelgateway.PlanRequest
+	6,  // 4: modelgateway.ModelGateway.GetPlanStream:input_type -> modelgateway.PlanRequest
+	9,  // 5: modelgateway.ModelGateway.GetRAGContext:input_type -> modelgateway.RAGContextRequest
+	0,  // 6: modelgateway.ModelGateway.Health:input_type -> modelgateway.HealthRequest
+	2,  // 7: modelgateway.ModelGateway.GetEmbeddings:input_type -> modelgateway.EmbeddingsRequest
+	12, // 8: modelgateway.ToolService.ExecuteTool:input_type -> modelgateway.ToolRequest
+	7,  // 9: modelgateway.ModelGateway.GetPlan:output_type -> modelgateway.PlanResponse
+	8,  // 10: modelgateway.ModelGateway.GetPlanStream:output_type -> modelgateway.PlanStreamChunk
+	11, // 11: modelgateway.ModelGateway.GetRAGContext:output_type -> modelgateway.RAGContextResponse
+	1,  // 12: modelgateway.ModelGateway.Health:output_type -> modelgateway.HealthResponse
+	4,  // 13: modelgateway.ModelGateway.GetEmbeddings:output_type -> modelgateway.EmbeddingsResponse
+	13, // 14: modelgateway.ToolService.ExecuteTool:output_type -> modelgateway.ToolResponse
+	9,  // [9:15] is the sub-list for method output_type
+	3,  // [3:9] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_model_proto_init() }
@@ -611,13 +1184,14 @@ func file_proto_model_proto_init() {
 	if File_proto_model_proto != nil {
 		return
 	}
+	file_proto_model_proto_msgTypes[6].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_model_proto_rawDesc), len(file_proto_model_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
@@ -20,7 +20,10 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	ModelGateway_GetPlan_FullMethodName       = "/modelgateway.ModelGateway/GetPlan"
+	ModelGateway_GetPlanStream_FullMethodName = "/modelgateway.ModelGateway/GetPlanStream"
 	ModelGateway_GetRAGContext_FullMethodName = "/modelgateway.ModelGateway/GetRAGContext"
+	ModelGateway_Health_FullMethodName        = "/modelgateway.ModelGateway/Health"
+	ModelGateway_GetEmbeddings_FullMethodName = "/modelgateway.ModelGateway/GetEmbeddings"
 )
 
 // ModelGatewayClient is the client API for ModelGateway service.
@@ -28,7 +31,15 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ModelGatewayClient interface {
 	GetPlan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (*PlanResponse, error)
+	// GetPlanStream streams the completion as it is generated. Intermediate chunks
+	// carry raw deltas; the final chunk (done=true) carries the normalized plan.
+	GetPlanStream(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlanStreamChunk], error)
 	GetRAGContext(ctx context.Context, in *RAGContextRequest, opts ...grpc.CallOption) (*RAGContextResponse, error)
+	// Health probes the configured LLM provider (cached for a few seconds).
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// GetEmbeddings embeds a batch of inputs with the primary provider's
+	// embedding model (EMBEDDING_MODEL_NAME).
+	GetEmbeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error)
 }
 
 type modelGatewayClient struct {
@@ -49,6 +60,25 @@ func (c *modelGatewayClient) GetPlan(ctx context.Context, in *PlanRequest, opts
 	return out, nil
 }
 
+func (c *modelGatewayClient) GetPlanStream(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlanStreamChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ModelGateway_ServiceDesc.Streams[0], ModelGateway_GetPlanStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PlanRequest, PlanStreamChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModelGateway_GetPlanStreamClient = grpc.ServerStreamingClient[PlanStreamChunk]
+
 func (c *modelGatewayClient) GetRAGContext(ctx context.Context, in *RAGContextRequest, opts ...grpc.CallOption) (*RAGContextResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RAGContextResponse)
@@ -59,12 +89,40 @@ func (c *modelGatewayClient) GetRAGContext(ctx context.Context, in *RAGContextRe
 	return out, nil
 }
 
+func (c *modelGatewayClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modelGatewayClient) GetEmbeddings(ctx context.Context, in *EmbeddingsRequest, opts ...grpc.CallOption) (*EmbeddingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmbeddingsResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_GetEmbeddings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ModelGatewayServer is the server API for ModelGateway service.
 // All implementations must embed UnimplementedModelGatewayServer
 // for forward compatibility.
 type ModelGatewayServer interface {
 	GetPlan(context.Context, *PlanRequest) (*PlanResponse, error)
+	// GetPlanStream streams the completion as it is generated. Intermediate chunks
+	// carry raw deltas; the final chunk (done=true) carries the normalized plan.
+	GetPlanStream(*PlanRequest, grpc.ServerStreamingServer[PlanStreamChunk]) error
 	GetRAGContext(context.Context, *RAGContextRequest) (*RAGContextResponse, error)
+	// Health probes the configured LLM provider (cached for a few seconds).
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// GetEmbeddings embeds a batch of inputs with the primary provider's
+	// embedding model (EMBEDDING_MODEL_NAME).
+	GetEmbeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error)
 	mustEmbedUnimplementedModelGatewayServer()
 }
 
@@ -78,9 +136,18 @@ type UnimplementedModelGatewayServer struct{}
 func (UnimplementedModelGatewayServer) GetPlan(context.Context, *PlanRequest) (*PlanResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetPlan not implemented")
 }
+func (UnimplementedModelGatewayServer) GetPlanStream(*PlanRequest, grpc.ServerStreamingServer[PlanStreamChunk]) error {
+	return status.Error(codes.Unimplemented, "method GetPlanStream not implemented")
+}
 func (UnimplementedModelGatewayServer) GetRAGContext(context.Context, *RAGContextRequest) (*RAGContextResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetRAGContext not implemented")
 }
+func (UnimplementedModelGatewayServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedModelGatewayServer) GetEmbeddings(context.Context, *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetEmbeddings not implemented")
+}
 func (UnimplementedModelGatewayServer) mustEmbedUnimplementedModelGatewayServer() {}
 func (UnimplementedModelGatewayServer) testEmbeddedByValue()                      {}
 
@@ -120,6 +187,17 @@ func _ModelGateway_GetPlan_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModelGateway_GetPlanStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PlanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ModelGatewayServer).GetPlanStream(m, &grpc.GenericServerStream[PlanRequest, PlanStreamChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModelGateway_GetPlanStreamServer = grpc.ServerStreamingServer[PlanStreamChunk]
+
 func _ModelGateway_GetRAGContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RAGContextRequest)
 	if err := dec(in); err != nil {
@@ -138,6 +216,42 @@ func _ModelGateway_GetRAGContext_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModelGateway_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModelGateway_GetEmbeddings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbeddingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).GetEmbeddings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_GetEmbeddings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).GetEmbeddings(ctx, req.(*EmbeddingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ModelGateway_ServiceDesc is the grpc.ServiceDesc for ModelGateway service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,8 +267,22 @@ var ModelGateway_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRAGContext",
 			Handler:    _ModelGateway_GetRAGContext_Handler,
 		},
+		{
+			MethodName: "Health",
+			Handler:    _ModelGateway_Health_Handler,
+		},
+		{
+			MethodName: "GetEmbeddings",
+			Handler:    _ModelGateway_GetEmbeddings_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetPlanStream",
+			Handler:       _ModelGateway_GetPlanStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/model.proto",
 }
 
@@ -0,0 +1,72 @@
+// Package redact masks secrets and personal data in text before it is logged or
+// stored. It is shared by both services' loggers and the agent planner's audit log.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+)
+
+// rule replaces every match of re with repl (which may reference submatches).
+type rule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// secretKeys are field names whose values are always masked.
+const secretKeys = `api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret|secret|password|passwd|token`
+
+// rules run in order; key=value secrets go first so the key name is kept.
+var rules = []rule{
+	// api_key=..., password: ..., "token": "..." (a quoted value is masked whole,
+	// an unquoted one up to the next separator).
+	{regexp.MustCompile(`(?i)\b(` + secretKeys + `)("?\s*[:=]\s*)"(?:[^"\\]|\\.)*"`), `${1}${2}"[REDACTED]"`},
+	{regexp.MustCompile(`(?i)\b(` + secretKeys + `)("?\s*[:=]\s*)[^\s"',;&}\\]+`), "${1}${2}[REDACTED]"},
+	{regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9._~+/=-]{8,}`), "${1} [REDACTED]"},
+	// Provider key formats: OpenAI/OpenRouter, Anthropic, AWS, GitHub, Slack, JWTs.
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`), "[REDACTED]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED]"},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`), "[REDACTED]"},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "[REDACTED]"},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`), "[REDACTED]"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+}
+
+// Redact returns s with API keys, bearer tokens, key=value secrets and email
+// addresses masked. Text without a match is returned unchanged.
+func Redact(s string) string {
+	for _, r := range rules {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// Digest returns a short, stable fingerprint of s ("sha256:" and 16 hex chars),
+// so redacted values can still be correlated across log lines.
+func Digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// ReplaceAttr is a slog.HandlerOptions.ReplaceAttr that redacts string, error
+// and []string values of every logged field.
+func ReplaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(Redact(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			a.Value = slog.StringValue(Redact(v.Error()))
+		case []string:
+			out := make([]string, len(v))
+			for i, s := range v {
+				out[i] = Redact(s)
+			}
+			a.Value = slog.AnyValue(out)
+		}
+	}
+	return a
+}
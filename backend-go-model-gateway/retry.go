@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultLLMMaxRetries = 3
+	retryBaseDelay       = 250 * time.Millisecond
+	retryMaxDelay        = 4 * time.Second
+)
+
+// retryPolicy retries transient upstream LLM failures with exponential backoff and jitter.
+// A provider Retry-After hint replaces the backoff when it is at most maxRetryAfter.
+type retryPolicy struct {
+	maxRetries    int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	maxRetryAfter time.Duration
+}
+
+// loadRetryPolicy reads LLM_MAX_RETRIES (default 3; 0 disables retries) and
+// LLM_RETRY_AFTER_MAX_SECONDS (default 30; 0 ignores Retry-After for backoff).
+func loadRetryPolicy() retryPolicy {
+	maxRetries := defaultLLMMaxRetries
+	if v := strings.TrimSpace(os.Getenv("LLM_MAX_RETRIES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxRetries = n
+		}
+	}
+	return retryPolicy{
+		maxRetries:    maxRetries,
+		baseDelay:     retryBaseDelay,
+		maxDelay:      retryMaxDelay,
+		maxRetryAfter: time.Duration(getEnvInt("LLM_RETRY_AFTER_MAX_SECONDS", defaultRetryAfterMaxSec)) * time.Second,
+	}
+}
+
+// retryableStatus reports the upstream HTTP status of err and whether it is worth retrying
+// (429 and the transient 5xx codes).
+func retryableStatus(err error) (int, bool) {
+	code := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		code = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		code = reqErr.HTTPStatusCode
+	}
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return code, true
+	default:
+		return code, false
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt (1-based).
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay << (attempt - 1)
+	if d <= 0 || d > p.maxDelay {
+		d = p.maxDelay
+	}
+	// Jitter in [d/2, d) so concurrent callers do not retry in lockstep.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error, retries are
+// exhausted, or ctx is done. It never sleeps past ctx's deadline. Errors of responses
+// that carried a Retry-After header are returned as *retryAfterError.
+func withRetry[T any](ctx context.Context, p retryPolicy, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	lg := logger.NewContextLogger(ctx)
+	for attempt := 1; ; attempt++ {
+		hint := &retryAfterHint{}
+		res, err := fn(withRetryAfterHint(ctx, hint))
+		retryAfter := hint.get()
+		if err != nil && retryAfter > 0 {
+			err = &retryAfterError{err: err, after: retryAfter}
+		}
+		if err == nil || ctx.Err() != nil || attempt > p.maxRetries {
+			return res, err
+		}
+		code, ok := retryableStatus(err)
+		if !ok {
+			return res, err
+		}
+
+		wait := p.backoff(attempt)
+		if retryAfter > 0 && p.maxRetryAfter > 0 {
+			if retryAfter > p.maxRetryAfter {
+				lg.Warn("llm_retry_abandoned", "op", op, "attempt", attempt, "status", code, "reason", "retry_after", "retry_after_ms", retryAfter.Milliseconds(), "error", err)
+				return res, err
+			}
+			wait = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			lg.Warn("llm_retry_abandoned", "op", op, "attempt", attempt, "status", code, "reason", "deadline", "error", err)
+			return res, err
+		}
+		lg.Warn(
+			"llm_retry",
+			"op", op,
+			"attempt", attempt,
+			"max_retries", p.maxRetries,
+			"status", code,
+			"backoff_ms", wait.Milliseconds(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	defaultRetryAfterMaxSec = 30
+	// retryAfterTrailer carries the provider's Retry-After (whole seconds) on failed RPCs.
+	retryAfterTrailer = "retry-after"
+)
+
+// retryAfterHint collects the Retry-After of the last throttled upstream response
+// of one attempt. go-openai errors do not expose response headers, so
+// retryAfterTransport records it into the hint found in the request context.
+type retryAfterHint struct {
+	mu    sync.Mutex
+	after time.Duration
+}
+
+func (h *retryAfterHint) set(d time.Duration) {
+	h.mu.Lock()
+	h.after = d
+	h.mu.Unlock()
+}
+
+func (h *retryAfterHint) get() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.after
+}
+
+type retryAfterHintKey struct{}
+
+func withRetryAfterHint(ctx context.Context, h *retryAfterHint) context.Context {
+	return context.WithValue(ctx, retryAfterHintKey{}, h)
+}
+
+// retryAfterTransport records the Retry-After header of 429/503 responses.
+type retryAfterTransport struct {
+	next http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return resp, err
+	}
+	if h, ok := req.Context().Value(retryAfterHintKey{}).(*retryAfterHint); ok {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			h.set(d)
+		}
+	}
+	return resp, err
+}
+
+// parseRetryAfter parses a Retry-After value: delay-seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryAfterError is an upstream error that came with a Retry-After hint.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.err, e.after)
+}
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// retryAfterOf returns the Retry-After hint carried by err, if any.
+func retryAfterOf(err error) (time.Duration, bool) {
+	var ra *retryAfterError
+	if errors.As(err, &ra) {
+		return ra.after, true
+	}
+	return 0, false
+}
+
+// setRetryAfterTrailer sets the retry-after trailer when err carries a hint,
+// rounding up to whole seconds like the HTTP header.
+func setRetryAfterTrailer(err error, set func(metadata.MD) error) {
+	d, ok := retryAfterOf(err)
+	if !ok {
+		return
+	}
+	secs := int((d + time.Second - 1) / time.Second)
+	_ = set(metadata.Pairs(retryAfterTrailer, strconv.Itoa(secs)))
+}
+
+func retryAfterUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		setRetryAfterTrailer(err, func(md metadata.MD) error { return grpc.SetTrailer(ctx, md) })
+	}
+	return resp, err
+}
+
+func retryAfterStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	if err != nil {
+		setRetryAfterTrailer(err, func(md metadata.MD) error { ss.SetTrailer(md); return nil })
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d, ok := parseRetryAfter("7", now); !ok || d != 7*time.Second {
+		t.Fatalf("seconds: got %v %v", d, ok)
+	}
+	if d, ok := parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now); !ok || d != 90*time.Second {
+		t.Fatalf("http date: got %v %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Fatalf("expected invalid value to be ignored")
+	}
+}
+
+func TestWithRetry_SurfacesRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: retryAfterTransport{next: http.DefaultTransport}}
+
+	_, err := withRetry(context.Background(), retryPolicy{maxRetries: 0}, "test", func(ctx context.Context) (int, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return 0, &openai.APIError{HTTPStatusCode: resp.StatusCode, Message: "rate limited"}
+	})
+	if d, ok := retryAfterOf(err); !ok || d != 2*time.Second {
+		t.Fatalf("expected 2s retry-after on %v, got %v %v", err, d, ok)
+	}
+	if code, _ := retryableStatus(err); code != http.StatusTooManyRequests {
+		t.Fatalf("expected wrapped status 429, got %d", code)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultPlanTemperature = 0.2
+
+// planParams are the sampling parameters used for a single plan request.
+type planParams struct {
+	temperature float32
+	// maxTokens is 0 when the provider default applies.
+	maxTokens int
+	// model overrides the primary runtime's model when non-empty.
+	model string
+	// seed is nil when the request leaves sampling unseeded.
+	seed *int
+}
+
+// planParamsFrom validates the optional temperature/max_tokens/seed of a PlanRequest,
+// falling back to the defaults (0.2, provider default, unseeded) when unset.
+func planParamsFrom(in *pb.PlanRequest) (planParams, error) {
+	p := planParams{temperature: defaultPlanTemperature, model: strings.TrimSpace(in.GetModel())}
+	if in.Temperature != nil {
+		t := in.GetTemperature()
+		if math.IsNaN(float64(t)) || t < 0 || t > 2 {
+			return p, status.Errorf(codes.InvalidArgument, "temperature must be between 0 and 2, got %v", t)
+		}
+		p.temperature = t
+	}
+	if in.MaxTokens != nil {
+		if in.GetMaxTokens() <= 0 {
+			return p, status.Errorf(codes.InvalidArgument, "max_tokens must be positive, got %d", in.GetMaxTokens())
+		}
+		p.maxTokens = int(in.GetMaxTokens())
+	}
+	if in.Seed != nil {
+		seed := int(in.GetSeed())
+		p.seed = &seed
+	}
+	return p, nil
+}
+
+// apply sets the sampling parameters on req.
+func (p planParams) apply(req *openai.ChatCompletionRequest) {
+	req.Temperature = p.temperature
+	if req.Temperature == 0 {
+		// go-openai omits a zero temperature (omitempty), which would select the
+		// provider default; send the smallest non-zero value instead.
+		req.Temperature = math.SmallestNonzeroFloat32
+	}
+	req.MaxTokens = p.maxTokens
+	req.Seed = p.seed
+}
+
+// modelFor returns the model to request from rt: the request's model override for
+// the primary runtime, else rt's configured model.
+func (p planParams) modelFor(rt, primary *llmRuntime) string {
+	if p.model != "" && rt == primary {
+		return p.model
+	}
+	return rt.Model
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetPlanStream implements modelgateway.ModelGatewayServer.
+//
+// Each completion delta is forwarded as-is; normalization runs once on the
+// accumulated text and is sent on the final chunk together with model_name and
+// latency_ms.
+func (s *server) GetPlanStream(in *pb.PlanRequest, stream grpc.ServerStreamingServer[pb.PlanStreamChunk]) (err error) {
+	requestStart := time.Now()
+
+	ctx := service.ContextWithTraceIDFromIncomingGRPC(stream.Context())
+
+	var rt *llmRuntime
+	var usage openai.Usage
+	defer func() { s.metrics.record(ctx, "GetPlanStream", s.llm, rt, requestStart, usage, err) }()
+
+	// Bound the LLM call.
+	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+
+	if in.GetN() > 1 {
+		return status.Error(codes.InvalidArgument, "GetPlanStream does not support n > 1; use GetPlan for multiple candidates")
+	}
+
+	params, err := planParamsFrom(in)
+	if err != nil {
+		return err
+	}
+
+	provider, messages, err := s.preparePlan(callCtx, "GetPlanStream", in, params)
+	if err != nil {
+		return err
+	}
+
+	// The slot is held until the stream is fully consumed.
+	release, err := s.limiter.acquire(callCtx, "GetPlanStream")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Only opening the stream is retried (and falls back); once deltas flow, failures are returned.
+	llmStream, rt, err := withFallback(callCtx, s, "CreateChatCompletionStream", func(ctx context.Context, rt *llmRuntime) (*openai.ChatCompletionStream, error) {
+		req := openai.ChatCompletionRequest{
+			Model:    params.modelFor(rt, s.llm),
+			Messages: messages,
+			Stream:   true,
+			// Ask for a trailing usage chunk; providers that ignore it report zeros.
+			StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+		}
+		params.apply(&req)
+		rt.applyJSONMode(&req)
+		return rt.Client.CreateChatCompletionStream(ctx, req)
+	})
+	if err != nil {
+		if isProviderContentFilter(err) {
+			return contentBlockedError(s.llm, params.modelFor(s.llm, s.llm))
+		}
+		return err
+	}
+	provider = string(rt.Provider)
+	defer llmStream.Close()
+
+	var content strings.Builder
+	var fingerprint string
+	for {
+		resp, err := llmStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.NewContextLogger(callCtx).Warn("plan_stream_failed", "received_bytes", content.Len(), "error", err)
+			return err
+		}
+		if resp.Usage != nil {
+			usage = *resp.Usage
+		}
+		if resp.SystemFingerprint != "" {
+			fingerprint = resp.SystemFingerprint
+		}
+		if len(resp.Choices) > 0 && resp.Choices[0].FinishReason == openai.FinishReasonContentFilter {
+			logger.NewContextLogger(callCtx).Warn("llm_content_blocked", "provider", provider, "model", params.modelFor(rt, s.llm), "received_bytes", content.Len())
+			return contentBlockedError(rt, params.modelFor(rt, s.llm))
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := stream.Send(&pb.PlanStreamChunk{Delta: delta}); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(content.String()) == "" {
+		logger.NewContextLogger(callCtx).Warn("llm_empty_generation", "provider", provider, "model", params.modelFor(rt, s.llm))
+		return emptyGenerationError(rt, params.modelFor(rt, s.llm))
+	}
+	plan, _ := normalizePlan(content.String(), in.GetPrompt(), provider, s.modelTypes, rt.JSONMode)
+	return stream.Send(&pb.PlanStreamChunk{
+		Done:              true,
+		Plan:              plan,
+		ModelName:         params.modelFor(rt, s.llm),
+		LatencyMs:         time.Since(requestStart).Milliseconds(),
+		PromptTokens:      int32(usage.PromptTokens),
+		CompletionTokens:  int32(usage.CompletionTokens),
+		TotalTokens:       int32(usage.TotalTokens),
+		EstimatedCostUsd:  s.prices.cost(params.modelFor(rt, s.llm), usage),
+		SystemFingerprint: fingerprint,
+	})
+}
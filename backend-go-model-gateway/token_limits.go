@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// approxCharsPerToken is the heuristic used to estimate token counts without a
+// provider-specific tokenizer.
+const approxCharsPerToken = 4
+
+// estimateTokens returns a rough token estimate for the given texts (chars/4, rounded up).
+func estimateTokens(texts ...string) int {
+	chars := 0
+	for _, t := range texts {
+		chars += utf8.RuneCountInString(t)
+	}
+	return (chars + approxCharsPerToken - 1) / approxCharsPerToken
+}
+
+// contextLimits holds the configured prompt context windows.
+type contextLimits struct {
+	// defaultMax applies to models without a per-model entry (0 = no limit).
+	defaultMax int
+	perModel   map[string]int
+}
+
+// loadContextLimits reads PLAN_MAX_CONTEXT_TOKENS and PLAN_MODEL_CONTEXT_TOKENS
+// ("model=tokens,model2=tokens").
+func loadContextLimits() contextLimits {
+	limits := contextLimits{
+		defaultMax: getEnvInt("PLAN_MAX_CONTEXT_TOKENS", 0),
+		perModel:   map[string]int{},
+	}
+	for _, entry := range strings.Split(getEnv("PLAN_MODEL_CONTEXT_TOKENS", ""), ",") {
+		model, tokens, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(tokens))
+		if err != nil || n <= 0 {
+			continue
+		}
+		limits.perModel[strings.TrimSpace(model)] = n
+	}
+	return limits
+}
+
+// maxFor returns the context window for model (0 = no limit).
+func (l contextLimits) maxFor(model string) int {
+	if n, ok := l.perModel[model]; ok {
+		return n
+	}
+	return l.defaultMax
+}
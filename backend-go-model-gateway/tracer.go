@@ -5,25 +5,37 @@ import (
 	"net/http"
 	"os"
 
+	openai "github.com/sashabaranov/go-openai"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-// InitTracer configures OpenTelemetry tracing with an OTLP/gRPC exporter.
+// InitTracer configures OpenTelemetry tracing with an OTLP/gRPC exporter sending
+// to OTEL_EXPORTER_OTLP_ENDPOINT (e.g. "otel-collector:4317").
 //
-// Default exporter target: localhost:4317
-// Override with OTEL_EXPORTER_OTLP_ENDPOINT.
+// When the endpoint is unset, it returns a nil provider: the global no-op tracer
+// stays in place, but W3C trace context is still propagated to the LLM provider.
 func InitTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	)
+
 	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
 	if endpoint == "" {
-		endpoint = "localhost:4317"
+		return nil, nil
 	}
 
 	exporter, err := otlptracegrpc.New(
@@ -51,14 +63,33 @@ func InitTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
 	)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
+	return tp, nil
+}
+
+// startLLMSpan starts a child span around one LLM call attempt.
+func startLLMSpan(ctx context.Context, name string, rt *llmRuntime, model string) (context.Context, trace.Span) {
+	return otel.Tracer(SERVICE_NAME).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("llm.provider", string(rt.Provider)),
+			attribute.String("llm.model", model),
 		),
 	)
+}
 
-	return tp, nil
+// endLLMSpan records the call's token usage (or error) and ends the span.
+func endLLMSpan(span trace.Span, usage openai.Usage, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(
+			attribute.Int("llm.usage.prompt_tokens", usage.PromptTokens),
+			attribute.Int("llm.usage.completion_tokens", usage.CompletionTokens),
+			attribute.Int("llm.usage.total_tokens", usage.TotalTokens),
+		)
+	}
+	span.End()
 }
 
 // ClientTraceTransport wraps an http.RoundTripper with OpenTelemetry
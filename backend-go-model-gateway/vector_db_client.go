@@ -2,10 +2,9 @@ package main
 
 import (
 	"context"
-	"log"
 	"math"
-	"time"
 
+	"backend-go-model-gateway/internal/logger"
 	pb "backend-go-model-gateway/proto/proto"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
@@ -104,10 +103,9 @@ func (c *RAGGRPCClient) GetContext(ctx context.Context, req VectorQueryRequest)
 		})
 	}
 
-	log.Printf(
-		`{"timestamp":"%s","level":"info","service":"%s","component":"RAGGRPCClient","method":"GetContext","rag_addr":%q,"query_text":%q,"top_k":%d,"match_count":%d}`,
-		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, getEnv("RAG_GRPC_ADDR", "localhost:50052"), req.QueryText, req.TopK, len(matches),
-	)
+	lg := logger.NewContextLogger(ctx)
+	lg.Info("rag_get_context", "rag_addr", getEnv("RAG_GRPC_ADDR", "localhost:50052"), "top_k", req.TopK, "match_count", len(matches))
+	lg.Debug("rag_get_context_query", textAttrs("query_text", req.QueryText)...)
 
 	return matches, nil
 }